@@ -11,11 +11,19 @@ cleanup() {
 trap cleanup SIGTERM SIGINT
 
 PROMPT="$SLACK_AGENT_PROMPT"
-mkdir -p sessions/$SLACK_THREAD_TS
-cd sessions/$SLACK_THREAD_TS
+mkdir -p "$SLACK_AGENT_WORKDIR"
+cd "$SLACK_AGENT_WORKDIR"
+
+PROFILE_ARGS=()
+if [ -n "$ALLOWED_TOOLS" ]; then
+    PROFILE_ARGS+=(--allowedTools "$ALLOWED_TOOLS")
+fi
+if [ -n "$CLAUDE_MODEL" ]; then
+    PROFILE_ARGS+=(--model "$CLAUDE_MODEL")
+fi
 
 mise exec -- claude -c --output-format stream-json --dangerously-skip-permissions \
-  --disallowedTools "$DISALLOWED_TOOLS" -p --verbose $CLAUDE_EXTRA_ARGS "$PROMPT" \
-  | claude-posts --bot-token=$SLACK_BOT_TOKEN \
+  --disallowedTools "$DISALLOWED_TOOLS" "${PROFILE_ARGS[@]}" -p --verbose $CLAUDE_EXTRA_ARGS "$PROMPT" \
+  | slack-agent post --bot-token=$SLACK_BOT_TOKEN \
       --channel-id=$SLACK_CHANNEL_ID \
       --thread-ts=$SLACK_THREAD_TS
@@ -0,0 +1,31 @@
+package domain
+
+// AccessRule lists users, user groups and channels by ID
+type AccessRule struct {
+	Users      []string
+	UserGroups []string
+	Channels   []string
+}
+
+// IsEmpty reports whether the rule lists nothing
+func (r AccessRule) IsEmpty() bool {
+	return len(r.Users) == 0 && len(r.UserGroups) == 0 && len(r.Channels) == 0
+}
+
+// AccessPolicy decides who may invoke the agent.
+// Deny takes precedence over Allow; an empty Allow rule allows everyone who is not denied.
+type AccessPolicy struct {
+	Allow AccessRule
+	Deny  AccessRule
+}
+
+// IsEmpty reports whether the policy restricts nothing
+func (p AccessPolicy) IsEmpty() bool {
+	return p.Allow.IsEmpty() && p.Deny.IsEmpty()
+}
+
+// AccessDecision is the outcome of an access check
+type AccessDecision struct {
+	Allowed bool
+	Reason  string
+}
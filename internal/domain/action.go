@@ -0,0 +1,59 @@
+package domain
+
+// Control is a button that the bot shows with its answers
+type Control string
+
+const (
+	// ControlRegenerate runs the last prompt of the thread again
+	ControlRegenerate Control = "regenerate"
+	// ControlContinue asks the agent to carry on where it stopped
+	ControlContinue Control = "continue"
+	// ControlStop cancels the running agent
+	ControlStop Control = "stop"
+	// ControlGood rates the answer as helpful
+	ControlGood Control = "feedback_good"
+	// ControlBad rates the answer as not helpful
+	ControlBad Control = "feedback_bad"
+)
+
+// Label returns the text shown on the button
+func (c Control) Label() string {
+	switch c {
+	case ControlRegenerate:
+		return "🔄 Regenerate"
+	case ControlContinue:
+		return "▶️ Continue"
+	case ControlStop:
+		return "🛑 Stop"
+	case ControlGood:
+		return "👍"
+	case ControlBad:
+		return "👎"
+	default:
+		return string(c)
+	}
+}
+
+// IsFeedback reports whether the control rates the answer
+func (c Control) IsFeedback() bool {
+	return c == ControlGood || c == ControlBad
+}
+
+// Action represents a click on one of the bot's controls
+type Action struct {
+	TeamID    string
+	UserID    string
+	ChannelID string
+	// ThreadTS is the thread the control was posted in
+	ThreadTS string
+	// MessageTS is the message that carries the control
+	MessageTS string
+	// AnswerTS is the answer the control refers to
+	AnswerTS string
+	Control  Control
+}
+
+// SessionKey returns the key of the session of the thread the control belongs to
+func (a *Action) SessionKey() SessionKey {
+	return SessionKey{TeamID: a.TeamID, ChannelID: a.ChannelID, ThreadTS: a.ThreadTS}
+}
@@ -0,0 +1,39 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestControl(t *testing.T) {
+	tests := []struct {
+		control      domain.Control
+		wantLabel    string
+		wantFeedback bool
+	}{
+		{control: domain.ControlRegenerate, wantLabel: "🔄 Regenerate"},
+		{control: domain.ControlStop, wantLabel: "🛑 Stop"},
+		{control: domain.ControlGood, wantLabel: "👍", wantFeedback: true},
+		{control: domain.ControlBad, wantLabel: "👎", wantFeedback: true},
+		{control: domain.Control("other"), wantLabel: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.control), func(t *testing.T) {
+			if label := tt.control.Label(); label != tt.wantLabel {
+				t.Errorf("expected label %q, got %q", tt.wantLabel, label)
+			}
+			if tt.control.IsFeedback() != tt.wantFeedback {
+				t.Errorf("expected IsFeedback to be %v", tt.wantFeedback)
+			}
+		})
+	}
+}
+
+func TestAction_SessionKey(t *testing.T) {
+	action := &domain.Action{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1", MessageTS: "2.2"}
+	if key := action.SessionKey(); key.String() != "T1/C1/1.1" {
+		t.Errorf("expected key of the thread, got %s", key)
+	}
+}
@@ -1,9 +1,77 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAgentCancelled is reported when a running agent was cancelled on request
+var ErrAgentCancelled = errors.New("agent run cancelled")
+
+// AgentErrorKind describes at which stage an agent run failed
+type AgentErrorKind string
+
+const (
+	// AgentErrorStart means the agent process could not be started
+	AgentErrorStart AgentErrorKind = "start"
+	// AgentErrorExit means the agent process exited unsuccessfully
+	AgentErrorExit AgentErrorKind = "exit"
+	// AgentErrorStream means the agent output could not be read or parsed
+	AgentErrorStream AgentErrorKind = "stream"
+	// AgentErrorPost means the agent output could not be posted to Slack
+	AgentErrorPost AgentErrorKind = "post"
+	// AgentErrorResult means the agent itself reported an error result
+	AgentErrorResult AgentErrorKind = "result"
+	// AgentErrorTimeout means the agent was stopped because it ran or stayed silent for too long
+	AgentErrorTimeout AgentErrorKind = "timeout"
+	// AgentErrorResume means the agent's conversation to continue no longer exists
+	AgentErrorResume AgentErrorKind = "resume"
+)
+
+// AgentError is a structured error reported by an agent run
+type AgentError struct {
+	Kind     AgentErrorKind
+	Message  string
+	ExitCode int
+	Stderr   string
+	Err      error
+}
+
+// Error returns a message suitable for showing to users
+func (e *AgentError) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.Kind == AgentErrorExit {
+		msg = fmt.Sprintf("%s (exit code %d)", msg, e.ExitCode)
+	}
+	if e.Stderr != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Stderr)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error
+func (e *AgentError) Unwrap() error {
+	return e.Err
+}
+
 // AgentResult represents the result from an AI agent
 type AgentResult struct {
 	Response string
 	Error    error
+	// Posted is true when the agent has already posted the response to Slack
+	Posted    bool
+	SessionID string
+	NumTurns  int
+	CostUSD   float64
+	Duration  time.Duration
+	// PostedTS are the messages a posted response was split into,
+	// and PostedText is the text of the last of them
+	PostedTS   []string
+	PostedText string
 }
 
 // NewAgentResult creates a new AgentResult instance
@@ -18,3 +86,17 @@ func NewAgentResult(response string, err error) *AgentResult {
 func (ar *AgentResult) IsError() bool {
 	return ar.Error != nil
 }
+
+// IsCancelled checks if the agent was cancelled before it finished
+func (ar *AgentResult) IsCancelled() bool {
+	return errors.Is(ar.Error, ErrAgentCancelled)
+}
+
+// AgentErrorKind returns the kind of a structured agent error, or an empty kind
+func (ar *AgentResult) AgentErrorKind() AgentErrorKind {
+	var agentErr *AgentError
+	if errors.As(ar.Error, &agentErr) {
+		return agentErr.Kind
+	}
+	return ""
+}
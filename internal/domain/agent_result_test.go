@@ -0,0 +1,82 @@
+package domain_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestAgentResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantError     bool
+		wantCancelled bool
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:      "error",
+			err:       errors.New("exit status 1"),
+			wantError: true,
+		},
+		{
+			name:          "cancelled",
+			err:           fmt.Errorf("claude: %w", domain.ErrAgentCancelled),
+			wantError:     true,
+			wantCancelled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := domain.NewAgentResult("", tt.err)
+			if result.IsError() != tt.wantError {
+				t.Errorf("expected IsError %v, got %v", tt.wantError, result.IsError())
+			}
+			if result.IsCancelled() != tt.wantCancelled {
+				t.Errorf("expected IsCancelled %v, got %v", tt.wantCancelled, result.IsCancelled())
+			}
+		})
+	}
+}
+
+func TestAgentError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      *domain.AgentError
+		expected string
+	}{
+		{
+			name:     "exit with stderr",
+			err:      &domain.AgentError{Kind: domain.AgentErrorExit, Message: "claude failed", ExitCode: 2, Stderr: "boom"},
+			expected: "claude failed (exit code 2): boom",
+		},
+		{
+			name:     "result",
+			err:      &domain.AgentError{Kind: domain.AgentErrorResult, Message: "max turns reached"},
+			expected: "max turns reached",
+		},
+		{
+			name:     "wrapped error",
+			err:      &domain.AgentError{Kind: domain.AgentErrorPost, Err: errors.New("rate limited")},
+			expected: "rate limited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Error() != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, tt.err.Error())
+			}
+
+			result := domain.NewAgentResult("", fmt.Errorf("run: %w", tt.err))
+			if result.AgentErrorKind() != tt.err.Kind {
+				t.Errorf("expected kind %s, got %s", tt.err.Kind, result.AgentErrorKind())
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WorkspaceFile describes a file in a session's working directory
+type WorkspaceFile struct {
+	// Path is relative to the working directory and uses forward slashes
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// WorkspaceSnapshot records the files of a working directory by path
+type WorkspaceSnapshot map[string]WorkspaceFile
+
+// Changed returns the files in after that are new or modified since the snapshot, sorted by path
+func (s WorkspaceSnapshot) Changed(after WorkspaceSnapshot) []WorkspaceFile {
+	var changed []WorkspaceFile
+	for p, file := range after {
+		if old, ok := s[p]; ok && old.Size == file.Size && old.ModTime.Equal(file.ModTime) {
+			continue
+		}
+		changed = append(changed, file)
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Path < changed[j].Path })
+	return changed
+}
+
+// ArtifactPolicy selects the files produced by the agent that are uploaded to the thread
+type ArtifactPolicy struct {
+	// Patterns are globs such as "*.csv" or "out/*.png".
+	// A pattern without a slash matches the file name in any directory.
+	// No patterns disables uploads.
+	Patterns []string
+	// MaxBytes is the largest file uploaded; zero means no limit
+	MaxBytes int64
+	// MaxFiles is the most files uploaded after a run; zero means no limit
+	MaxFiles int
+}
+
+// Enabled reports whether any files are uploaded
+func (p ArtifactPolicy) Enabled() bool {
+	return len(p.Patterns) > 0
+}
+
+// Match reports whether the file matches one of the patterns
+func (p ArtifactPolicy) Match(file WorkspaceFile) bool {
+	for _, pattern := range p.Patterns {
+		name := file.Path
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(file.Path)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
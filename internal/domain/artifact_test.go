@@ -0,0 +1,50 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestWorkspaceSnapshot_Changed(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := domain.WorkspaceSnapshot{
+		"same.txt":     {Path: "same.txt", Size: 1, ModTime: now},
+		"modified.txt": {Path: "modified.txt", Size: 1, ModTime: now},
+		"deleted.txt":  {Path: "deleted.txt", Size: 1, ModTime: now},
+	}
+	after := domain.WorkspaceSnapshot{
+		"same.txt":     {Path: "same.txt", Size: 1, ModTime: now},
+		"modified.txt": {Path: "modified.txt", Size: 1, ModTime: now.Add(time.Second)},
+		"out/new.csv":  {Path: "out/new.csv", Size: 5, ModTime: now},
+	}
+
+	changed := before.Changed(after)
+	if len(changed) != 2 || changed[0].Path != "modified.txt" || changed[1].Path != "out/new.csv" {
+		t.Errorf("unexpected changed files %+v", changed)
+	}
+}
+
+func TestArtifactPolicy_Match(t *testing.T) {
+	policy := domain.ArtifactPolicy{Patterns: []string{"*.csv", "out/*.png"}}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "report.csv", expected: true},
+		{path: "data/report.csv", expected: true},
+		{path: "out/chart.png", expected: true},
+		{path: "chart.png", expected: false},
+		{path: "main.go", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := policy.Match(domain.WorkspaceFile{Path: tt.path}); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
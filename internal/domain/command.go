@@ -0,0 +1,21 @@
+package domain
+
+import "strings"
+
+// SlashCommand is an invocation of the bot's slash command, e.g. "/agent ask why is CI red?"
+type SlashCommand struct {
+	TeamID    string
+	ChannelID string
+	UserID    string
+	// Command is the command name including the slash
+	Command string
+	Text    string
+	// ResponseURL replies to the command, also in channels the bot has not joined
+	ResponseURL string
+}
+
+// Subcommand splits the text into the lower-cased subcommand and its arguments
+func (c *SlashCommand) Subcommand() (string, string) {
+	name, args, _ := strings.Cut(strings.TrimSpace(c.Text), " ")
+	return strings.ToLower(name), strings.TrimSpace(args)
+}
@@ -0,0 +1,30 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestSlashCommand_Subcommand(t *testing.T) {
+	tests := []struct {
+		text     string
+		wantName string
+		wantArgs string
+	}{
+		{text: "", wantName: "", wantArgs: ""},
+		{text: "status", wantName: "status", wantArgs: ""},
+		{text: "  ASK   why is CI red? ", wantName: "ask", wantArgs: "why is CI red?"},
+		{text: "ask Keep  Case", wantName: "ask", wantArgs: "Keep  Case"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			command := &domain.SlashCommand{Command: "/agent", Text: tt.text}
+			name, args := command.Subcommand()
+			if name != tt.wantName || args != tt.wantArgs {
+				t.Errorf("expected (%q, %q), got (%q, %q)", tt.wantName, tt.wantArgs, name, args)
+			}
+		})
+	}
+}
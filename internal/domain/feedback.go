@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// ResponseSummaryLength is the number of characters of an answer kept for feedback
+const ResponseSummaryLength = 500
+
+// Rating is a user's verdict on an answer
+type Rating string
+
+const (
+	// RatingGood means the answer was helpful
+	RatingGood Rating = "good"
+	// RatingBad means the answer was not helpful
+	RatingBad Rating = "bad"
+)
+
+// FeedbackSource tells how the feedback was given
+type FeedbackSource string
+
+const (
+	// FeedbackReaction is feedback given with a 👍/👎 reaction on the bot's message
+	FeedbackReaction FeedbackSource = "reaction"
+	// FeedbackButton is feedback given with the 👍/👎 buttons
+	FeedbackButton FeedbackSource = "button"
+)
+
+// RatingFromReaction returns the rating expressed by a reaction, ignoring skin tones
+func RatingFromReaction(name string) (Rating, bool) {
+	name, _, _ = strings.Cut(strings.Trim(name, ":"), "::")
+	switch name {
+	case "+1", "thumbsup":
+		return RatingGood, true
+	case "-1", "thumbsdown":
+		return RatingBad, true
+	default:
+		return "", false
+	}
+}
+
+// RatingFromControl returns the rating expressed by a feedback button
+func RatingFromControl(control Control) (Rating, bool) {
+	switch control {
+	case ControlGood:
+		return RatingGood, true
+	case ControlBad:
+		return RatingBad, true
+	default:
+		return "", false
+	}
+}
+
+// Feedback is a rating of an answer together with what was asked and answered
+type Feedback struct {
+	Time time.Time
+	Key  SessionKey
+	// MessageTS is the message that was rated
+	MessageTS string
+	// UserID is the user who rated the answer
+	UserID  string
+	Rating  Rating
+	Source  FeedbackSource
+	Profile string
+	// Prompt and Response are the rated answer's prompt and a summary of the answer
+	Prompt   string
+	Response string
+	// Latency is how long the agent took to answer
+	Latency time.Duration
+}
+
+// NewFeedback creates a Feedback for the rated answer; answer is nil if it is not known
+func NewFeedback(key SessionKey, answer *Answer, messageTS, userID string, rating Rating, source FeedbackSource, now time.Time) *Feedback {
+	feedback := &Feedback{
+		Time:      now,
+		Key:       key,
+		MessageTS: messageTS,
+		UserID:    userID,
+		Rating:    rating,
+		Source:    source,
+	}
+	if answer != nil {
+		feedback.Profile = answer.Profile
+		feedback.Prompt = answer.Prompt
+		feedback.Response = answer.Response
+		feedback.Latency = answer.Latency
+	}
+	return feedback
+}
+
+// Summarize shortens text to at most limit characters, marking the cut with an ellipsis
+func Summarize(text string, limit int) string {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:limit-1])) + "…"
+}
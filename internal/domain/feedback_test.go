@@ -0,0 +1,55 @@
+package domain_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestRatingFromReaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		wantRating domain.Rating
+		wantOK     bool
+	}{
+		{name: "+1", wantRating: domain.RatingGood, wantOK: true},
+		{name: "thumbsup", wantRating: domain.RatingGood, wantOK: true},
+		{name: "+1::skin-tone-3", wantRating: domain.RatingGood, wantOK: true},
+		{name: "-1", wantRating: domain.RatingBad, wantOK: true},
+		{name: "thumbsdown", wantRating: domain.RatingBad, wantOK: true},
+		{name: "octagonal_sign"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rating, ok := domain.RatingFromReaction(tt.name)
+			if rating != tt.wantRating || ok != tt.wantOK {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.wantRating, tt.wantOK, rating, ok)
+			}
+		})
+	}
+}
+
+func TestNewFeedback(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	session := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}, start)
+	session.Start(start)
+	session.Finish(domain.NewAgentResult("because "+strings.Repeat("x", 1000), nil), start.Add(5*time.Second))
+	if n := len([]rune(session.LastResponse)); n != domain.ResponseSummaryLength || !strings.HasSuffix(session.LastResponse, "…") {
+		t.Errorf("expected a %d character summary, got %d characters", domain.ResponseSummaryLength, n)
+	}
+
+	answer := &domain.Answer{MessageTS: []string{"1.2"}, Prompt: "why?", Response: session.LastResponse, Latency: session.LastLatency, Profile: "sre"}
+	feedback := domain.NewFeedback(session.Key, answer, "1.2", "U1", domain.RatingBad, domain.FeedbackReaction, start.Add(time.Minute))
+	if feedback.Key != session.Key || feedback.Prompt != "why?" || feedback.Latency != 5*time.Second || feedback.Profile != "sre" {
+		t.Errorf("unexpected feedback: %+v", feedback)
+	}
+
+	// Ratings of answers that are not remembered are kept without them
+	feedback = domain.NewFeedback(session.Key, nil, "1.2", "U1", domain.RatingBad, domain.FeedbackReaction, start.Add(time.Minute))
+	if feedback.Prompt != "" || feedback.Rating != domain.RatingBad {
+		t.Errorf("unexpected feedback: %+v", feedback)
+	}
+}
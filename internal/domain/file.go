@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"path"
+	"strings"
+)
+
+// File is a file shared in a Slack message
+type File struct {
+	ID       string
+	Name     string
+	MimeType string
+	Size     int64
+	// URL is the private download URL, which requires the bot token
+	URL string
+}
+
+// AttachmentPolicy limits which shared files are handed to the agent
+type AttachmentPolicy struct {
+	// MaxBytes is the largest file accepted; zero means no limit
+	MaxBytes int64
+	// AllowedTypes are MIME types such as "text/plain" or patterns such as "image/*".
+	// An empty list accepts every type.
+	AllowedTypes []string
+}
+
+// Check returns why the file is not accepted, or an empty string if it is
+func (p AttachmentPolicy) Check(file File) string {
+	if p.MaxBytes > 0 && file.Size > p.MaxBytes {
+		return "the file is too large"
+	}
+	if len(p.AllowedTypes) == 0 {
+		return ""
+	}
+	mimeType := strings.ToLower(strings.TrimSpace(file.MimeType))
+	for _, pattern := range p.AllowedTypes {
+		if ok, _ := path.Match(strings.ToLower(strings.TrimSpace(pattern)), mimeType); ok {
+			return ""
+		}
+	}
+	if mimeType == "" {
+		mimeType = "unknown"
+	}
+	return "files of type " + mimeType + " are not accepted"
+}
+
+// LocalName returns a file name that is safe to use on disk and unique within the session
+func (f File) LocalName() string {
+	return pathSegment(f.ID) + "-" + pathSegment(f.Name)
+}
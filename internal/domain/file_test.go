@@ -0,0 +1,41 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestAttachmentPolicy_Check(t *testing.T) {
+	policy := domain.AttachmentPolicy{MaxBytes: 1024, AllowedTypes: []string{"text/*", "application/json"}}
+
+	tests := []struct {
+		name     string
+		policy   domain.AttachmentPolicy
+		file     domain.File
+		accepted bool
+	}{
+		{name: "text", policy: policy, file: domain.File{MimeType: "text/plain", Size: 100}, accepted: true},
+		{name: "exact type", policy: policy, file: domain.File{MimeType: "application/json", Size: 100}, accepted: true},
+		{name: "type not allowed", policy: policy, file: domain.File{MimeType: "application/zip", Size: 100}},
+		{name: "unknown type", policy: policy, file: domain.File{Size: 100}},
+		{name: "too large", policy: policy, file: domain.File{MimeType: "text/plain", Size: 2048}},
+		{name: "no limits", file: domain.File{MimeType: "application/zip", Size: 1 << 30}, accepted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := tt.policy.Check(tt.file)
+			if accepted := reason == ""; accepted != tt.accepted {
+				t.Errorf("expected accepted %v, got reason %q", tt.accepted, reason)
+			}
+		})
+	}
+}
+
+func TestFile_LocalName(t *testing.T) {
+	file := domain.File{ID: "F123", Name: "../my report (1).txt"}
+	if name := file.LocalName(); name != "F123-.._my_report__1_.txt" {
+		t.Errorf("unexpected local name %q", name)
+	}
+}
@@ -0,0 +1,9 @@
+package domain
+
+// Handoff is an event passed on to the replica that owns the event's thread.
+// Exactly one of the fields is set.
+type Handoff struct {
+	Message  *Message  `json:"message,omitempty"`
+	Reaction *Reaction `json:"reaction,omitempty"`
+	Action   *Action   `json:"action,omitempty"`
+}
@@ -7,11 +7,14 @@ import (
 // Message represents a Slack message
 type Message struct {
 	ID        string
+	TeamID    string
 	UserID    string
 	ChannelID string
 	Text      string
 	ThreadTS  string
 	Timestamp time.Time
+	// Files are the files shared with the message
+	Files []File
 }
 
 // NewMessage creates a new Message instance
@@ -25,3 +28,8 @@ func NewMessage(id, userID, channelID, text, threadTS string, timestamp time.Tim
 		Timestamp: timestamp,
 	}
 }
+
+// SessionKey returns the key of the agent session the message belongs to
+func (m *Message) SessionKey() SessionKey {
+	return SessionKey{TeamID: m.TeamID, ChannelID: m.ChannelID, ThreadTS: m.ThreadTS}
+}
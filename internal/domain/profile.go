@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultProfileName is the name of the profile used in channels without their own profile
+const DefaultProfileName = "default"
+
+// Profile configures how the agent behaves in a channel
+type Profile struct {
+	Name            string
+	SystemPrompt    string
+	AllowedTools    []string
+	DisallowedTools []string
+	ExtraArgs       []string
+	Model           string
+	WorkdirTemplate string
+	// Timeout limits how long a run may take, and IdleTimeout how long it may go without output; zero disables them.
+	// They are not inherited, since zero is a valid channel setting.
+	Timeout     time.Duration
+	IdleTimeout time.Duration
+	// FollowThreads makes the bot answer every message in threads it takes part in, without a mention.
+	// It is not inherited either, since false is a valid channel setting.
+	FollowThreads bool
+	// Access restricts who may invoke the agent in the profile's channels, on top of the global policy
+	Access AccessPolicy
+	// ToolRules change the tool policy for the users they match, in order
+	ToolRules []ToolRule
+}
+
+// ToolRule allows or disallows tools for some users, e.g. Bash only for the SRE user group
+type ToolRule struct {
+	// Match lists the users, user groups and channels the rule applies to
+	Match           AccessRule
+	AllowedTools    []string
+	DisallowedTools []string
+}
+
+// WithToolRules returns a copy of the profile with the rules' tool policy merged in.
+// A rule's allowed tools are taken off the disallowed list and the other way round,
+// so that a later rule overrides an earlier one and the rules override the profile.
+func (p *Profile) WithToolRules(rules []ToolRule) *Profile {
+	merged := *p
+	merged.AllowedTools = slices.Clone(p.AllowedTools)
+	merged.DisallowedTools = slices.Clone(p.DisallowedTools)
+	for _, rule := range rules {
+		for _, tool := range rule.AllowedTools {
+			merged.DisallowedTools = slices.DeleteFunc(merged.DisallowedTools, func(t string) bool { return t == tool })
+			if !slices.Contains(merged.AllowedTools, tool) {
+				merged.AllowedTools = append(merged.AllowedTools, tool)
+			}
+		}
+		for _, tool := range rule.DisallowedTools {
+			merged.AllowedTools = slices.DeleteFunc(merged.AllowedTools, func(t string) bool { return t == tool })
+			if !slices.Contains(merged.DisallowedTools, tool) {
+				merged.DisallowedTools = append(merged.DisallowedTools, tool)
+			}
+		}
+	}
+	return &merged
+}
+
+// Profiles maps channels to their agent profile
+type Profiles struct {
+	defaults *Profile
+	channels map[string]*Profile
+}
+
+// NewProfiles creates a new Profiles instance.
+// Channels are keyed by channel ID or channel name, with or without a leading '#'.
+// Empty settings of a channel profile are inherited from the defaults; the tool lists are
+// inherited together, so a profile that sets either list replaces the default tool policy.
+func NewProfiles(defaults Profile, channels map[string]Profile) *Profiles {
+	if defaults.Name == "" {
+		defaults.Name = DefaultProfileName
+	}
+
+	p := &Profiles{
+		defaults: &defaults,
+		channels: make(map[string]*Profile, len(channels)),
+	}
+	for key, profile := range channels {
+		key = normalizeChannelKey(key)
+		if profile.Name == "" {
+			profile.Name = key
+		}
+		if profile.SystemPrompt == "" {
+			profile.SystemPrompt = defaults.SystemPrompt
+		}
+		if len(profile.AllowedTools) == 0 && len(profile.DisallowedTools) == 0 {
+			profile.AllowedTools = defaults.AllowedTools
+			profile.DisallowedTools = defaults.DisallowedTools
+		}
+		if len(profile.ExtraArgs) == 0 {
+			profile.ExtraArgs = defaults.ExtraArgs
+		}
+		if profile.Model == "" {
+			profile.Model = defaults.Model
+		}
+		if profile.WorkdirTemplate == "" {
+			profile.WorkdirTemplate = defaults.WorkdirTemplate
+		}
+		p.channels[key] = &profile
+	}
+	return p
+}
+
+// Default returns the profile used in channels without their own profile
+func (p *Profiles) Default() *Profile {
+	return p.defaults
+}
+
+// Lookup returns the profile for the channel, matched by ID first and then by name.
+// It falls back to the default profile.
+func (p *Profiles) Lookup(channelID, channelName string) *Profile {
+	if profile, ok := p.channels[normalizeChannelKey(channelID)]; ok && channelID != "" {
+		return profile
+	}
+	if profile, ok := p.channels[normalizeChannelKey(channelName)]; ok && channelName != "" {
+		return profile
+	}
+	return p.defaults
+}
+
+// HasChannelProfiles reports whether any channel has its own profile
+func (p *Profiles) HasChannelProfiles() bool {
+	return len(p.channels) > 0
+}
+
+// HasToolRules reports whether any profile has tool rules
+func (p *Profiles) HasToolRules() bool {
+	if len(p.defaults.ToolRules) > 0 {
+		return true
+	}
+	for _, profile := range p.channels {
+		if len(profile.ToolRules) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeChannelKey makes channel IDs and names comparable.
+// Configuration keys are case-insensitive, so keys are compared in lower case.
+func normalizeChannelKey(key string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(key), "#"))
+}
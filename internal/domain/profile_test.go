@@ -0,0 +1,115 @@
+package domain_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestProfiles_Lookup(t *testing.T) {
+	profiles := domain.NewProfiles(
+		domain.Profile{
+			SystemPrompt:    "default prompt",
+			DisallowedTools: []string{"Bash", "Edit"},
+			ExtraArgs:       []string{"--max-turns", "5"},
+		},
+		map[string]domain.Profile{
+			"#infra":     {SystemPrompt: "infra prompt", AllowedTools: []string{"Bash"}},
+			"C0123ABCDE": {Model: "opus"},
+		},
+	)
+
+	tests := []struct {
+		name            string
+		channelID       string
+		channelName     string
+		expectedName    string
+		expectedPrompt  string
+		expectedAllowed string
+		expectedDenied  string
+		expectedModel   string
+	}{
+		{
+			name:           "no profile",
+			channelID:      "C999",
+			channelName:    "random",
+			expectedName:   "default",
+			expectedPrompt: "default prompt",
+			expectedDenied: "Bash,Edit",
+		},
+		{
+			name:            "by name",
+			channelID:       "C111",
+			channelName:     "infra",
+			expectedName:    "infra",
+			expectedPrompt:  "infra prompt",
+			expectedAllowed: "Bash",
+		},
+		{
+			name:           "by id inherits defaults",
+			channelID:      "C0123ABCDE",
+			expectedName:   "c0123abcde",
+			expectedPrompt: "default prompt",
+			expectedDenied: "Bash,Edit",
+			expectedModel:  "opus",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := profiles.Lookup(tt.channelID, tt.channelName)
+			if profile.Name != tt.expectedName {
+				t.Errorf("expected profile %q, got %q", tt.expectedName, profile.Name)
+			}
+			if profile.SystemPrompt != tt.expectedPrompt {
+				t.Errorf("expected prompt %q, got %q", tt.expectedPrompt, profile.SystemPrompt)
+			}
+			if got := strings.Join(profile.AllowedTools, ","); got != tt.expectedAllowed {
+				t.Errorf("expected allowed tools %q, got %q", tt.expectedAllowed, got)
+			}
+			if got := strings.Join(profile.DisallowedTools, ","); got != tt.expectedDenied {
+				t.Errorf("expected disallowed tools %q, got %q", tt.expectedDenied, got)
+			}
+			if profile.Model != tt.expectedModel {
+				t.Errorf("expected model %q, got %q", tt.expectedModel, profile.Model)
+			}
+			if strings.Join(profile.ExtraArgs, " ") != "--max-turns 5" {
+				t.Errorf("expected extra args to be inherited, got %v", profile.ExtraArgs)
+			}
+		})
+	}
+}
+
+func TestProfiles_KeepsChannelTimeouts(t *testing.T) {
+	profiles := domain.NewProfiles(
+		domain.Profile{Timeout: time.Hour, IdleTimeout: 5 * time.Minute},
+		map[string]domain.Profile{"#infra": {Timeout: 2 * time.Hour}},
+	)
+
+	// Zero disables a limit in the channel instead of inheriting the default
+	profile := profiles.Lookup("C111", "infra")
+	if profile.Timeout != 2*time.Hour {
+		t.Errorf("expected the channel timeout, got %s", profile.Timeout)
+	}
+	if profile.IdleTimeout != 0 {
+		t.Errorf("expected no idle timeout, got %s", profile.IdleTimeout)
+	}
+}
+
+func TestProfile_WithToolRules(t *testing.T) {
+	profile := &domain.Profile{Name: "sre", AllowedTools: []string{"Read"}, DisallowedTools: []string{"Bash", "Write"}}
+
+	merged := profile.WithToolRules([]domain.ToolRule{
+		{AllowedTools: []string{"Bash"}},
+		{DisallowedTools: []string{"Read"}},
+	})
+	if !slices.Equal(merged.AllowedTools, []string{"Bash"}) || !slices.Equal(merged.DisallowedTools, []string{"Write", "Read"}) {
+		t.Errorf("unexpected tool policy: allowed %v, disallowed %v", merged.AllowedTools, merged.DisallowedTools)
+	}
+	if merged.Name != "sre" || !slices.Equal(profile.DisallowedTools, []string{"Bash", "Write"}) {
+		t.Errorf("expected a copy that leaves the profile unchanged, got %+v", profile)
+	}
+}
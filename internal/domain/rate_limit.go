@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimit allows Requests requests per Per, refilled continuously.
+// The zero value means no limit.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// ParseRateLimit parses a limit such as "20/1h". An empty string means no limit.
+func ParseRateLimit(s string) (RateLimit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return RateLimit{}, nil
+	}
+
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected <requests>/<duration>", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: requests must be a positive number", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: duration must be positive, e.g. 1h", s)
+	}
+	return RateLimit{Requests: n, Per: d}, nil
+}
+
+// IsZero reports whether the limit is disabled
+func (l RateLimit) IsZero() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// String returns the limit in words, e.g. "20 requests per hour"
+func (l RateLimit) String() string {
+	per := l.Per.String()
+	switch {
+	case l.Per == time.Hour:
+		per = "hour"
+	case l.Per == time.Minute:
+		per = "minute"
+	case l.Per == 24*time.Hour:
+		per = "day"
+	case l.Per%time.Hour == 0:
+		per = fmt.Sprintf("%d hours", l.Per/time.Hour)
+	case l.Per%time.Minute == 0:
+		per = fmt.Sprintf("%d minutes", l.Per/time.Minute)
+	}
+	return fmt.Sprintf("%d requests per %s", l.Requests, per)
+}
+
+// TokenBucket holds the requests left under a rate limit.
+// A bucket starts full and refills at Requests/Per, up to Requests tokens.
+type TokenBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// NewTokenBucket creates a full TokenBucket
+func NewTokenBucket(limit RateLimit, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		Tokens:    float64(limit.Requests),
+		UpdatedAt: now,
+	}
+}
+
+// Refill adds the tokens earned since the last update
+func (b *TokenBucket) Refill(limit RateLimit, now time.Time) {
+	if elapsed := now.Sub(b.UpdatedAt); elapsed > 0 {
+		b.Tokens += elapsed.Seconds() * float64(limit.Requests) / limit.Per.Seconds()
+	}
+	b.Tokens = math.Min(b.Tokens, float64(limit.Requests))
+	b.UpdatedAt = now
+}
+
+// RetryAfter returns how long until a request can be taken, or zero if one can be taken now
+func (b *TokenBucket) RetryAfter(limit RateLimit) time.Duration {
+	if b.Tokens >= 1 {
+		return 0
+	}
+	missing := 1 - b.Tokens
+	return time.Duration(math.Ceil(missing * float64(limit.Per) / float64(limit.Requests)))
+}
+
+// Take uses one request from the bucket
+func (b *TokenBucket) Take() {
+	b.Tokens--
+}
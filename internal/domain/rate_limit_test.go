@@ -0,0 +1,68 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestParseRateLimit(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    domain.RateLimit
+		expectedStr string
+		expectError bool
+	}{
+		{input: "", expected: domain.RateLimit{}},
+		{input: "20/1h", expected: domain.RateLimit{Requests: 20, Per: time.Hour}, expectedStr: "20 requests per hour"},
+		{input: " 5 / 30m ", expected: domain.RateLimit{Requests: 5, Per: 30 * time.Minute}, expectedStr: "5 requests per 30 minutes"},
+		{input: "100/24h", expected: domain.RateLimit{Requests: 100, Per: 24 * time.Hour}, expectedStr: "100 requests per day"},
+		{input: "20", expectError: true},
+		{input: "0/1h", expectError: true},
+		{input: "20/soon", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			limit, err := domain.ParseRateLimit(tt.input)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if limit != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, limit)
+			}
+			if tt.expectedStr != "" && limit.String() != tt.expectedStr {
+				t.Errorf("expected %q, got %q", tt.expectedStr, limit.String())
+			}
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	limit := domain.RateLimit{Requests: 2, Per: time.Hour}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := domain.NewTokenBucket(limit, now)
+
+	bucket.Take()
+	bucket.Take()
+	if retry := bucket.RetryAfter(limit); retry != 30*time.Minute {
+		t.Errorf("expected to retry in 30 minutes, got %v", retry)
+	}
+
+	bucket.Refill(limit, now.Add(15*time.Minute))
+	if retry := bucket.RetryAfter(limit); retry != 15*time.Minute {
+		t.Errorf("expected to retry in 15 minutes, got %v", retry)
+	}
+
+	bucket.Refill(limit, now.Add(10*time.Hour))
+	if bucket.Tokens != 2 {
+		t.Errorf("expected the bucket to refill up to its capacity, got %v", bucket.Tokens)
+	}
+}
@@ -0,0 +1,27 @@
+package domain
+
+// Reaction represents an emoji reaction added to a Slack message
+type Reaction struct {
+	TeamID    string
+	UserID    string
+	ChannelID string
+	ItemTS    string
+	// ItemUserID is the author of the message that was reacted to
+	ItemUserID string
+	Name       string
+}
+
+// NewReaction creates a new Reaction instance
+func NewReaction(userID, channelID, itemTS, name string) *Reaction {
+	return &Reaction{
+		UserID:    userID,
+		ChannelID: channelID,
+		ItemTS:    itemTS,
+		Name:      name,
+	}
+}
+
+// SessionKey returns the key of the session whose thread root was reacted to
+func (r *Reaction) SessionKey() SessionKey {
+	return SessionKey{TeamID: r.TeamID, ChannelID: r.ChannelID, ThreadTS: r.ItemTS}
+}
@@ -0,0 +1,24 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestNewReaction(t *testing.T) {
+	reaction := domain.NewReaction("U123", "C456", "1234567890.123456", "octagonal_sign")
+
+	if reaction.UserID != "U123" {
+		t.Errorf("expected UserID to be U123, got %s", reaction.UserID)
+	}
+	if reaction.ChannelID != "C456" {
+		t.Errorf("expected ChannelID to be C456, got %s", reaction.ChannelID)
+	}
+	if reaction.ItemTS != "1234567890.123456" {
+		t.Errorf("expected ItemTS to be 1234567890.123456, got %s", reaction.ItemTS)
+	}
+	if reaction.Name != "octagonal_sign" {
+		t.Errorf("expected Name to be octagonal_sign, got %s", reaction.Name)
+	}
+}
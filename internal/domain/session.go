@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrSessionNotFound is reported when no session is stored for a key
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionStatus describes the state of an agent session
+type SessionStatus string
+
+const (
+	// SessionIdle means the session is waiting for the next message
+	SessionIdle SessionStatus = "idle"
+	// SessionRunning means an agent is currently working in the session
+	SessionRunning SessionStatus = "running"
+	// SessionFailed means the last run of the session ended with an error
+	SessionFailed SessionStatus = "failed"
+)
+
+// SessionKey identifies a conversation with the agent.
+// Thread timestamps are only unique within a channel, so the key includes the team and channel.
+type SessionKey struct {
+	TeamID    string
+	ChannelID string
+	ThreadTS  string
+}
+
+// String returns the key in the form team/channel/thread
+func (k SessionKey) String() string {
+	return k.TeamID + "/" + k.ChannelID + "/" + k.ThreadTS
+}
+
+// Path returns a relative file path that is unique for the key
+func (k SessionKey) Path() string {
+	return filepath.Join(pathSegment(k.TeamID), pathSegment(k.ChannelID), pathSegment(k.ThreadTS))
+}
+
+// pathSegment makes an ID safe to use as a single path element
+func pathSegment(s string) string {
+	if s == "" || s == "." || s == ".." {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// maxSessionAnswers is the number of recent answers a session remembers
+const maxSessionAnswers = 20
+
+// Answer is an answer the bot posted in a thread, so that its controls and ratings refer to that answer
+type Answer struct {
+	// MessageTS are the messages the answer was posted as; the controls are on the last one
+	MessageTS []string
+	// Prompt is the prompt that was answered
+	Prompt string
+	// Response is a summary of the answer and Latency the time it took
+	Response string
+	Latency  time.Duration
+	// Profile is the profile the answer was given with
+	Profile string
+}
+
+// TS returns the message that carries the answer's controls
+func (a *Answer) TS() string {
+	if len(a.MessageTS) == 0 {
+		return ""
+	}
+	return a.MessageTS[len(a.MessageTS)-1]
+}
+
+// Session represents the agent session that backs a Slack thread
+type Session struct {
+	Key            SessionKey
+	AgentSessionID string
+	CreatedAt      time.Time
+	LastActivity   time.Time
+	MessageCount   int
+	Status         SessionStatus
+	// Workdir is the agent's working directory, resolved from the profile when the session starts
+	Workdir string
+	// Dismissed is set when a user said goodbye; the bot then only answers mentions in the thread
+	Dismissed bool
+	// LastPrompt is the text of the most recent prompt, used to regenerate the answer
+	LastPrompt string
+	// LastResponse is a summary of the most recent answer and LastLatency the time it took
+	LastResponse string
+	LastLatency  time.Duration
+	// Answers are the most recent answers in the thread, oldest first
+	Answers []Answer
+	// ControlsTS is the answer message that shows the controls and ControlsText its text,
+	// so that the controls can be taken off when the next answer gets them
+	ControlsTS   string
+	ControlsText string
+}
+
+// NewSession creates a new Session instance
+func NewSession(key SessionKey, now time.Time) *Session {
+	return &Session{
+		Key:          key,
+		CreatedAt:    now,
+		LastActivity: now,
+		Status:       SessionIdle,
+	}
+}
+
+// Start marks the session as running a new prompt
+func (s *Session) Start(now time.Time) {
+	s.Status = SessionRunning
+	s.MessageCount++
+	s.LastActivity = now
+	s.Dismissed = false
+}
+
+// Dismiss stops the bot from following the thread until it is mentioned again
+func (s *Session) Dismiss(now time.Time) {
+	s.Dismissed = true
+	s.LastActivity = now
+}
+
+// IsFollowing reports whether the bot answers messages in the thread without a mention.
+// The thread is followed until it is dismissed or idle for longer than idleTimeout; zero means no timeout.
+func (s *Session) IsFollowing(now time.Time, idleTimeout time.Duration) bool {
+	if s.Dismissed {
+		return false
+	}
+	return idleTimeout <= 0 || now.Sub(s.LastActivity) <= idleTimeout
+}
+
+// Finish records the outcome of a run.
+// The agent session ID is kept so that the next run can resume the conversation.
+func (s *Session) Finish(result *AgentResult, now time.Time) {
+	if result != nil && result.SessionID != "" {
+		s.AgentSessionID = result.SessionID
+	}
+	if result != nil {
+		s.LastResponse = Summarize(result.Response, ResponseSummaryLength)
+	}
+	s.LastLatency = now.Sub(s.LastActivity)
+	s.Status = SessionIdle
+	if result == nil || result.IsError() && !result.IsCancelled() {
+		s.Status = SessionFailed
+	}
+	s.LastActivity = now
+}
+
+// Abandon marks a run that was never finished as failed, e.g. because the bot crashed during it.
+// It reports whether the session was running.
+func (s *Session) Abandon() bool {
+	if s.Status != SessionRunning {
+		return false
+	}
+	s.Status = SessionFailed
+	return true
+}
+
+// AddAnswer remembers an answer, forgetting the oldest ones beyond the limit
+func (s *Session) AddAnswer(answer Answer) {
+	s.Answers = append(s.Answers, answer)
+	if len(s.Answers) > maxSessionAnswers {
+		s.Answers = s.Answers[len(s.Answers)-maxSessionAnswers:]
+	}
+}
+
+// FindAnswer returns the answer that was posted as the message, or nil if it is not remembered
+func (s *Session) FindAnswer(ts string) *Answer {
+	for i := len(s.Answers) - 1; i >= 0; i-- {
+		if slices.Contains(s.Answers[i].MessageTS, ts) {
+			return &s.Answers[i]
+		}
+	}
+	return nil
+}
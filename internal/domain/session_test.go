@@ -0,0 +1,105 @@
+package domain_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestSessionKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      domain.SessionKey
+		expected string
+	}{
+		{name: "full key", key: domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}, expected: "T1/C1/1.1"},
+		{name: "missing team", key: domain.SessionKey{ChannelID: "C1", ThreadTS: "1.1"}, expected: "_/C1/1.1"},
+		{name: "unsafe characters", key: domain.SessionKey{TeamID: "..", ChannelID: "C/1", ThreadTS: "1.1"}, expected: "_/C_1/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Path(); got != tt.expected {
+				t.Errorf("expected path %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSession_Lifecycle(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	session := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}, start)
+
+	session.Start(start.Add(time.Minute))
+	if session.Status != domain.SessionRunning || session.MessageCount != 1 {
+		t.Fatalf("expected running session with one message, got %+v", session)
+	}
+
+	result := domain.NewAgentResult("ok", nil)
+	result.SessionID = "sess-1"
+	session.Finish(result, start.Add(2*time.Minute))
+	if session.Status != domain.SessionIdle || session.AgentSessionID != "sess-1" {
+		t.Errorf("expected idle session with agent session ID, got %+v", session)
+	}
+	if !session.LastActivity.Equal(start.Add(2 * time.Minute)) {
+		t.Errorf("expected last activity to be updated, got %v", session.LastActivity)
+	}
+
+	session.Start(start.Add(3 * time.Minute))
+	session.Finish(domain.NewAgentResult("", errors.New("boom")), start.Add(4*time.Minute))
+	if session.Status != domain.SessionFailed || session.AgentSessionID != "sess-1" || session.MessageCount != 2 {
+		t.Errorf("expected failed session that keeps its agent session ID, got %+v", session)
+	}
+
+	session.Start(start.Add(5 * time.Minute))
+	session.Finish(domain.NewAgentResult("", domain.ErrAgentCancelled), start.Add(6*time.Minute))
+	if session.Status != domain.SessionIdle {
+		t.Errorf("expected cancelled run to leave the session idle, got %s", session.Status)
+	}
+}
+
+func TestSession_Answers(t *testing.T) {
+	session := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}, time.Now())
+	for i := 0; i < 25; i++ {
+		session.AddAnswer(domain.Answer{MessageTS: []string{fmt.Sprintf("2.%d", i), fmt.Sprintf("3.%d", i)}, Prompt: fmt.Sprintf("prompt %d", i)})
+	}
+
+	if len(session.Answers) != 20 {
+		t.Errorf("expected only the recent answers to be kept, got %d", len(session.Answers))
+	}
+	if answer := session.FindAnswer("2.10"); answer == nil || answer.Prompt != "prompt 10" || answer.TS() != "3.10" {
+		t.Errorf("expected to find the answer by any of its messages, got %+v", answer)
+	}
+	if answer := session.FindAnswer("2.1"); answer != nil {
+		t.Errorf("expected old answers to be forgotten, got %+v", answer)
+	}
+}
+
+func TestSession_IsFollowing(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := domain.NewSession(domain.SessionKey{ChannelID: "C1", ThreadTS: "1.1"}, now)
+
+	if !session.IsFollowing(now.Add(10*time.Minute), 30*time.Minute) {
+		t.Error("expected a recently active thread to be followed")
+	}
+	if session.IsFollowing(now.Add(time.Hour), 30*time.Minute) {
+		t.Error("expected an idle thread not to be followed")
+	}
+	if !session.IsFollowing(now.Add(24*time.Hour), 0) {
+		t.Error("expected no idle timeout when it is zero")
+	}
+
+	session.Dismiss(now)
+	if session.IsFollowing(now, 30*time.Minute) {
+		t.Error("expected a dismissed thread not to be followed")
+	}
+
+	// A mention starts following the thread again
+	session.Start(now)
+	if !session.IsFollowing(now, 30*time.Minute) {
+		t.Error("expected the thread to be followed again after a new run")
+	}
+}
@@ -0,0 +1,233 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+// Agent backend names accepted by ai.backend
+const (
+	BackendClaudeCLI  = "claude-cli"
+	BackendScript     = "script"
+	BackendOpenAIHTTP = "openai-compatible-http"
+)
+
+// SessionsDir is the directory that holds the working directory of each session
+const SessionsDir = "sessions"
+
+// ProcessesDir is the directory that records the process groups of running agents.
+// It is local to the host, since process IDs only refer to processes running on it.
+var ProcessesDir = filepath.Join(os.TempDir(), "slack-agent", "processes")
+
+// claudeInstructionsFile is written to the working directory with the system prompt
+const claudeInstructionsFile = "CLAUDE.md"
+
+// mentionRegex matches mention tags like <@U12345> or <@UMG0E05JR>
+var mentionRegex = regexp.MustCompile(`<@[A-Z0-9_]+>`)
+
+// AgentBackendConfig holds the settings available to every agent backend.
+// SystemPrompt, ClaudeExtraArgs and DisallowedTools are the defaults used when Profiles is nil.
+// Authorizer applies the profiles' tool rules to each user; without it they are ignored.
+type AgentBackendConfig struct {
+	SlackRepo       usecase.SlackRepository
+	Profiles        *usecase.ProfileResolver
+	Authorizer      *usecase.Authorizer
+	SystemPrompt    string
+	AgentScriptPath string
+	ClaudeExtraArgs []string
+	DisallowedTools []string
+	OpenAIAPIKey    string
+	OpenAIBaseURL   string
+	OpenAIModel     string
+	UpdateInterval  time.Duration
+	// KillGracePeriod is how long a stopped agent may take to exit after SIGTERM before it gets SIGKILL
+	KillGracePeriod time.Duration
+	Debug           bool
+}
+
+// AgentBackendFactory creates an agent backend from the shared configuration
+type AgentBackendFactory func(cfg AgentBackendConfig) (usecase.AgentRepository, error)
+
+var (
+	agentBackendsMu sync.RWMutex
+	agentBackends   = map[string]AgentBackendFactory{
+		BackendClaudeCLI: func(cfg AgentBackendConfig) (usecase.AgentRepository, error) {
+			repo := NewAgentRepository(cfg.SlackRepo, cfg.SystemPrompt, cfg.ClaudeExtraArgs, cfg.DisallowedTools)
+			repo.SetDebug(cfg.Debug)
+			repo.SetUpdateInterval(cfg.UpdateInterval)
+			repo.SetKillGracePeriod(cfg.KillGracePeriod)
+			repo.SetProfiles(cfg.profileResolver())
+			repo.SetAuthorizer(cfg.Authorizer)
+			return repo, nil
+		},
+		BackendScript: func(cfg AgentBackendConfig) (usecase.AgentRepository, error) {
+			return NewScriptAgentRepository(cfg)
+		},
+		BackendOpenAIHTTP: func(cfg AgentBackendConfig) (usecase.AgentRepository, error) {
+			return NewOpenAIAgentRepository(cfg)
+		},
+	}
+)
+
+// RegisterAgentBackend makes an agent backend available under the given name
+func RegisterAgentBackend(name string, factory AgentBackendFactory) {
+	agentBackendsMu.Lock()
+	defer agentBackendsMu.Unlock()
+	agentBackends[name] = factory
+}
+
+// NewAgentBackend creates the agent backend registered under the given name.
+// An empty name selects the claude-cli backend.
+func NewAgentBackend(name string, cfg AgentBackendConfig) (usecase.AgentRepository, error) {
+	if name == "" {
+		name = BackendClaudeCLI
+	}
+
+	agentBackendsMu.RLock()
+	factory, ok := agentBackends[name]
+	agentBackendsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown agent backend %q (available: %s)", name, strings.Join(AgentBackendNames(), ", "))
+	}
+
+	return factory(cfg)
+}
+
+// AgentBackendNames returns the names of all registered agent backends
+func AgentBackendNames() []string {
+	agentBackendsMu.RLock()
+	defer agentBackendsMu.RUnlock()
+
+	names := make([]string, 0, len(agentBackends))
+	for name := range agentBackends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileResolver returns the configured profiles or a resolver that always yields the defaults
+func (cfg AgentBackendConfig) profileResolver() *usecase.ProfileResolver {
+	if cfg.Profiles != nil {
+		return cfg.Profiles
+	}
+	return defaultProfileResolver(cfg.SystemPrompt, cfg.ClaudeExtraArgs, cfg.DisallowedTools)
+}
+
+// resolveProfile returns the profile of the message's channel with its tool rules applied to the
+// message's author. Every backend that runs tools resolves its profile this way.
+func resolveProfile(ctx context.Context, profiles *usecase.ProfileResolver, authorizer *usecase.Authorizer, message *domain.Message) *domain.Profile {
+	profile := profiles.Resolve(ctx, message.ChannelID)
+	if authorizer != nil {
+		profile = authorizer.ProfileFor(ctx, message.UserID, message.ChannelID, profile)
+	}
+	return profile
+}
+
+// defaultProfileResolver creates a resolver with only a default profile
+func defaultProfileResolver(systemPrompt string, extraArgs, disallowedTools []string) *usecase.ProfileResolver {
+	return usecase.NewProfileResolver(domain.NewProfiles(domain.Profile{
+		SystemPrompt:    systemPrompt,
+		DisallowedTools: disallowedTools,
+		ExtraArgs:       extraArgs,
+	}, nil), nil)
+}
+
+// workdirData is passed to a profile's working-directory template
+type workdirData struct {
+	TeamID      string
+	ChannelID   string
+	ThreadTS    string
+	UserID      string
+	SessionPath string
+	Profile     string
+}
+
+// sessionWorkdir returns the working directory recorded in the session, resolving it for sessions that have none
+func sessionWorkdir(profile *domain.Profile, session *domain.Session, message *domain.Message) (string, error) {
+	if session.Workdir != "" {
+		return session.Workdir, nil
+	}
+	dir, err := SessionWorkdir(SessionsDir, profile, session.Key, message.UserID)
+	if err != nil {
+		return "", err
+	}
+	session.Workdir = dir
+	return dir, nil
+}
+
+// SessionWorkdir returns the working directory of a session started by the user.
+// Without a template in the profile it is <root>/<team>/<channel>/<thread>.
+// The agent backends and the session workspace both use it so that they agree on the directory.
+func SessionWorkdir(root string, profile *domain.Profile, key domain.SessionKey, userID string) (string, error) {
+	if profile == nil || profile.WorkdirTemplate == "" {
+		return filepath.Join(root, key.Path()), nil
+	}
+
+	tmpl, err := template.New("workdir").Option("missingkey=error").Parse(profile.WorkdirTemplate)
+	if err != nil {
+		return "", fmt.Errorf("invalid workdir template in profile %s: %w", profile.Name, err)
+	}
+	var dir bytes.Buffer
+	if err := tmpl.Execute(&dir, workdirData{
+		TeamID:      key.TeamID,
+		ChannelID:   key.ChannelID,
+		ThreadTS:    key.ThreadTS,
+		UserID:      userID,
+		SessionPath: key.Path(),
+		Profile:     profile.Name,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render workdir template in profile %s: %w", profile.Name, err)
+	}
+	return filepath.Clean(dir.String()), nil
+}
+
+// loadSystemPrompt reads the system prompt from a file if a .txt path is given
+func loadSystemPrompt(systemPrompt string) string {
+	if systemPrompt == "" || !strings.HasSuffix(systemPrompt, ".txt") {
+		return systemPrompt
+	}
+
+	content, err := os.ReadFile(systemPrompt)
+	if err != nil {
+		log.Printf("Error reading system prompt file: %v", err)
+		return systemPrompt
+	}
+	return string(content)
+}
+
+// cleanMessageText removes mention tags from message text
+func cleanMessageText(text string, debug bool) string {
+	if debug {
+		log.Printf("Original message text: '%s'", text)
+	}
+
+	// Use regex to properly remove mention tags like <@U12345> or <@UMG0E05JR>
+	cleanedText := mentionRegex.ReplaceAllString(text, "")
+
+	// Trim whitespace
+	cleanedText = strings.TrimSpace(cleanedText)
+
+	if debug {
+		log.Printf("Cleaned message text: '%s'", cleanedText)
+	}
+
+	if cleanedText == "" {
+		log.Printf("WARNING: Cleaned text is empty after removing mentions from: '%s'", text)
+	}
+
+	return cleanedText
+}
@@ -0,0 +1,349 @@
+package infrastructure_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+func TestNewAgentBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		cfg     infrastructure.AgentBackendConfig
+		wantErr bool
+	}{
+		{name: "default", backend: ""},
+		{name: "claude-cli", backend: infrastructure.BackendClaudeCLI},
+		{name: "script", backend: infrastructure.BackendScript, cfg: infrastructure.AgentBackendConfig{AgentScriptPath: "/bin/true"}},
+		{name: "script without path", backend: infrastructure.BackendScript, wantErr: true},
+		{name: "openai", backend: infrastructure.BackendOpenAIHTTP, cfg: infrastructure.AgentBackendConfig{OpenAIModel: "gpt-4o"}},
+		{name: "openai without model", backend: infrastructure.BackendOpenAIHTTP, wantErr: true},
+		{name: "openai with tool rules", backend: infrastructure.BackendOpenAIHTTP, cfg: infrastructure.AgentBackendConfig{
+			OpenAIModel: "gpt-4o",
+			Profiles: usecase.NewProfileResolver(domain.NewProfiles(domain.Profile{ToolRules: []domain.ToolRule{
+				{Match: domain.AccessRule{Users: []string{"U1"}}, DisallowedTools: []string{"Bash"}},
+			}}, nil), nil),
+		}, wantErr: true},
+		{name: "unknown", backend: "nope", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, err := infrastructure.NewAgentBackend(tt.backend, tt.cfg)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if repo == nil {
+				t.Error("expected a backend")
+			}
+		})
+	}
+}
+
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent.sh")
+	if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return path
+}
+
+// testSession returns a fresh session for thread 1.1 in channel C1
+func testSession() *domain.Session {
+	return domain.NewSession(domain.SessionKey{ChannelID: "C1", ThreadTS: "1.1"}, time.Now())
+}
+
+func TestScriptAgentRepository(t *testing.T) {
+	// The script echoes the prompt it received on stdin and the thread from the environment
+	script := writeScript(t, `#!/bin/sh
+prompt=$(cat | sed -n 's/.*"prompt":"\([^"]*\)".*/\1/p')
+echo "prompt=$prompt thread=$SLACK_THREAD_TS"
+`)
+	repo, err := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{AgentScriptPath: script})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := domain.NewMessage("", "U1", "C1", "<@UBOT> hello", "1.1", time.Now())
+	result, err := repo.GenerateResponse(context.Background(), domain.NewSession(msg.SessionKey(), time.Now()), msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError() {
+		t.Fatalf("unexpected agent error: %v", result.Error)
+	}
+	if result.Response != "prompt=hello thread=1.1" {
+		t.Errorf("unexpected response: %q", result.Response)
+	}
+}
+
+func TestScriptAgentRepository_Profile(t *testing.T) {
+	script := writeScript(t, `#!/bin/sh
+echo "$SLACK_AGENT_PROFILE|$ALLOWED_TOOLS|$DISALLOWED_TOOLS|$CLAUDE_MODEL|$SLACK_AGENT_WORKDIR|$SYSTEM_PROMPT"
+`)
+	profiles := domain.NewProfiles(
+		domain.Profile{SystemPrompt: "default", DisallowedTools: []string{"Bash"}},
+		map[string]domain.Profile{
+			"C1": {SystemPrompt: "infra", AllowedTools: []string{"Bash", "Read"}, Model: "opus", WorkdirTemplate: "work/{{.Profile}}/{{.ThreadTS}}"},
+		},
+	)
+	repo, err := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{
+		AgentScriptPath: script,
+		Profiles:        usecase.NewProfileResolver(profiles, nil),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		channelID string
+		expected  string
+	}{
+		{channelID: "C1", expected: "c1|Bash,Read||opus|work/c1/1.1|infra"},
+		{channelID: "C2", expected: "default||Bash||sessions/_/C2/1.1|default"},
+	}
+	for _, tt := range tests {
+		msg := domain.NewMessage("", "U1", tt.channelID, "hi", "1.1", time.Now())
+		result, err := repo.GenerateResponse(context.Background(), domain.NewSession(msg.SessionKey(), time.Now()), msg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Response != tt.expected {
+			t.Errorf("expected %q for channel %s, got %q", tt.expected, tt.channelID, result.Response)
+		}
+	}
+}
+
+func TestScriptAgentRepository_ToolRules(t *testing.T) {
+	script := writeScript(t, `#!/bin/sh
+echo "$ALLOWED_TOOLS|$DISALLOWED_TOOLS"
+`)
+	profiles := domain.NewProfiles(domain.Profile{
+		AllowedTools: []string{"Bash", "Read"},
+		ToolRules: []domain.ToolRule{
+			{Match: domain.AccessRule{Users: []string{"U2"}}, DisallowedTools: []string{"Bash"}},
+		},
+	}, nil)
+	repo, err := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{
+		AgentScriptPath: script,
+		Profiles:        usecase.NewProfileResolver(profiles, nil),
+		Authorizer:      usecase.NewAuthorizer(domain.AccessPolicy{}, nil),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The script gets the tools as adjusted for the user
+	tests := []struct {
+		userID   string
+		expected string
+	}{
+		{userID: "U1", expected: "Bash,Read|"},
+		{userID: "U2", expected: "Read|Bash"},
+	}
+	for _, tt := range tests {
+		msg := domain.NewMessage("", tt.userID, "C1", "hi", "1.1", time.Now())
+		result, err := repo.GenerateResponse(context.Background(), domain.NewSession(msg.SessionKey(), time.Now()), msg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Response != tt.expected {
+			t.Errorf("expected %q for user %s, got %q", tt.expected, tt.userID, result.Response)
+		}
+	}
+}
+
+func TestScriptAgentRepository_Failure(t *testing.T) {
+	script := writeScript(t, "#!/bin/sh\necho 'something broke' >&2\nexit 3\n")
+	repo, _ := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{AgentScriptPath: script})
+
+	result, err := repo.GenerateResponse(context.Background(), testSession(), domain.NewMessage("", "U1", "C1", "hi", "1.1", time.Now()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError() || !strings.Contains(result.Error.Error(), "something broke") {
+		t.Errorf("expected error with stderr, got %v", result.Error)
+	}
+}
+
+func TestScriptAgentRepository_Cancel(t *testing.T) {
+	script := writeScript(t, "#!/bin/sh\nexec sleep 10\n")
+	repo, _ := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{AgentScriptPath: script})
+
+	go func() {
+		for !repo.CancelSession(testSession().Key) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	result, err := repo.GenerateResponse(context.Background(), testSession(), domain.NewMessage("", "U1", "C1", "hi", "1.1", time.Now()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsCancelled() {
+		t.Errorf("expected cancelled result, got %v", result.Error)
+	}
+}
+
+func TestScriptAgentRepository_Timeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  domain.Profile
+		expected string
+	}{
+		{name: "wall clock", profile: domain.Profile{Timeout: 300 * time.Millisecond}, expected: "no answer within 300ms"},
+		{name: "idle", profile: domain.Profile{IdleTimeout: 300 * time.Millisecond}, expected: "no output for 300ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := writeScript(t, "#!/bin/sh\necho 'partial answer'\nexec sleep 10\n")
+			repo, _ := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{
+				AgentScriptPath: script,
+				Profiles:        usecase.NewProfileResolver(domain.NewProfiles(tt.profile, nil), nil),
+				KillGracePeriod: time.Second,
+			})
+
+			start := time.Now()
+			result, err := repo.GenerateResponse(context.Background(), testSession(), domain.NewMessage("", "U1", "C1", "hi", "1.1", time.Now()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("expected the run to be stopped, took %s", elapsed)
+			}
+			if result.AgentErrorKind() != domain.AgentErrorTimeout || result.Error.Error() != tt.expected {
+				t.Errorf("expected timeout %q, got %v", tt.expected, result.Error)
+			}
+			if result.Response != "partial answer" {
+				t.Errorf("expected the partial output to be kept, got %q", result.Response)
+			}
+		})
+	}
+}
+
+func TestScriptAgentRepository_StopsProcessGroup(t *testing.T) {
+	// The background sleep inherits stdout, so the run only ends early if it is killed too
+	script := writeScript(t, "#!/bin/sh\nsleep 10 &\nexec sleep 10\n")
+	repo, _ := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{
+		AgentScriptPath: script,
+		KillGracePeriod: 5 * time.Second,
+	})
+
+	go func() {
+		for repo.CancelAll() == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	result, err := repo.GenerateResponse(context.Background(), testSession(), domain.NewMessage("", "U1", "C1", "hi", "1.1", time.Now()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsCancelled() {
+		t.Errorf("expected cancelled result, got %v", result.Error)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("expected the whole process group to be stopped, took %s", elapsed)
+	}
+}
+
+func TestOpenAIAgentRepository(t *testing.T) {
+	var requests []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer test-key" {
+			t.Errorf("unexpected authorization header %q", r.Header.Get("Authorization"))
+		}
+
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"  hi there  "}}]}`))
+	}))
+	defer server.Close()
+
+	repo, err := infrastructure.NewOpenAIAgentRepository(infrastructure.AgentBackendConfig{
+		OpenAIAPIKey:  "test-key",
+		OpenAIBaseURL: server.URL + "/v1/",
+		OpenAIModel:   "test-model",
+		SystemPrompt:  "be nice",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, text := range []string{"<@UBOT> hello", "again"} {
+		result, err := repo.GenerateResponse(context.Background(), testSession(), domain.NewMessage("", "U1", "C1", text, "1.1", time.Now()))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Response != "hi there" {
+			t.Errorf("unexpected response: %q", result.Response)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if requests[0]["model"] != "test-model" {
+		t.Errorf("unexpected model %v", requests[0]["model"])
+	}
+	// system + history (user, assistant) + new prompt
+	if messages := requests[1]["messages"].([]any); len(messages) != 4 {
+		t.Errorf("expected follow-up to include the conversation, got %d messages", len(messages))
+	}
+
+	// A reset thread starts over
+	repo.ForgetSession(testSession().Key)
+	if _, err := repo.GenerateResponse(context.Background(), testSession(), domain.NewMessage("", "U1", "C1", "fresh", "1.1", time.Now())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if messages := requests[2]["messages"].([]any); len(messages) != 2 {
+		t.Errorf("expected the forgotten conversation to be left out, got %d messages", len(messages))
+	}
+}
+
+func TestOpenAIAgentRepository_ErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"message":"invalid api key"}}`))
+	}))
+	defer server.Close()
+
+	repo, _ := infrastructure.NewOpenAIAgentRepository(infrastructure.AgentBackendConfig{
+		OpenAIBaseURL: server.URL,
+		OpenAIModel:   "test-model",
+	})
+
+	result, err := repo.GenerateResponse(context.Background(), testSession(), domain.NewMessage("", "U1", "C1", "hi", "1.1", time.Now()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError() || !strings.Contains(result.Error.Error(), "invalid api key") {
+		t.Errorf("expected API error, got %v", result.Error)
+	}
+}
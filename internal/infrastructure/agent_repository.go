@@ -1,40 +1,41 @@
 package infrastructure
 
 import (
-	"bufio"
+	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"time"
 
 	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
 )
 
 // AgentRepositoryImpl implements the AgentRepository interface
 type AgentRepositoryImpl struct {
-	systemPrompt    string
-	agentScriptPath string
-	claudeExtraArgs []string
-	disallowedTools []string
-	slackBotToken   string
-	debug           bool
+	slackRepo      usecase.SlackRepository
+	profiles       *usecase.ProfileResolver
+	authorizer     *usecase.Authorizer
+	updateInterval time.Duration
+	killGrace      time.Duration
+	debug          bool
+	runs           *runRegistry
 }
 
 // NewAgentRepository creates a new AgentRepository instance
-func NewAgentRepository(systemPrompt, agentScriptPath string, claudeExtraArgs []string, disallowedTools []string) *AgentRepositoryImpl {
+func NewAgentRepository(slackRepo usecase.SlackRepository, systemPrompt string, claudeExtraArgs []string, disallowedTools []string) *AgentRepositoryImpl {
 	return &AgentRepositoryImpl{
-		systemPrompt:    systemPrompt,
-		agentScriptPath: agentScriptPath,
-		claudeExtraArgs: claudeExtraArgs,
-		disallowedTools: disallowedTools,
-		slackBotToken:   os.Getenv("SLACK_BOT_TOKEN"),
-		debug:           false,
+		slackRepo:      slackRepo,
+		profiles:       defaultProfileResolver(systemPrompt, claudeExtraArgs, disallowedTools),
+		updateInterval: DefaultUpdateInterval,
+		killGrace:      DefaultKillGracePeriod,
+		debug:          false,
+		runs:           newRunRegistry(),
 	}
 }
 
@@ -43,235 +44,211 @@ func (r *AgentRepositoryImpl) SetDebug(debug bool) {
 	r.debug = debug
 }
 
-// StreamMessage represents a Claude stream output message
-type StreamMessage struct {
-	Type    string `json:"type"`
-	Content string `json:"content,omitempty"`
-	Text    string `json:"text,omitempty"`
-}
-
-// GenerateResponse generates a response using the AI agent
-func (r *AgentRepositoryImpl) GenerateResponse(ctx context.Context, message *domain.Message) (*domain.AgentResult, error) {
-	// Create session directory
-	sessionDir := filepath.Join("sessions", message.ThreadTS)
-	if err := os.MkdirAll(sessionDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create session directory: %w", err)
-	}
-
-	// Load system prompt from file if path is provided
-	systemPrompt := r.systemPrompt
-	if r.systemPrompt != "" && strings.HasSuffix(r.systemPrompt, ".txt") {
-		content, err := os.ReadFile(r.systemPrompt)
-		if err != nil {
-			log.Printf("Error reading system prompt file: %v", err)
-		} else {
-			systemPrompt = string(content)
-		}
+// SetUpdateInterval sets the minimum time between edits of the live message
+func (r *AgentRepositoryImpl) SetUpdateInterval(interval time.Duration) {
+	if interval > 0 {
+		r.updateInterval = interval
 	}
+}
 
-	// Save system prompt to CLAUDE.md in session directory
-	if systemPrompt != "" {
-		claudeMdPath := filepath.Join(sessionDir, "CLAUDE.md")
-		if err := os.WriteFile(claudeMdPath, []byte(systemPrompt), 0644); err != nil {
-			log.Printf("Error writing CLAUDE.md: %v", err)
-		}
+// SetKillGracePeriod sets how long a stopped agent may take to exit before it is killed
+func (r *AgentRepositoryImpl) SetKillGracePeriod(grace time.Duration) {
+	if grace > 0 {
+		r.killGrace = grace
 	}
+}
 
-	// Clean message text by removing mention
-	cleanedText := r.cleanMessageText(message.Text)
-
-	// Build Claude command arguments
-	args := []string{
-		"exec", "--",
-		"claude", "-c",
-		"--output-format", "stream-json",
-		"--dangerously-skip-permissions",
-		"-p", "--verbose",
-	}
+// SetProfiles sets the per-channel profiles that replace the defaults given to NewAgentRepository
+func (r *AgentRepositoryImpl) SetProfiles(profiles *usecase.ProfileResolver) {
+	r.profiles = profiles
+}
 
-	// Add disallowed tools if provided
-	if len(r.disallowedTools) > 0 {
-		args = append(args, "--disallowedTools", strings.Join(r.disallowedTools, ","))
-	}
+// SetAuthorizer sets the authorizer that applies the profiles' tool rules to each user
+func (r *AgentRepositoryImpl) SetAuthorizer(authorizer *usecase.Authorizer) {
+	r.authorizer = authorizer
+}
 
-	// Add extra arguments if provided
-	args = append(args, r.claudeExtraArgs...)
-	args = append(args, "--print")
+// CancelSession cancels the agent running in the given session.
+// It reports whether an agent was running.
+func (r *AgentRepositoryImpl) CancelSession(key domain.SessionKey) bool {
+	return r.runs.cancel(key.String())
+}
 
-	// Add the prompt as the last argument
-	args = append(args, cleanedText)
+// CancelAll cancels every running agent, stopping their process groups
+func (r *AgentRepositoryImpl) CancelAll() int {
+	return r.runs.cancelAll()
+}
 
-	// Log the full command for debugging (only if debug is enabled)
-	if r.debug {
-		log.Printf("Executing Claude command: mise %s", strings.Join(args, " "))
-		log.Printf("Claude prompt text: '%s'", cleanedText)
-		log.Printf("Working directory: %s", sessionDir)
-	}
+// ForgetSession does nothing; Claude keeps the conversation under the session ID stored with the session
+func (r *AgentRepositoryImpl) ForgetSession(key domain.SessionKey) {}
 
-	// Create the command to run Claude through mise
-	cmd := exec.CommandContext(ctx, "mise", args...)
-	cmd.Dir = sessionDir
+// GenerateResponse runs Claude and streams its output to the Slack thread
+func (r *AgentRepositoryImpl) GenerateResponse(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+	// Use the channel's profile, or the defaults
+	profile := resolveProfile(ctx, r.profiles, r.authorizer, message)
 
-	// Set environment variables
-	env := os.Environ()
-	if systemPrompt != "" {
-		env = append(env, fmt.Sprintf("SYSTEM_PROMPT=%s", systemPrompt))
-		if r.debug {
-			log.Printf("Setting SYSTEM_PROMPT environment variable (length: %d)", len(systemPrompt))
-		}
-	}
-	env = append(env, fmt.Sprintf("SLACK_AGENT_PROMPT=%s", cleanedText))
-	if r.debug {
-		log.Printf("Setting SLACK_AGENT_PROMPT environment variable: '%s'", cleanedText)
-	}
-	cmd.Env = env
+	// Register the run so that it can be cancelled from Slack
+	ctx, cancel := runContext(ctx, profile)
+	defer cancel(nil)
+	defer r.runs.register(session.Key.String(), func() { cancel(nil) })()
 
-	// Create pipes for claude-posts command
-	claudePipe, err := cmd.StdoutPipe()
+	cmd, err := r.prepareCommand(ctx, profile, session, message)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+		return nil, err
 	}
+	defer superviseProcess(cmd, r.killGrace)()
 
-	stderr, err := cmd.StderrPipe()
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
 
 	// Start Claude command
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start claude: %w", err)
-	}
-
-	// Create claude-posts command
-	postsArgs := []string{
-		fmt.Sprintf("--bot-token=%s", r.slackBotToken),
-		fmt.Sprintf("--channel-id=%s", message.ChannelID),
-		fmt.Sprintf("--thread-ts=%s", message.ThreadTS),
+		return domain.NewAgentResult("", &domain.AgentError{Kind: domain.AgentErrorStart, Message: "failed to start claude", Err: err}), nil
 	}
+	defer recordProcessGroup(cmd)()
 
-	// Log claude-posts command for debugging (only if debug is enabled)
-	if r.debug {
-		log.Printf("Executing claude-posts command: claude-posts %s", strings.Join(postsArgs, " "))
-		log.Printf("claude-posts bot-token: %s (length: %d)", maskToken(r.slackBotToken), len(r.slackBotToken))
-		log.Printf("claude-posts channel-id: %s", message.ChannelID)
-		log.Printf("claude-posts thread-ts: %s", message.ThreadTS)
-	}
-
-	postsCmd := exec.CommandContext(ctx, "claude-posts", postsArgs...)
-	postsCmd.Dir = sessionDir
-
-	// Connect claude output to claude-posts input
-	postsCmd.Stdin = claudePipe
+	// Post the output while Claude is running
+	watchdog := newIdleWatchdog(profile.IdleTimeout, cancel)
+	defer watchdog.Stop()
+	poster := NewStreamPoster(r.slackRepo, message.ChannelID, message.ThreadTS)
+	poster.SetDebug(r.debug)
+	poster.SetUpdateInterval(r.updateInterval)
+	result := poster.Run(ctx, watchdog.Reader(stdout))
 
-	// Start claude-posts command
-	if err := postsCmd.Start(); err != nil {
-		_ = cmd.Process.Kill()
-		return nil, fmt.Errorf("failed to start claude-posts: %w", err)
-	}
-
-	// Read any errors from Claude
-	errBytes, _ := io.ReadAll(stderr)
-
-	// Wait for both commands to complete
 	claudeErr := cmd.Wait()
-	postsErr := postsCmd.Wait()
 
-	if claudeErr != nil {
-		log.Printf("Claude stderr: %s", string(errBytes))
-		return domain.NewAgentResult("", claudeErr), nil
+	// The process is stopped when the run is cancelled or times out;
+	// the poster has already left the partial output in the thread
+	if err := runInterruption(ctx); err != nil {
+		result.Error = err
+		return result, nil
 	}
 
-	if postsErr != nil {
-		return domain.NewAgentResult("", postsErr), nil
+	if claudeErr != nil {
+		log.Printf("Claude stderr: %s", stderr.String())
+		// An error result from Claude explains the failure better than the exit status
+		if result.AgentErrorKind() != domain.AgentErrorResult {
+			result.Error = exitError("claude failed", claudeErr, stderr.String())
+		}
+		result.Error = resumeError(session, result.Error)
 	}
 
-	// Since claude-posts handles the posting directly, we return an empty response
-	// The actual response has been sent to Slack already
-	return domain.NewAgentResult("", nil), nil
+	return result, nil
 }
 
-// cleanMessageText removes mention tags from message text
-func (r *AgentRepositoryImpl) cleanMessageText(text string) string {
-	if r.debug {
-		log.Printf("Original message text: '%s'", text)
-	}
+// GenerateResponseWithReturn runs Claude and returns the final result instead of posting it
+func (r *AgentRepositoryImpl) GenerateResponseWithReturn(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+	profile := resolveProfile(ctx, r.profiles, r.authorizer, message)
+	ctx, cancel := runContext(ctx, profile)
+	defer cancel(nil)
 
-	// Use regex to properly remove mention tags like <@U12345> or <@UMG0E05JR>
-	mentionRegex := regexp.MustCompile(`<@[A-Z0-9_]+>`)
-	cleanedText := mentionRegex.ReplaceAllString(text, "")
+	cmd, err := r.prepareCommand(ctx, profile, session, message)
+	if err != nil {
+		return nil, err
+	}
+	defer superviseProcess(cmd, r.killGrace)()
 
-	// Trim whitespace
-	cleanedText = strings.TrimSpace(cleanedText)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	if r.debug {
-		log.Printf("Cleaned message text: '%s'", cleanedText)
+	err = runProcess(cmd)
+	if interrupted := runInterruption(ctx); interrupted != nil {
+		return domain.NewAgentResult("", interrupted), nil
 	}
-
-	if cleanedText == "" {
-		log.Printf("WARNING: Cleaned text is empty after removing mentions from: '%s'", text)
+	if err != nil {
+		log.Printf("Claude stderr: %s", stderr.String())
+		return domain.NewAgentResult("", resumeError(session, exitError("claude failed", err, stderr.String()))), nil
 	}
 
-	return cleanedText
-}
-
-// maskToken masks sensitive token for logging
-func maskToken(token string) string {
-	if len(token) <= 8 {
-		return "***"
+	// Collect the final result from the stream
+	for _, line := range bytes.Split(stdout.Bytes(), []byte("\n")) {
+		event, err := ParseStreamEvent(line)
+		if err != nil || event.Type != StreamEventResult {
+			continue
+		}
+		if event.IsError {
+			result := domain.NewAgentResult("", &domain.AgentError{Kind: domain.AgentErrorResult, Message: event.Result})
+			result.SessionID = event.SessionID
+			return result, nil
+		}
+		if response := strings.TrimSpace(event.Result); response != "" {
+			result := domain.NewAgentResult(response, nil)
+			result.SessionID = event.SessionID
+			return result, nil
+		}
 	}
-	return token[:4] + "..." + token[len(token)-4:]
+
+	return nil, fmt.Errorf("empty response from agent")
 }
 
-// Alternative implementation that collects output and returns it
-func (r *AgentRepositoryImpl) GenerateResponseWithReturn(ctx context.Context, message *domain.Message) (*domain.AgentResult, error) {
+// prepareCommand sets up the session directory and builds the Claude command for the profile
+func (r *AgentRepositoryImpl) prepareCommand(ctx context.Context, profile *domain.Profile, session *domain.Session, message *domain.Message) (*exec.Cmd, error) {
 	// Create session directory
-	sessionDir := filepath.Join("sessions", message.ThreadTS)
+	sessionDir, err := sessionWorkdir(profile, session, message)
+	if err != nil {
+		return nil, err
+	}
 	if err := os.MkdirAll(sessionDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create session directory: %w", err)
 	}
 
 	// Load system prompt from file if path is provided
-	systemPrompt := r.systemPrompt
-	if r.systemPrompt != "" && strings.HasSuffix(r.systemPrompt, ".txt") {
-		content, err := os.ReadFile(r.systemPrompt)
-		if err != nil {
-			log.Printf("Error reading system prompt file: %v", err)
-		} else {
-			systemPrompt = string(content)
-		}
-	}
+	systemPrompt := loadSystemPrompt(profile.SystemPrompt)
 
 	// Save system prompt to CLAUDE.md in session directory
 	if systemPrompt != "" {
-		claudeMdPath := filepath.Join(sessionDir, "CLAUDE.md")
+		claudeMdPath := filepath.Join(sessionDir, claudeInstructionsFile)
 		if err := os.WriteFile(claudeMdPath, []byte(systemPrompt), 0644); err != nil {
 			log.Printf("Error writing CLAUDE.md: %v", err)
 		}
 	}
 
 	// Clean message text by removing mention
-	cleanedText := r.cleanMessageText(message.Text)
+	cleanedText := cleanMessageText(message.Text, r.debug)
 
 	// Build Claude command arguments
 	args := []string{
 		"exec", "--",
-		"claude", "-c",
+		"claude",
 		"--output-format", "stream-json",
 		"--dangerously-skip-permissions",
 		"-p", "--verbose",
 	}
 
-	// Add disallowed tools if provided
-	if len(r.disallowedTools) > 0 {
-		args = append(args, "--disallowedTools", strings.Join(r.disallowedTools, ","))
+	// Continue the thread's conversation if Claude has already run in it
+	if session.AgentSessionID != "" {
+		args = append(args, "--resume", session.AgentSessionID)
+	}
+
+	// Apply the profile's model and tool policy
+	if profile.Model != "" {
+		args = append(args, "--model", profile.Model)
+	}
+	if len(profile.AllowedTools) > 0 {
+		args = append(args, "--allowedTools", strings.Join(profile.AllowedTools, ","))
+	}
+	if len(profile.DisallowedTools) > 0 {
+		args = append(args, "--disallowedTools", strings.Join(profile.DisallowedTools, ","))
 	}
 
 	// Add extra arguments if provided
-	args = append(args, r.claudeExtraArgs...)
+	args = append(args, profile.ExtraArgs...)
 
 	// Add the prompt as the last argument
 	args = append(args, cleanedText)
 
+	// Log the full command for debugging (only if debug is enabled)
+	if r.debug {
+		log.Printf("Executing Claude command: mise %s", strings.Join(args, " "))
+		log.Printf("Claude prompt text: '%s'", cleanedText)
+		log.Printf("Working directory: %s (profile %s)", sessionDir, profile.Name)
+	}
+
 	// Create the command to run Claude through mise
 	cmd := exec.CommandContext(ctx, "mise", args...)
 	cmd.Dir = sessionDir
@@ -280,53 +257,42 @@ func (r *AgentRepositoryImpl) GenerateResponseWithReturn(ctx context.Context, me
 	env := os.Environ()
 	if systemPrompt != "" {
 		env = append(env, fmt.Sprintf("SYSTEM_PROMPT=%s", systemPrompt))
+		if r.debug {
+			log.Printf("Setting SYSTEM_PROMPT environment variable (length: %d)", len(systemPrompt))
+		}
 	}
-	cmd.Env = env
-
-	// Set up pipes
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	env = append(env, fmt.Sprintf("SLACK_AGENT_PROMPT=%s", cleanedText))
+	if r.debug {
+		log.Printf("Setting SLACK_AGENT_PROMPT environment variable: '%s'", cleanedText)
 	}
+	cmd.Env = env
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
-	}
+	return cmd, nil
+}
 
-	// Start the command
-	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start claude: %w", err)
-	}
+// missingConversation is what claude reports when the conversation to resume is not under ~/.claude
+const missingConversation = "No conversation found"
 
-	// Collect the response text
-	var responseText strings.Builder
-	scanner := bufio.NewScanner(stdout)
-	for scanner.Scan() {
-		line := scanner.Text()
-		var msg StreamMessage
-		if err := json.Unmarshal([]byte(line), &msg); err == nil {
-			if msg.Type == "text" && msg.Text != "" {
-				responseText.WriteString(msg.Text)
-			} else if msg.Type == "content" && msg.Content != "" {
-				responseText.WriteString(msg.Content)
-			}
-		}
+// resumeError reports a run that failed because the session's conversation no longer exists,
+// e.g. after a restart without a persistent ~/.claude or when another replica ran the thread
+func resumeError(session *domain.Session, err error) error {
+	if session.AgentSessionID == "" || err == nil || !strings.Contains(err.Error(), missingConversation) {
+		return err
 	}
+	return &domain.AgentError{Kind: domain.AgentErrorResume, Message: "conversation " + session.AgentSessionID + " not found", Err: err}
+}
 
-	// Read any errors
-	errBytes, _ := io.ReadAll(stderr)
-
-	// Wait for the command to complete
-	if err := cmd.Wait(); err != nil {
-		log.Printf("Claude stderr: %s", string(errBytes))
-		return domain.NewAgentResult("", err), nil
+// exitError converts a failed process into a structured agent error
+func exitError(message string, err error, stderr string) *domain.AgentError {
+	agentErr := &domain.AgentError{
+		Kind:    domain.AgentErrorExit,
+		Message: message,
+		Stderr:  tail(stderr, 500),
+		Err:     err,
 	}
-
-	response := strings.TrimSpace(responseText.String())
-	if response == "" {
-		return nil, fmt.Errorf("empty response from agent")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		agentErr.ExitCode = exitErr.ExitCode()
 	}
-
-	return domain.NewAgentResult(response, nil), nil
+	return agentErr
 }
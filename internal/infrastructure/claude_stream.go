@@ -0,0 +1,126 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Claude stream-json event types
+const (
+	StreamEventSystem    = "system"
+	StreamEventAssistant = "assistant"
+	StreamEventUser      = "user"
+	StreamEventResult    = "result"
+)
+
+// StreamEvent is a single line of Claude's --output-format stream-json output
+type StreamEvent struct {
+	Type      string         `json:"type"`
+	Subtype   string         `json:"subtype,omitempty"`
+	SessionID string         `json:"session_id,omitempty"`
+	Message   *StreamMessage `json:"message,omitempty"`
+
+	// Set on system/init events
+	Model string   `json:"model,omitempty"`
+	Tools []string `json:"tools,omitempty"`
+
+	// Set on result events
+	Result       string  `json:"result,omitempty"`
+	IsError      bool    `json:"is_error,omitempty"`
+	DurationMS   int64   `json:"duration_ms,omitempty"`
+	NumTurns     int     `json:"num_turns,omitempty"`
+	TotalCostUSD float64 `json:"total_cost_usd,omitempty"`
+}
+
+// StreamMessage is the message carried by assistant and user events
+type StreamMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Role    string          `json:"role"`
+	Content []StreamContent `json:"content"`
+}
+
+// StreamContent is a content block of a stream message
+type StreamContent struct {
+	Type string `json:"type"`
+
+	// Set on text blocks
+	Text string `json:"text,omitempty"`
+
+	// Set on tool_use blocks
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// Set on tool_result blocks
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+// ParseStreamEvent parses a single stream-json line
+func ParseStreamEvent(line []byte) (*StreamEvent, error) {
+	var event StreamEvent
+	if err := json.Unmarshal(line, &event); err != nil {
+		return nil, fmt.Errorf("invalid stream event: %w", err)
+	}
+	if event.Type == "" {
+		return nil, fmt.Errorf("stream event without type")
+	}
+	return &event, nil
+}
+
+// Text returns the concatenated text blocks of the message
+func (m *StreamMessage) Text() string {
+	var parts []string
+	for _, c := range m.Content {
+		if c.Type == "text" && strings.TrimSpace(c.Text) != "" {
+			parts = append(parts, c.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// ToolUses returns the tool_use blocks of the message
+func (m *StreamMessage) ToolUses() []StreamContent {
+	var uses []StreamContent
+	for _, c := range m.Content {
+		if c.Type == "tool_use" {
+			uses = append(uses, c)
+		}
+	}
+	return uses
+}
+
+// toolInputKeys lists the input fields that best describe a tool call, in order of preference
+var toolInputKeys = []string{"command", "file_path", "path", "pattern", "url", "query", "description", "prompt"}
+
+// ToolSummary returns a one-line description of a tool_use block
+func (c StreamContent) ToolSummary() string {
+	var input map[string]any
+	_ = json.Unmarshal(c.Input, &input)
+
+	for _, key := range toolInputKeys {
+		if value, ok := input[key].(string); ok && value != "" {
+			return fmt.Sprintf("🔧 *%s*: `%s`", c.Name, truncate(firstLine(value), 200))
+		}
+	}
+	return fmt.Sprintf("🔧 *%s*", c.Name)
+}
+
+// firstLine returns the first line of s
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " …"
+	}
+	return s
+}
+
+// truncate shortens s to at most n runes
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n]) + "…"
+}
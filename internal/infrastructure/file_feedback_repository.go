@@ -0,0 +1,129 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// feedbackRecord is the on-disk representation of feedback
+type feedbackRecord struct {
+	Time      time.Time             `json:"time"`
+	TeamID    string                `json:"team_id"`
+	ChannelID string                `json:"channel_id"`
+	ThreadTS  string                `json:"thread_ts"`
+	MessageTS string                `json:"message_ts"`
+	UserID    string                `json:"user_id"`
+	Rating    domain.Rating         `json:"rating"`
+	Source    domain.FeedbackSource `json:"source"`
+	Profile   string                `json:"profile,omitempty"`
+	Prompt    string                `json:"prompt,omitempty"`
+	Response  string                `json:"response,omitempty"`
+	Latency   time.Duration         `json:"latency,omitempty"`
+}
+
+// FileFeedbackRepository implements the FeedbackRepository interface with a JSON Lines file.
+// Feedback is only ever appended, so the file can be shared by replicas and read with standard tools.
+type FileFeedbackRepository struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileFeedbackRepository creates a new FileFeedbackRepository instance
+func NewFileFeedbackRepository(path string) (*FileFeedbackRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create feedback directory: %w", err)
+	}
+	return &FileFeedbackRepository{path: path}, nil
+}
+
+// Add appends the feedback to the file
+func (r *FileFeedbackRepository) Add(ctx context.Context, feedback *domain.Feedback) error {
+	data, err := json.Marshal(feedbackRecord{
+		Time:      feedback.Time,
+		TeamID:    feedback.Key.TeamID,
+		ChannelID: feedback.Key.ChannelID,
+		ThreadTS:  feedback.Key.ThreadTS,
+		MessageTS: feedback.MessageTS,
+		UserID:    feedback.UserID,
+		Rating:    feedback.Rating,
+		Source:    feedback.Source,
+		Profile:   feedback.Profile,
+		Prompt:    feedback.Prompt,
+		Response:  feedback.Response,
+		Latency:   feedback.Latency,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode feedback: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	// A single write keeps lines from concurrent writers intact
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write feedback: %w", err)
+	}
+	return f.Close()
+}
+
+// List returns the feedback given at or after since, in the order it was recorded
+func (r *FileFeedbackRepository) List(ctx context.Context, since time.Time) ([]*domain.Feedback, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := os.Open(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	defer f.Close()
+
+	var feedback []*domain.Feedback
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var record feedbackRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// A torn line must not hide the rest of the feedback
+			log.Printf("Skipping invalid feedback at %s:%d: %v", r.path, line, err)
+			continue
+		}
+		if record.Time.Before(since) {
+			continue
+		}
+		feedback = append(feedback, &domain.Feedback{
+			Time:      record.Time,
+			Key:       domain.SessionKey{TeamID: record.TeamID, ChannelID: record.ChannelID, ThreadTS: record.ThreadTS},
+			MessageTS: record.MessageTS,
+			UserID:    record.UserID,
+			Rating:    record.Rating,
+			Source:    record.Source,
+			Profile:   record.Profile,
+			Prompt:    record.Prompt,
+			Response:  record.Response,
+			Latency:   record.Latency,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read feedback: %w", err)
+	}
+	return feedback, nil
+}
@@ -0,0 +1,62 @@
+package infrastructure_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+)
+
+func TestFileFeedbackRepository(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "feedback.jsonl")
+	repo, err := infrastructure.NewFileFeedbackRepository(path)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	if feedback, err := repo.List(ctx, time.Time{}); err != nil || len(feedback) != 0 {
+		t.Fatalf("expected no feedback, got %v, %v", feedback, err)
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}
+	old := &domain.Feedback{Time: now.Add(-48 * time.Hour), Key: key, UserID: "U1", Rating: domain.RatingGood, Source: domain.FeedbackButton}
+	recent := &domain.Feedback{
+		Time: now, Key: key, MessageTS: "1.2", UserID: "U2", Rating: domain.RatingBad, Source: domain.FeedbackReaction,
+		Profile: "infra", Prompt: "why?", Response: "because", Latency: 3 * time.Second,
+	}
+	for _, feedback := range []*domain.Feedback{old, recent} {
+		if err := repo.Add(ctx, feedback); err != nil {
+			t.Fatalf("failed to add feedback: %v", err)
+		}
+	}
+
+	// A torn line is skipped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	_, _ = f.WriteString("{\"time\":\n")
+	f.Close()
+
+	all, err := repo.List(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("failed to list feedback: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 feedback entries, got %d", len(all))
+	}
+
+	since, err := repo.List(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to list feedback: %v", err)
+	}
+	if len(since) != 1 || *since[0] != *recent {
+		t.Errorf("expected only the recent feedback, got %+v", since)
+	}
+}
@@ -0,0 +1,103 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// FileHandoffRepository passes events between replicas through an inbox directory per replica
+// on a shared volume. Each event is a JSON file, so the inbox survives restarts.
+type FileHandoffRepository struct {
+	dir string
+	seq atomic.Uint64
+}
+
+// NewFileHandoffRepository creates a new FileHandoffRepository instance
+func NewFileHandoffRepository(dir string) *FileHandoffRepository {
+	return &FileHandoffRepository{dir: dir}
+}
+
+// Send puts the event into the replica's inbox
+func (r *FileHandoffRepository) Send(ctx context.Context, replica string, handoff *domain.Handoff) error {
+	// File names sort by the time they were sent
+	name := fmt.Sprintf("%020d-%d-%d.json", time.Now().UnixNano(), os.Getpid(), r.seq.Add(1))
+	return writeJSONFile(filepath.Join(r.inbox(replica), name), handoff)
+}
+
+// Receive removes and returns the events in the replica's inbox, oldest first
+func (r *FileHandoffRepository) Receive(ctx context.Context, replica string) ([]*domain.Handoff, error) {
+	dir := r.inbox(replica)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inbox %s: %w", dir, err)
+	}
+
+	var handoffs []*domain.Handoff
+	for _, entry := range entries {
+		// Skip files that are still being written
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		// Claim the event first since other replicas may empty the inbox of a replica that is gone
+		path := filepath.Join(dir, "."+entry.Name())
+		if err := os.Rename(filepath.Join(dir, entry.Name()), path); errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return handoffs, fmt.Errorf("failed to claim %s: %w", entry.Name(), err)
+		}
+		var handoff domain.Handoff
+		err := readJSONFile(path, &handoff)
+		if removeErr := os.Remove(path); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			return handoffs, fmt.Errorf("failed to remove %s: %w", path, removeErr)
+		}
+		if err != nil {
+			log.Printf("Dropping unreadable handoff %s: %v", path, err)
+			continue
+		}
+		handoffs = append(handoffs, &handoff)
+	}
+	return handoffs, nil
+}
+
+// Replicas returns the replicas that have an inbox
+func (r *FileHandoffRepository) Replicas(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(r.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list inboxes in %s: %w", r.dir, err)
+	}
+
+	var replicas []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		replica, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
+// inbox returns the directory of the replica's inbox
+func (r *FileHandoffRepository) inbox(replica string) string {
+	return filepath.Join(r.dir, url.PathEscape(replica))
+}
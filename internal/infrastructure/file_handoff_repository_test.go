@@ -0,0 +1,81 @@
+package infrastructure_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+)
+
+func TestFileHandoffRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sender := infrastructure.NewFileHandoffRepository(dir)
+	receiver := infrastructure.NewFileHandoffRepository(dir)
+
+	if handoffs, err := receiver.Receive(ctx, "b"); err != nil || len(handoffs) != 0 {
+		t.Fatalf("expected an empty inbox, got %v, %v", handoffs, err)
+	}
+
+	message := domain.NewMessage("1.2", "U1", "C1", "<@UBOT> hello", "1.1", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	message.TeamID = "T1"
+	reaction := &domain.Reaction{TeamID: "T1", UserID: "U1", ChannelID: "C1", ItemTS: "1.3", Name: "octagonal_sign"}
+	action := &domain.Action{TeamID: "T1", UserID: "U1", ChannelID: "C1", ThreadTS: "1.1", MessageTS: "1.3", Control: domain.ControlRegenerate}
+	for _, handoff := range []*domain.Handoff{{Message: message}, {Reaction: reaction}, {Action: action}} {
+		if err := sender.Send(ctx, "b", handoff); err != nil {
+			t.Fatalf("failed to send: %v", err)
+		}
+	}
+	if err := sender.Send(ctx, "c", &domain.Handoff{Reaction: reaction}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	// Unreadable and partially written files are skipped
+	if err := os.WriteFile(filepath.Join(dir, "b", "0-torn.json"), []byte("{"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "b", ".0.json.1.tmp"), []byte("{"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	handoffs, err := receiver.Receive(ctx, "b")
+	if err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+	if len(handoffs) != 3 {
+		t.Fatalf("expected 3 handoffs, got %d", len(handoffs))
+	}
+	if got := handoffs[0].Message; got == nil || got.Text != message.Text || got.SessionKey() != message.SessionKey() {
+		t.Errorf("expected the message first, got %+v", handoffs[0])
+	}
+	if got := handoffs[1].Reaction; got == nil || *got != *reaction {
+		t.Errorf("expected the reaction second, got %+v", handoffs[1])
+	}
+	if got := handoffs[2].Action; got == nil || *got != *action {
+		t.Errorf("expected the action third, got %+v", handoffs[2])
+	}
+
+	// Received events are removed from the inbox
+	if handoffs, err := receiver.Receive(ctx, "b"); err != nil || len(handoffs) != 0 {
+		t.Errorf("expected an empty inbox, got %v, %v", handoffs, err)
+	}
+	if handoffs, err := receiver.Receive(ctx, "c"); err != nil || len(handoffs) != 1 {
+		t.Errorf("expected 1 handoff for c, got %v, %v", handoffs, err)
+	}
+
+	// Inboxes are listed by replica so that the inboxes of replicas that are gone can be emptied
+	if err := sender.Send(ctx, "pod/d", &domain.Handoff{Reaction: reaction}); err != nil {
+		t.Fatalf("failed to send: %v", err)
+	}
+	replicas, err := receiver.Replicas(ctx)
+	if err != nil {
+		t.Fatalf("failed to list replicas: %v", err)
+	}
+	if !slices.Equal(replicas, []string{"b", "c", "pod/d"}) {
+		t.Errorf("expected the replicas b, c and pod/d, got %v", replicas)
+	}
+}
@@ -0,0 +1,132 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// staleLockAge is how old a lock file may get before it is considered left behind by a crashed replica
+const staleLockAge = 10 * time.Second
+
+// leaseRecord is the on-disk representation of a lease
+type leaseRecord struct {
+	Name    string    `json:"name"`
+	Holder  string    `json:"holder"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLeaseRepository keeps leases as JSON files in a directory that replicas share on a volume.
+// Changes to a lease are serialized with a lock file created exclusively next to it.
+type FileLeaseRepository struct {
+	dir string
+}
+
+// NewFileLeaseRepository creates a new FileLeaseRepository instance, creating the directory if needed
+func NewFileLeaseRepository(dir string) (*FileLeaseRepository, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lease directory: %w", err)
+	}
+	return &FileLeaseRepository{dir: dir}, nil
+}
+
+// Acquire grants or renews the lease for holder unless another holder has an unexpired lease
+func (r *FileLeaseRepository) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (string, error) {
+	path := r.path(name)
+	unlock, err := lockFile(ctx, path+".lock")
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	current, err := readLease(path)
+	if err != nil {
+		return "", err
+	}
+	if current != nil && current.Holder != holder && time.Now().Before(current.Expires) {
+		return current.Holder, nil
+	}
+
+	if err := writeJSONFile(path, &leaseRecord{Name: name, Holder: holder, Expires: time.Now().Add(ttl)}); err != nil {
+		return "", fmt.Errorf("failed to write lease %s: %w", name, err)
+	}
+	return holder, nil
+}
+
+// Holder returns the holder of the lease, or an empty string when it is free
+func (r *FileLeaseRepository) Holder(ctx context.Context, name string) (string, error) {
+	current, err := readLease(r.path(name))
+	if err != nil || current == nil || !time.Now().Before(current.Expires) {
+		return "", err
+	}
+	return current.Holder, nil
+}
+
+// Release gives up the lease if holder holds it
+func (r *FileLeaseRepository) Release(ctx context.Context, name, holder string) error {
+	path := r.path(name)
+	unlock, err := lockFile(ctx, path+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := readLease(path)
+	if err != nil || current == nil || current.Holder != holder {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to release lease %s: %w", name, err)
+	}
+	return nil
+}
+
+// path returns the file of the lease; names are hashed since they contain slashes
+func (r *FileLeaseRepository) path(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return filepath.Join(r.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// readLease reads a lease file, returning nil if there is none
+func readLease(path string) (*leaseRecord, error) {
+	var record leaseRecord
+	if err := readJSONFile(path, &record); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+// lockFile takes an exclusive lock by creating the file, waiting while another holder has it
+func lockFile(ctx context.Context, path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+
+		// Break locks left behind by a replica that crashed while holding them
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			_ = os.Remove(path)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
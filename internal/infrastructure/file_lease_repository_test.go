@@ -0,0 +1,102 @@
+package infrastructure_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+)
+
+func TestFileLeaseRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "leases")
+	// Replicas open the same directory
+	a, err := infrastructure.NewFileLeaseRepository(dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	b, err := infrastructure.NewFileLeaseRepository(dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	if holder, err := a.Holder(ctx, "thread/T1:C1:1.1"); err != nil || holder != "" {
+		t.Fatalf("expected a free lease, got %q, %v", holder, err)
+	}
+	if holder, err := a.Acquire(ctx, "thread/T1:C1:1.1", "a", time.Minute); err != nil || holder != "a" {
+		t.Fatalf("expected a to get the lease, got %q, %v", holder, err)
+	}
+	if holder, err := b.Acquire(ctx, "thread/T1:C1:1.1", "b", time.Minute); err != nil || holder != "a" {
+		t.Errorf("expected the lease to stay with a, got %q, %v", holder, err)
+	}
+	if holder, err := b.Holder(ctx, "thread/T1:C1:1.1"); err != nil || holder != "a" {
+		t.Errorf("expected a to hold the lease, got %q, %v", holder, err)
+	}
+
+	// Only the holder can release it
+	if err := b.Release(ctx, "thread/T1:C1:1.1", "b"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if holder, _ := b.Holder(ctx, "thread/T1:C1:1.1"); holder != "a" {
+		t.Errorf("expected a to still hold the lease, got %q", holder)
+	}
+	if err := a.Release(ctx, "thread/T1:C1:1.1", "a"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	if holder, err := b.Acquire(ctx, "thread/T1:C1:1.1", "b", time.Minute); err != nil || holder != "b" {
+		t.Errorf("expected b to get the released lease, got %q, %v", holder, err)
+	}
+
+	// Expired leases are free
+	if _, err := a.Acquire(ctx, "replica/a", "a", time.Millisecond); err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if holder, _ := b.Holder(ctx, "replica/a"); holder != "" {
+		t.Errorf("expected the expired lease to be free, got %q", holder)
+	}
+	if holder, err := b.Acquire(ctx, "replica/a", "b", time.Minute); err != nil || holder != "b" {
+		t.Errorf("expected b to get the expired lease, got %q, %v", holder, err)
+	}
+}
+
+func TestFileLeaseRepository_ConcurrentAcquire(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	holders := make(chan string, 10)
+	for _, replica := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo, err := infrastructure.NewFileLeaseRepository(dir)
+			if err != nil {
+				t.Errorf("failed to create repository: %v", err)
+				return
+			}
+			holder, err := repo.Acquire(ctx, "thread/T1:C1:1.1", replica, time.Minute)
+			if err != nil {
+				t.Errorf("failed to acquire: %v", err)
+				return
+			}
+			holders <- holder
+		}()
+	}
+	wg.Wait()
+	close(holders)
+
+	// Every replica sees the same winner
+	var winner string
+	for holder := range holders {
+		if winner == "" {
+			winner = holder
+		}
+		if holder != winner {
+			t.Errorf("expected a single holder, got %q and %q", winner, holder)
+		}
+	}
+}
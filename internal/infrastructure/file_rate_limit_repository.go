@@ -0,0 +1,75 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// tokenBucketRecord is the on-disk representation of a token bucket
+type tokenBucketRecord struct {
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FileRateLimitRepository implements the RateLimitRepository interface with a single JSON file.
+// The buckets are kept in memory and the whole file is replaced atomically on every change.
+type FileRateLimitRepository struct {
+	path string
+
+	mu      sync.Mutex
+	buckets map[string]tokenBucketRecord
+}
+
+// NewFileRateLimitRepository creates a new FileRateLimitRepository instance, loading the buckets stored at path
+func NewFileRateLimitRepository(path string) (*FileRateLimitRepository, error) {
+	buckets := make(map[string]tokenBucketRecord)
+	if err := readJSONFile(path, &buckets); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load rate limits: %w", err)
+	}
+	return &FileRateLimitRepository{path: path, buckets: buckets}, nil
+}
+
+// Get returns the bucket stored under the key, or nil if there is none
+func (r *FileRateLimitRepository) Get(ctx context.Context, key string) (*domain.TokenBucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.buckets[key]
+	if !ok {
+		return nil, nil
+	}
+	return &domain.TokenBucket{Tokens: record.Tokens, UpdatedAt: record.UpdatedAt}, nil
+}
+
+// Save stores the bucket under the key
+func (r *FileRateLimitRepository) Save(ctx context.Context, key string, bucket *domain.TokenBucket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.buckets[key] = tokenBucketRecord{Tokens: bucket.Tokens, UpdatedAt: bucket.UpdatedAt}
+	return writeJSONFile(r.path, r.buckets)
+}
+
+// DeleteIdle removes buckets that have not been updated since the given time
+func (r *FileRateLimitRepository) DeleteIdle(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	deleted := 0
+	for key, record := range r.buckets {
+		if record.UpdatedAt.Before(before) {
+			delete(r.buckets, key)
+			deleted++
+		}
+	}
+	if deleted == 0 {
+		return nil
+	}
+	return writeJSONFile(r.path, r.buckets)
+}
@@ -0,0 +1,56 @@
+package infrastructure_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+)
+
+func TestFileRateLimitRepository(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state", "ratelimits.json")
+	repo, err := infrastructure.NewFileRateLimitRepository(path)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	if bucket, err := repo.Get(ctx, "user/T1/U1"); err != nil || bucket != nil {
+		t.Fatalf("expected no bucket, got %v, %v", bucket, err)
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := repo.Save(ctx, "user/T1/U1", &domain.TokenBucket{Tokens: 1.5, UpdatedAt: now}); err != nil {
+		t.Fatalf("failed to save bucket: %v", err)
+	}
+	if err := repo.Save(ctx, "user/T1/U2", &domain.TokenBucket{Tokens: 3, UpdatedAt: now.Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("failed to save bucket: %v", err)
+	}
+
+	// A new repository on the same file sees the stored buckets
+	reopened, err := infrastructure.NewFileRateLimitRepository(path)
+	if err != nil {
+		t.Fatalf("failed to reopen repository: %v", err)
+	}
+	bucket, err := reopened.Get(ctx, "user/T1/U1")
+	if err != nil {
+		t.Fatalf("failed to get bucket: %v", err)
+	}
+	if bucket == nil || bucket.Tokens != 1.5 || !bucket.UpdatedAt.Equal(now) {
+		t.Errorf("unexpected bucket %+v", bucket)
+	}
+
+	if err := reopened.DeleteIdle(ctx, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("failed to delete idle buckets: %v", err)
+	}
+	reopened, _ = infrastructure.NewFileRateLimitRepository(path)
+	if bucket, _ := reopened.Get(ctx, "user/T1/U2"); bucket != nil {
+		t.Errorf("expected the idle bucket to be removed, got %+v", bucket)
+	}
+	if bucket, _ := reopened.Get(ctx, "user/T1/U1"); bucket == nil {
+		t.Error("expected the recent bucket to be kept")
+	}
+}
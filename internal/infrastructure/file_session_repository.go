@@ -0,0 +1,205 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// sessionRecord is the on-disk representation of a session
+type sessionRecord struct {
+	TeamID         string               `json:"team_id"`
+	ChannelID      string               `json:"channel_id"`
+	ThreadTS       string               `json:"thread_ts"`
+	AgentSessionID string               `json:"agent_session_id,omitempty"`
+	CreatedAt      time.Time            `json:"created_at"`
+	LastActivity   time.Time            `json:"last_activity"`
+	MessageCount   int                  `json:"message_count"`
+	Status         domain.SessionStatus `json:"status"`
+	Workdir        string               `json:"workdir,omitempty"`
+	Dismissed      bool                 `json:"dismissed,omitempty"`
+	LastPrompt     string               `json:"last_prompt,omitempty"`
+	LastResponse   string               `json:"last_response,omitempty"`
+	LastLatency    time.Duration        `json:"last_latency,omitempty"`
+	Answers        []answerRecord       `json:"answers,omitempty"`
+	ControlsTS     string               `json:"controls_ts,omitempty"`
+	ControlsText   string               `json:"controls_text,omitempty"`
+}
+
+// answerRecord is the on-disk representation of an answer in a session
+type answerRecord struct {
+	MessageTS []string      `json:"message_ts"`
+	Prompt    string        `json:"prompt,omitempty"`
+	Response  string        `json:"response,omitempty"`
+	Latency   time.Duration `json:"latency,omitempty"`
+	Profile   string        `json:"profile,omitempty"`
+}
+
+// FileSessionRepository implements the SessionRepository interface with one JSON file per session.
+// Files live under <dir>/<team>/<channel>/<thread>.json so that the store can be put on a
+// persistent or shared volume and survive restarts.
+type FileSessionRepository struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileSessionRepository creates a new FileSessionRepository instance
+func NewFileSessionRepository(dir string) (*FileSessionRepository, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session store directory: %w", err)
+	}
+	return &FileSessionRepository{dir: filepath.Clean(dir)}, nil
+}
+
+// Get returns the session stored for the key
+func (r *FileSessionRepository) Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var record sessionRecord
+	if err := readJSONFile(r.path(key), &record); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, domain.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return record.session(), nil
+}
+
+// Save stores the session, replacing any previous version
+func (r *FileSessionRepository) Save(ctx context.Context, session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return writeJSONFile(r.path(session.Key), sessionRecord{
+		TeamID:         session.Key.TeamID,
+		ChannelID:      session.Key.ChannelID,
+		ThreadTS:       session.Key.ThreadTS,
+		AgentSessionID: session.AgentSessionID,
+		CreatedAt:      session.CreatedAt,
+		LastActivity:   session.LastActivity,
+		MessageCount:   session.MessageCount,
+		Status:         session.Status,
+		Workdir:        session.Workdir,
+		Dismissed:      session.Dismissed,
+		LastPrompt:     session.LastPrompt,
+		LastResponse:   session.LastResponse,
+		LastLatency:    session.LastLatency,
+		Answers:        answerRecords(session.Answers),
+		ControlsTS:     session.ControlsTS,
+		ControlsText:   session.ControlsText,
+	})
+}
+
+// Delete removes the session stored for the key
+func (r *FileSessionRepository) Delete(ctx context.Context, key domain.SessionKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := r.path(key)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete session %s: %w", key, err)
+	}
+
+	// Remove the channel and team directories once their last session is gone
+	for parent := filepath.Dir(path); parent != r.dir && parent != "."; parent = filepath.Dir(parent) {
+		if err := os.Remove(parent); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// List returns all stored sessions
+func (r *FileSessionRepository) List(ctx context.Context) ([]*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sessions []*domain.Session
+	err := filepath.WalkDir(r.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip directories and temporary files of unfinished writes
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") || filepath.Ext(path) != ".json" {
+			return nil
+		}
+
+		// One broken file should not hide all other sessions
+		var record sessionRecord
+		if err := readJSONFile(path, &record); err != nil {
+			log.Printf("Skipping unreadable session %s: %v", path, err)
+			return nil
+		}
+		sessions = append(sessions, record.session())
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// path returns the file that stores the session for the key
+func (r *FileSessionRepository) path(key domain.SessionKey) string {
+	return filepath.Join(r.dir, key.Path()+".json")
+}
+
+// session converts the record into a domain session
+func (rec sessionRecord) session() *domain.Session {
+	return &domain.Session{
+		Key:            domain.SessionKey{TeamID: rec.TeamID, ChannelID: rec.ChannelID, ThreadTS: rec.ThreadTS},
+		AgentSessionID: rec.AgentSessionID,
+		CreatedAt:      rec.CreatedAt,
+		LastActivity:   rec.LastActivity,
+		MessageCount:   rec.MessageCount,
+		Status:         rec.Status,
+		Workdir:        rec.Workdir,
+		Dismissed:      rec.Dismissed,
+		LastPrompt:     rec.LastPrompt,
+		LastResponse:   rec.LastResponse,
+		LastLatency:    rec.LastLatency,
+		Answers:        answers(rec.Answers),
+		ControlsTS:     rec.ControlsTS,
+		ControlsText:   rec.ControlsText,
+	}
+}
+
+// answerRecords converts the answers of a session into records
+func answerRecords(answers []domain.Answer) []answerRecord {
+	var records []answerRecord
+	for _, answer := range answers {
+		records = append(records, answerRecord{
+			MessageTS: answer.MessageTS,
+			Prompt:    answer.Prompt,
+			Response:  answer.Response,
+			Latency:   answer.Latency,
+			Profile:   answer.Profile,
+		})
+	}
+	return records
+}
+
+// answers converts answer records into domain answers
+func answers(records []answerRecord) []domain.Answer {
+	var answers []domain.Answer
+	for _, rec := range records {
+		answers = append(answers, domain.Answer{
+			MessageTS: rec.MessageTS,
+			Prompt:    rec.Prompt,
+			Response:  rec.Response,
+			Latency:   rec.Latency,
+			Profile:   rec.Profile,
+		})
+	}
+	return answers
+}
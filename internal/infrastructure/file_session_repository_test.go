@@ -0,0 +1,102 @@
+package infrastructure_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+)
+
+func TestFileSessionRepository(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "sessions")
+	repo, err := infrastructure.NewFileSessionRepository(dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+
+	key := domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}
+	if _, err := repo.Get(ctx, key); !errors.Is(err, domain.ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	session := domain.NewSession(key, now)
+	session.Start(now)
+	session.AgentSessionID = "sess-1"
+	session.LastPrompt = "<@UBOT> hello"
+	session.AddAnswer(domain.Answer{MessageTS: []string{"1.2", "1.3"}, Prompt: "<@UBOT> hello"})
+	if err := repo.Save(ctx, session); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	// The same thread timestamp in another channel is a different session
+	other := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C2", ThreadTS: "1.1"}, now)
+	if err := repo.Save(ctx, other); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	// A new repository on the same directory sees the stored sessions
+	reopened, err := infrastructure.NewFileSessionRepository(dir)
+	if err != nil {
+		t.Fatalf("failed to reopen repository: %v", err)
+	}
+	got, err := reopened.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if got.AgentSessionID != "sess-1" || got.MessageCount != 1 || got.Status != domain.SessionRunning || !got.CreatedAt.Equal(now) || got.LastPrompt != "<@UBOT> hello" {
+		t.Errorf("unexpected session: %+v", got)
+	}
+	if answer := got.FindAnswer("1.2"); answer == nil || answer.TS() != "1.3" || answer.Prompt != "<@UBOT> hello" {
+		t.Errorf("expected the answer to be stored, got %+v", got.Answers)
+	}
+
+	sessions, err := reopened.List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Errorf("expected 2 sessions, got %d", len(sessions))
+	}
+
+	if err := reopened.Delete(ctx, key); err != nil {
+		t.Fatalf("failed to delete session: %v", err)
+	}
+	if err := reopened.Delete(ctx, key); err != nil {
+		t.Errorf("expected deleting a missing session to succeed, got %v", err)
+	}
+	if _, err := reopened.Get(ctx, key); !errors.Is(err, domain.ErrSessionNotFound) {
+		t.Errorf("expected ErrSessionNotFound after delete, got %v", err)
+	}
+}
+
+func TestFileSessionRepository_ListSkipsCorruptFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	repo, err := infrastructure.NewFileSessionRepository(dir)
+	if err != nil {
+		t.Fatalf("failed to create repository: %v", err)
+	}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	session := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}, now)
+	if err := repo.Save(ctx, session); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "corrupt.json"), []byte("{not json"), 0644); err != nil {
+		t.Fatalf("failed to write corrupt session: %v", err)
+	}
+
+	sessions, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Key != session.Key {
+		t.Errorf("expected only the readable session, got %+v", sessions)
+	}
+}
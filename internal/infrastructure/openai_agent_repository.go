@@ -0,0 +1,227 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+const (
+	// defaultOpenAIBaseURL is used when no base URL is configured
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	// maxHistoryMessages bounds the conversation kept per thread
+	maxHistoryMessages = 20
+	// maxHistoryThreads bounds the number of threads whose conversation is kept in memory
+	maxHistoryThreads = 500
+)
+
+// chatMessage is a single message of the chat completions API
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatCompletionRequest is the body sent to /chat/completions
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+// chatCompletionResponse is the subset of the /chat/completions response that is used
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// threadHistory is the conversation of a thread kept between requests
+type threadHistory struct {
+	messages []chatMessage
+	lastUsed time.Time
+}
+
+// OpenAIAgentRepository answers using an OpenAI-compatible chat completions endpoint.
+// The conversation of each thread is kept in memory so follow-ups have context.
+type OpenAIAgentRepository struct {
+	client   *http.Client
+	baseURL  string
+	apiKey   string
+	model    string
+	profiles *usecase.ProfileResolver
+	debug    bool
+	runs     *runRegistry
+
+	mu      sync.Mutex
+	history map[string]*threadHistory
+}
+
+// NewOpenAIAgentRepository creates a new OpenAIAgentRepository instance
+func NewOpenAIAgentRepository(cfg AgentBackendConfig) (*OpenAIAgentRepository, error) {
+	if cfg.OpenAIModel == "" {
+		return nil, errors.New("model is required for the openai-compatible-http backend")
+	}
+	// The endpoint runs no tools, so per-user tool rules could not be enforced
+	if cfg.Profiles != nil && cfg.Profiles.HasToolRules() {
+		return nil, errors.New("tool_rules are not supported by the openai-compatible-http backend")
+	}
+
+	baseURL := cfg.OpenAIBaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+
+	return &OpenAIAgentRepository{
+		client:   &http.Client{},
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   cfg.OpenAIAPIKey,
+		model:    cfg.OpenAIModel,
+		profiles: cfg.profileResolver(),
+		debug:    cfg.Debug,
+		runs:     newRunRegistry(),
+		history:  make(map[string]*threadHistory),
+	}, nil
+}
+
+// CancelSession cancels the request in flight for the given session
+func (r *OpenAIAgentRepository) CancelSession(key domain.SessionKey) bool {
+	return r.runs.cancel(key.String())
+}
+
+// CancelAll cancels every request in flight
+func (r *OpenAIAgentRepository) CancelAll() int {
+	return r.runs.cancelAll()
+}
+
+// GenerateResponse sends the thread's conversation to the chat completions endpoint
+func (r *OpenAIAgentRepository) GenerateResponse(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+	// The channel's profile may override the system prompt, the model and the timeout
+	profile := r.profiles.Resolve(ctx, message.ChannelID)
+
+	key := session.Key.String()
+	ctx, cancel := runContext(ctx, profile)
+	defer cancel(nil)
+	defer r.runs.register(key, func() { cancel(nil) })()
+
+	model := r.model
+	if profile.Model != "" {
+		model = profile.Model
+	}
+
+	prompt := chatMessage{Role: "user", Content: cleanMessageText(message.Text, r.debug)}
+
+	messages := make([]chatMessage, 0, maxHistoryMessages+2)
+	if systemPrompt := loadSystemPrompt(profile.SystemPrompt); systemPrompt != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, r.conversation(key)...)
+	messages = append(messages, prompt)
+
+	body, err := json.Marshal(chatCompletionRequest{Model: model, Messages: messages})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode chat request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if interrupted := runInterruption(ctx); interrupted != nil {
+		return domain.NewAgentResult("", interrupted), nil
+	}
+	if err != nil {
+		return domain.NewAgentResult("", fmt.Errorf("chat request failed: %w", err)), nil
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return domain.NewAgentResult("", fmt.Errorf("failed to read chat response: %w", err)), nil
+	}
+
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(respBody, &completion); err != nil {
+		return domain.NewAgentResult("", fmt.Errorf("chat endpoint returned %s: %s", resp.Status, tail(string(respBody), 500))), nil
+	}
+	if completion.Error != nil {
+		return domain.NewAgentResult("", fmt.Errorf("chat endpoint returned %s: %s", resp.Status, completion.Error.Message)), nil
+	}
+	if resp.StatusCode != http.StatusOK || len(completion.Choices) == 0 {
+		return domain.NewAgentResult("", fmt.Errorf("chat endpoint returned %s with no choices", resp.Status)), nil
+	}
+
+	answer := completion.Choices[0].Message
+	r.remember(key, prompt, chatMessage{Role: "assistant", Content: answer.Content})
+
+	return domain.NewAgentResult(strings.TrimSpace(answer.Content), nil), nil
+}
+
+// ForgetSession drops the conversation of the thread
+func (r *OpenAIAgentRepository) ForgetSession(key domain.SessionKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.history, key.String())
+}
+
+// conversation returns a copy of the messages exchanged in the thread so far
+func (r *OpenAIAgentRepository) conversation(key string) []chatMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.history[key]
+	if !ok {
+		return nil
+	}
+	return append([]chatMessage(nil), h.messages...)
+}
+
+// remember appends an exchange to the thread's conversation
+func (r *OpenAIAgentRepository) remember(key string, exchange ...chatMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h, ok := r.history[key]
+	if !ok {
+		if len(r.history) >= maxHistoryThreads {
+			r.evictOldestLocked()
+		}
+		h = &threadHistory{}
+		r.history[key] = h
+	}
+
+	h.messages = append(h.messages, exchange...)
+	if len(h.messages) > maxHistoryMessages {
+		h.messages = h.messages[len(h.messages)-maxHistoryMessages:]
+	}
+	h.lastUsed = time.Now()
+}
+
+// evictOldestLocked drops the least recently used conversation; r.mu must be held
+func (r *OpenAIAgentRepository) evictOldestLocked() {
+	var oldestKey string
+	var oldest time.Time
+	for key, h := range r.history {
+		if oldestKey == "" || h.lastUsed.Before(oldest) {
+			oldestKey, oldest = key, h.lastUsed
+		}
+	}
+	delete(r.history, oldestKey)
+}
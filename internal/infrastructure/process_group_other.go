@@ -0,0 +1,24 @@
+//go:build !unix
+
+package infrastructure
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup does nothing on platforms without process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signalProcessGroup signals only p itself on platforms without process groups,
+// falling back to killing it when the signal is not supported
+func signalProcessGroup(p *os.Process, sig syscall.Signal) error {
+	if sig == syscall.SIGKILL {
+		return p.Kill()
+	}
+	if err := p.Signal(sig); err != nil {
+		return p.Kill()
+	}
+	return nil
+}
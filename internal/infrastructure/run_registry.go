@@ -0,0 +1,60 @@
+package infrastructure
+
+import (
+	"context"
+	"sync"
+)
+
+// runRegistry tracks the cancel functions of running agents per thread
+type runRegistry struct {
+	mu   sync.Mutex
+	runs map[string]context.CancelFunc
+}
+
+// newRunRegistry creates a new runRegistry instance
+func newRunRegistry() *runRegistry {
+	return &runRegistry{
+		runs: make(map[string]context.CancelFunc),
+	}
+}
+
+// register records a running agent and returns a function that removes it again
+func (r *runRegistry) register(key string, cancel context.CancelFunc) func() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runs[key] = cancel
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(r.runs, key)
+	}
+}
+
+// cancel cancels the agent running in the thread, reporting whether there was one
+func (r *runRegistry) cancel(key string) bool {
+	r.mu.Lock()
+	cancel, ok := r.runs[key]
+	r.mu.Unlock()
+
+	if !ok {
+		return false
+	}
+	cancel()
+	return true
+}
+
+// cancelAll cancels every running agent and returns how many there were
+func (r *runRegistry) cancelAll() int {
+	r.mu.Lock()
+	cancels := make([]context.CancelFunc, 0, len(r.runs))
+	for _, cancel := range r.runs {
+		cancels = append(cancels, cancel)
+	}
+	r.mu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+	return len(cancels)
+}
@@ -0,0 +1,187 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// DefaultKillGracePeriod is how long a stopped agent may take to exit after SIGTERM before it gets SIGKILL
+const DefaultKillGracePeriod = 10 * time.Second
+
+// runContext derives the context of an agent run, which ends with a timeout error once the
+// profile's timeout has passed. Cancelling it with a nil cause stops the run as cancelled.
+func runContext(ctx context.Context, profile *domain.Profile) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if profile.Timeout <= 0 {
+		return ctx, cancel
+	}
+
+	timer := time.AfterFunc(profile.Timeout, func() {
+		cancel(&domain.AgentError{Kind: domain.AgentErrorTimeout, Message: "no answer within " + shortDuration(profile.Timeout)})
+	})
+	return ctx, func(cause error) {
+		timer.Stop()
+		cancel(cause)
+	}
+}
+
+// runInterruption returns why the run's context ended: a timeout, a cancellation, or nil if it is still live
+func runInterruption(ctx context.Context) error {
+	var agentErr *domain.AgentError
+	if errors.As(context.Cause(ctx), &agentErr) {
+		return agentErr
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return domain.ErrAgentCancelled
+	}
+	return nil
+}
+
+// superviseProcess starts the command in its own process group. When the run's context ends
+// the whole group receives SIGTERM, and SIGKILL if it is still running after the grace period.
+// The returned function kills whatever is left of the group; call it once the command has exited.
+func superviseProcess(cmd *exec.Cmd, grace time.Duration) func() {
+	if grace <= 0 {
+		grace = DefaultKillGracePeriod
+	}
+	setProcessGroup(cmd)
+
+	var (
+		mu     sync.Mutex
+		timer  *time.Timer
+		exited bool
+	)
+	cmd.Cancel = func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		timer = time.AfterFunc(grace, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			if !exited {
+				_ = signalProcessGroup(cmd.Process, syscall.SIGKILL)
+			}
+		})
+		return signalProcessGroup(cmd.Process, syscall.SIGTERM)
+	}
+	cmd.WaitDelay = grace
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if exited || cmd.Process == nil {
+			return
+		}
+		exited = true
+		if timer != nil {
+			timer.Stop()
+		}
+		// Tool subprocesses may outlive the agent itself
+		_ = signalProcessGroup(cmd.Process, syscall.SIGKILL)
+	}
+}
+
+// runProcess runs the command like cmd.Run, recording its process group while it runs
+func runProcess(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	defer recordProcessGroup(cmd)()
+	return cmd.Wait()
+}
+
+// idleWatchdog ends a run with a timeout error when it produces no output for a while.
+// A nil watchdog never fires.
+type idleWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+// newIdleWatchdog starts a watchdog, or returns nil if the timeout is disabled
+func newIdleWatchdog(timeout time.Duration, cancel context.CancelCauseFunc) *idleWatchdog {
+	if timeout <= 0 {
+		return nil
+	}
+	return &idleWatchdog{
+		timeout: timeout,
+		timer: time.AfterFunc(timeout, func() {
+			cancel(&domain.AgentError{Kind: domain.AgentErrorTimeout, Message: "no output for " + shortDuration(timeout)})
+		}),
+	}
+}
+
+// Reset restarts the idle period
+func (w *idleWatchdog) Reset() {
+	if w != nil {
+		w.timer.Reset(w.timeout)
+	}
+}
+
+// Stop stops the watchdog
+func (w *idleWatchdog) Stop() {
+	if w != nil {
+		w.timer.Stop()
+	}
+}
+
+// Reader returns a reader that counts everything read from r as output
+func (w *idleWatchdog) Reader(r io.Reader) io.Reader {
+	if w == nil {
+		return r
+	}
+	return &watchedReader{r: r, watchdog: w}
+}
+
+// Writer returns a writer that counts everything written to dst as output
+func (w *idleWatchdog) Writer(dst io.Writer) io.Writer {
+	if w == nil {
+		return dst
+	}
+	return &watchedWriter{w: dst, watchdog: w}
+}
+
+// watchedReader resets the watchdog whenever data is read
+type watchedReader struct {
+	r        io.Reader
+	watchdog *idleWatchdog
+}
+
+func (r *watchedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.watchdog.Reset()
+	}
+	return n, err
+}
+
+// watchedWriter resets the watchdog whenever data is written
+type watchedWriter struct {
+	w        io.Writer
+	watchdog *idleWatchdog
+}
+
+func (w *watchedWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		w.watchdog.Reset()
+	}
+	return w.w.Write(p)
+}
+
+// shortDuration formats d without trailing zero units, e.g. 30m instead of 30m0s
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
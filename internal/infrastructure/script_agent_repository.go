@@ -0,0 +1,164 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+// ScriptRequest is written as JSON to the agent script's stdin
+type ScriptRequest struct {
+	Prompt         string `json:"prompt"`
+	TeamID         string `json:"team_id,omitempty"`
+	UserID         string `json:"user_id"`
+	ChannelID      string `json:"channel_id"`
+	ThreadTS       string `json:"thread_ts"`
+	SessionPath    string `json:"session_path"`
+	Workdir        string `json:"workdir"`
+	AgentSessionID string `json:"agent_session_id,omitempty"`
+	Profile        string `json:"profile"`
+	SystemPrompt   string `json:"system_prompt,omitempty"`
+	Model          string `json:"model,omitempty"`
+}
+
+// ScriptAgentRepository runs an external script as the agent.
+//
+// Protocol:
+//   - The request is written to stdin as a single JSON object (see ScriptRequest).
+//     The same values are also exported as SLACK_AGENT_PROMPT, SLACK_TEAM_ID, SLACK_USER_ID,
+//     SLACK_CHANNEL_ID, SLACK_THREAD_TS, SLACK_SESSION_PATH, SLACK_AGENT_WORKDIR,
+//     SLACK_AGENT_SESSION_ID, SLACK_AGENT_PROFILE, SYSTEM_PROMPT and CLAUDE_MODEL, together
+//     with the channel profile's ALLOWED_TOOLS, DISALLOWED_TOOLS (after its tool rules for the user) and CLAUDE_EXTRA_ARGS, for
+//     scripts such as bin/start_agent.sh. SLACK_SESSION_PATH is a relative path unique to the thread.
+//   - Everything written to stdout is posted to the thread as the reply.
+//     A script that posts to Slack by itself should print nothing.
+//   - A non-zero exit status is reported as an error, including the tail of stderr.
+type ScriptAgentRepository struct {
+	scriptPath string
+	profiles   *usecase.ProfileResolver
+	authorizer *usecase.Authorizer
+	killGrace  time.Duration
+	debug      bool
+	runs       *runRegistry
+}
+
+// NewScriptAgentRepository creates a new ScriptAgentRepository instance
+func NewScriptAgentRepository(cfg AgentBackendConfig) (*ScriptAgentRepository, error) {
+	if cfg.AgentScriptPath == "" {
+		return nil, errors.New("agent script path is required for the script backend")
+	}
+
+	return &ScriptAgentRepository{
+		scriptPath: cfg.AgentScriptPath,
+		profiles:   cfg.profileResolver(),
+		authorizer: cfg.Authorizer,
+		killGrace:  cfg.KillGracePeriod,
+		debug:      cfg.Debug,
+		runs:       newRunRegistry(),
+	}, nil
+}
+
+// CancelSession cancels the script running in the given session
+func (r *ScriptAgentRepository) CancelSession(key domain.SessionKey) bool {
+	return r.runs.cancel(key.String())
+}
+
+// CancelAll cancels every running script
+func (r *ScriptAgentRepository) CancelAll() int {
+	return r.runs.cancelAll()
+}
+
+// ForgetSession does nothing; scripts keep their state in the session's working directory
+func (r *ScriptAgentRepository) ForgetSession(key domain.SessionKey) {}
+
+// GenerateResponse runs the agent script and returns its stdout as the response
+func (r *ScriptAgentRepository) GenerateResponse(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+	profile := resolveProfile(ctx, r.profiles, r.authorizer, message)
+	ctx, cancel := runContext(ctx, profile)
+	defer cancel(nil)
+	defer r.runs.register(session.Key.String(), func() { cancel(nil) })()
+
+	workdir, err := sessionWorkdir(profile, session, message)
+	if err != nil {
+		return nil, err
+	}
+
+	request := ScriptRequest{
+		Prompt:         cleanMessageText(message.Text, r.debug),
+		TeamID:         session.Key.TeamID,
+		UserID:         message.UserID,
+		ChannelID:      message.ChannelID,
+		ThreadTS:       message.ThreadTS,
+		SessionPath:    session.Key.Path(),
+		Workdir:        workdir,
+		AgentSessionID: session.AgentSessionID,
+		Profile:        profile.Name,
+		SystemPrompt:   loadSystemPrompt(profile.SystemPrompt),
+		Model:          profile.Model,
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode script request: %w", err)
+	}
+
+	if r.debug {
+		log.Printf("Executing agent script: %s", r.scriptPath)
+	}
+
+	cmd := exec.CommandContext(ctx, r.scriptPath)
+	defer superviseProcess(cmd, r.killGrace)()
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("SLACK_AGENT_PROMPT=%s", request.Prompt),
+		fmt.Sprintf("SLACK_TEAM_ID=%s", request.TeamID),
+		fmt.Sprintf("SLACK_USER_ID=%s", request.UserID),
+		fmt.Sprintf("SLACK_CHANNEL_ID=%s", request.ChannelID),
+		fmt.Sprintf("SLACK_THREAD_TS=%s", request.ThreadTS),
+		fmt.Sprintf("SLACK_SESSION_PATH=%s", request.SessionPath),
+		fmt.Sprintf("SLACK_AGENT_WORKDIR=%s", request.Workdir),
+		fmt.Sprintf("SLACK_AGENT_SESSION_ID=%s", request.AgentSessionID),
+		fmt.Sprintf("SLACK_AGENT_PROFILE=%s", request.Profile),
+		fmt.Sprintf("SYSTEM_PROMPT=%s", request.SystemPrompt),
+		fmt.Sprintf("CLAUDE_MODEL=%s", request.Model),
+		fmt.Sprintf("ALLOWED_TOOLS=%s", strings.Join(profile.AllowedTools, ",")),
+		fmt.Sprintf("DISALLOWED_TOOLS=%s", strings.Join(profile.DisallowedTools, ",")),
+		fmt.Sprintf("CLAUDE_EXTRA_ARGS=%s", strings.Join(profile.ExtraArgs, " ")),
+	)
+
+	var stdout, stderr bytes.Buffer
+	watchdog := newIdleWatchdog(profile.IdleTimeout, cancel)
+	defer watchdog.Stop()
+	cmd.Stdout = watchdog.Writer(&stdout)
+	cmd.Stderr = watchdog.Writer(&stderr)
+
+	err = runProcess(cmd)
+	if interrupted := runInterruption(ctx); interrupted != nil {
+		// Keep the partial output so that it can be shown with the timeout
+		return domain.NewAgentResult(strings.TrimSpace(stdout.String()), interrupted), nil
+	}
+	if err != nil {
+		log.Printf("Agent script stderr: %s", stderr.String())
+		return domain.NewAgentResult("", exitError("agent script failed", err, stderr.String())), nil
+	}
+
+	return domain.NewAgentResult(strings.TrimSpace(stdout.String()), nil), nil
+}
+
+// tail returns at most the last n bytes of s
+func tail(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= n {
+		return s
+	}
+	return "..." + s[len(s)-n:]
+}
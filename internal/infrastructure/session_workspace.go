@@ -0,0 +1,206 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+// AttachmentsDir is the directory in a session that holds the files shared by users
+const AttachmentsDir = "attachments"
+
+// SessionWorkspace implements the WorkspaceRepository interface for the working directories
+// of the sessions, which are under the sessions directory unless a profile says otherwise
+type SessionWorkspace struct {
+	root     string
+	profiles *usecase.ProfileResolver
+	sessions usecase.SessionRepository
+}
+
+// NewSessionWorkspace creates a new SessionWorkspace instance
+func NewSessionWorkspace(root string) *SessionWorkspace {
+	return &SessionWorkspace{root: filepath.Clean(root)}
+}
+
+// SetProfiles resolves the working directories with the per-channel profiles, as the agent backends do
+func (w *SessionWorkspace) SetProfiles(profiles *usecase.ProfileResolver) {
+	w.profiles = profiles
+}
+
+// SetSessions looks up the working directory recorded in each session
+func (w *SessionWorkspace) SetSessions(sessions usecase.SessionRepository) {
+	w.sessions = sessions
+}
+
+// Workdir returns the working directory of a session started by the user
+func (w *SessionWorkspace) Workdir(ctx context.Context, key domain.SessionKey, userID string) (string, error) {
+	var profile *domain.Profile
+	if w.profiles != nil {
+		profile = w.profiles.Resolve(ctx, key.ChannelID)
+	}
+	return SessionWorkdir(w.root, profile, key, userID)
+}
+
+// Size returns the total size of the files in the session's working directory
+func (w *SessionWorkspace) Size(ctx context.Context, key domain.SessionKey) (int64, error) {
+	dir, err := w.dir(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	return size, err
+}
+
+// Remove deletes the session's working directory and any parent directories left empty.
+// Directories outside the sessions directory may be shared by several sessions and are kept.
+func (w *SessionWorkspace) Remove(ctx context.Context, key domain.SessionKey) error {
+	dir, err := w.dir(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !w.contains(dir) {
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+
+	// Remove the channel and team directories once their last session is gone
+	for parent := filepath.Dir(dir); parent != w.root && parent != "."; parent = filepath.Dir(parent) {
+		if err := os.Remove(parent); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// SaveAttachment writes a file shared by the user to the attachments directory of the session
+func (w *SessionWorkspace) SaveAttachment(ctx context.Context, key domain.SessionKey, name string, content io.Reader) (string, error) {
+	workdir, err := w.dir(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(workdir, AttachmentsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create attachments directory: %w", err)
+	}
+
+	path, err := filepath.Abs(filepath.Join(dir, filepath.Base(name)))
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create attachment: %w", err)
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Don't leave a partial file for the agent
+		os.Remove(path)
+		return "", fmt.Errorf("failed to write attachment: %w", err)
+	}
+	return path, nil
+}
+
+// Snapshot lists the files in the session's working directory.
+// Hidden files, attachments and the CLAUDE.md written for the agent are left out.
+func (w *SessionWorkspace) Snapshot(ctx context.Context, key domain.SessionKey) (domain.WorkspaceSnapshot, error) {
+	dir, err := w.dir(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(domain.WorkspaceSnapshot)
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || rel == AttachmentsDir || rel == claudeInstructionsFile {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		snapshot[rel] = domain.WorkspaceFile{Path: rel, Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return snapshot, nil
+	}
+	return snapshot, err
+}
+
+// Open opens a file in the session's working directory
+func (w *SessionWorkspace) Open(ctx context.Context, key domain.SessionKey, path string) (io.ReadCloser, error) {
+	dir, err := w.dir(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	full := filepath.Join(dir, filepath.FromSlash(path))
+	if !strings.HasPrefix(full, dir+string(filepath.Separator)) {
+		return nil, fmt.Errorf("path %s is outside the session", path)
+	}
+	return os.Open(full)
+}
+
+// dir returns the working directory recorded in the session, or the one a new session would get
+func (w *SessionWorkspace) dir(ctx context.Context, key domain.SessionKey) (string, error) {
+	if w.sessions != nil {
+		session, err := w.sessions.Get(ctx, key)
+		if err == nil && session.Workdir != "" {
+			return filepath.Clean(session.Workdir), nil
+		}
+		if err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+			return "", fmt.Errorf("failed to look up session %s: %w", key, err)
+		}
+	}
+	return w.Workdir(ctx, key, "")
+}
+
+// contains reports whether dir is inside the sessions directory
+func (w *SessionWorkspace) contains(dir string) bool {
+	rel, err := filepath.Rel(w.root, dir)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,195 @@
+package infrastructure_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+func TestSessionWorkspace(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	workspace := infrastructure.NewSessionWorkspace(root)
+	key := domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}
+
+	if size, err := workspace.Size(ctx, key); err != nil || size != 0 {
+		t.Fatalf("expected missing workspace to be empty, got %d, %v", size, err)
+	}
+
+	dir := filepath.Join(root, key.Path())
+	if err := os.MkdirAll(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "CLAUDE.md"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "src", "main.go"), make([]byte, 32), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	size, err := workspace.Size(ctx, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size != 42 {
+		t.Errorf("expected 42 bytes, got %d", size)
+	}
+
+	if err := workspace.Remove(ctx, key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "T1")); !os.IsNotExist(err) {
+		t.Errorf("expected empty parent directories to be removed, got %v", err)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Errorf("expected the sessions root to be kept, got %v", err)
+	}
+}
+
+func TestSessionWorkspace_SaveAttachment(t *testing.T) {
+	root := t.TempDir()
+	workspace := infrastructure.NewSessionWorkspace(root)
+	key := domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}
+
+	path, err := workspace.SaveAttachment(context.Background(), key, "F1-app.log", strings.NewReader("panic: oops"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := filepath.Join(root, key.Path(), infrastructure.AttachmentsDir, "F1-app.log"); path != expected {
+		t.Errorf("expected %s, got %s", expected, path)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "panic: oops" {
+		t.Errorf("unexpected content %q, %v", content, err)
+	}
+}
+
+func TestSessionWorkspace_SaveAttachmentFails(t *testing.T) {
+	root := t.TempDir()
+	workspace := infrastructure.NewSessionWorkspace(root)
+	key := domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}
+
+	// A download that breaks off leaves no partial file behind
+	content := io.MultiReader(strings.NewReader("panic:"), iotest.ErrReader(errors.New("connection reset")))
+	if _, err := workspace.SaveAttachment(context.Background(), key, "F1-app.log", content); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := os.Stat(filepath.Join(root, key.Path(), infrastructure.AttachmentsDir, "F1-app.log")); !os.IsNotExist(err) {
+		t.Errorf("expected the partial file to be removed, got %v", err)
+	}
+}
+
+func TestSessionWorkspace_Snapshot(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	workspace := infrastructure.NewSessionWorkspace(root)
+	key := domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"}
+
+	dir := filepath.Join(root, key.Path())
+	for _, name := range []string{"CLAUDE.md", "attachments/F1-app.log", ".git/HEAD", "out/report.csv", "main.py"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	snapshot, err := workspace.Snapshot(ctx, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(snapshot) != 2 || snapshot["out/report.csv"].Size != int64(len("out/report.csv")) {
+		t.Errorf("expected only the agent's files, got %+v", snapshot)
+	}
+
+	file, err := workspace.Open(ctx, key, "out/report.csv")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	file.Close()
+	if _, err := workspace.Open(ctx, key, "../../C2/1.1/secret"); err == nil {
+		t.Error("expected paths outside the session to be rejected")
+	}
+}
+
+func TestSessionWorkspace_ProfileWorkdir(t *testing.T) {
+	ctx := context.Background()
+	tmp := t.TempDir()
+	profiles := usecase.NewProfileResolver(domain.NewProfiles(domain.Profile{}, map[string]domain.Profile{
+		"C1": {WorkdirTemplate: filepath.Join(tmp, "work") + "/{{.ChannelID}}/{{.UserID}}"},
+	}), nil)
+	sessionRepo, err := infrastructure.NewFileSessionRepository(filepath.Join(tmp, "state"))
+	if err != nil {
+		t.Fatalf("failed to create session repository: %v", err)
+	}
+	workspace := infrastructure.NewSessionWorkspace(filepath.Join(tmp, "sessions"))
+	workspace.SetProfiles(profiles)
+	workspace.SetSessions(sessionRepo)
+
+	// The agent writes a file into the directory it runs in
+	script := writeScript(t, `#!/bin/sh
+mkdir -p "$SLACK_AGENT_WORKDIR" && echo "a,b" > "$SLACK_AGENT_WORKDIR/report.csv" && echo "$SLACK_AGENT_WORKDIR"
+`)
+	repo, err := infrastructure.NewScriptAgentRepository(infrastructure.AgentBackendConfig{AgentScriptPath: script, Profiles: profiles})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The session records the directory it started in
+	msg := domain.NewMessage("1.1", "U1", "C1", "hi", "1.1", time.Now())
+	session := domain.NewSession(msg.SessionKey(), time.Now())
+	expected := filepath.Join(tmp, "work", "C1", "U1")
+	if session.Workdir, err = workspace.Workdir(ctx, session.Key, msg.UserID); err != nil || session.Workdir != expected {
+		t.Fatalf("expected %s, got %q, %v", expected, session.Workdir, err)
+	}
+	if err := sessionRepo.Save(ctx, session); err != nil {
+		t.Fatalf("failed to save session: %v", err)
+	}
+
+	path, err := workspace.SaveAttachment(ctx, session.Key, "F1-app.log", strings.NewReader("panic: oops"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Dir(path) != filepath.Join(expected, infrastructure.AttachmentsDir) {
+		t.Errorf("expected the attachment in the agent's directory, got %s", path)
+	}
+
+	// Follow-ups from other users stay in the same directory
+	followUp := domain.NewMessage("1.2", "U2", "C1", "more", "1.1", time.Now())
+	result, err := repo.GenerateResponse(ctx, session, followUp)
+	if err != nil || result.IsError() {
+		t.Fatalf("unexpected error: %v, %v", err, result.Error)
+	}
+	if result.Response != expected {
+		t.Errorf("expected the agent to run in %s, got %s", expected, result.Response)
+	}
+
+	snapshot, err := workspace.Snapshot(ctx, session.Key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := snapshot["report.csv"]; !ok || len(snapshot) != 1 {
+		t.Errorf("expected the agent's file in the snapshot, got %v", snapshot)
+	}
+	if size, err := workspace.Size(ctx, session.Key); err != nil || size == 0 {
+		t.Errorf("expected the agent's directory to be measured, got %d, %v", size, err)
+	}
+
+	// Directories outside the sessions directory are kept
+	if err := workspace.Remove(ctx, session.Key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(expected, "report.csv")); err != nil {
+		t.Errorf("expected the directory to be kept, got %v", err)
+	}
+}
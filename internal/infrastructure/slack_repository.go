@@ -2,12 +2,17 @@ package infrastructure
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
+	"github.com/takutakahashi/slack-agent/internal/domain"
 )
 
 // SlackRepositoryImpl implements the SlackRepository interface
@@ -48,8 +53,8 @@ func NewSlackRepository(token, appToken string) (*SlackRepositoryImpl, error) {
 	return repo, nil
 }
 
-// PostMessage posts a message to Slack
-func (r *SlackRepositoryImpl) PostMessage(ctx context.Context, channelID, text, threadTS string) error {
+// PostMessage posts a message to Slack and returns its timestamp
+func (r *SlackRepositoryImpl) PostMessage(ctx context.Context, channelID, text, threadTS string) (string, error) {
 	options := []slack.MsgOption{
 		slack.MsgOptionText(text, false),
 	}
@@ -58,19 +63,214 @@ func (r *SlackRepositoryImpl) PostMessage(ctx context.Context, channelID, text,
 		options = append(options, slack.MsgOptionTS(threadTS))
 	}
 
-	_, _, err := r.client.PostMessageContext(ctx, channelID, options...)
+	_, ts, err := r.client.PostMessageContext(ctx, channelID, options...)
 	if err != nil {
-		return fmt.Errorf("failed to post message: %w", err)
+		return "", fmt.Errorf("failed to post message: %w", err)
+	}
+
+	return ts, nil
+}
+
+// UpdateMessage replaces the text of a message
+func (r *SlackRepositoryImpl) UpdateMessage(ctx context.Context, channelID, ts, text string) error {
+	_, _, _, err := r.client.UpdateMessageContext(ctx, channelID, ts, slack.MsgOptionText(text, false))
+	if err != nil {
+		return fmt.Errorf("failed to update message: %w", err)
 	}
 
 	return nil
 }
 
+// PostControls posts a message with a row of buttons.
+// Each button's action ID is the control and its value is the thread, so that clicks can be
+// mapped back to the thread's session.
+func (r *SlackRepositoryImpl) PostControls(ctx context.Context, channelID, threadTS, text string, controls []domain.Control) (string, error) {
+	options := []slack.MsgOption{
+		// The text is the fallback for notifications
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionBlocks(ControlBlocks(text, threadTS, "", controls)...),
+	}
+	if threadTS != "" {
+		options = append(options, slack.MsgOptionTS(threadTS))
+	}
+
+	_, ts, err := r.client.PostMessageContext(ctx, channelID, options...)
+	if err != nil {
+		return "", fmt.Errorf("failed to post controls: %w", err)
+	}
+
+	return ts, nil
+}
+
+// SetControls puts buttons for the controls on a message posted by the bot, or takes them off
+// when there are none. The message is replaced, so text must be its current text.
+func (r *SlackRepositoryImpl) SetControls(ctx context.Context, channelID, threadTS, ts, text string, controls []domain.Control) error {
+	blocks := []slack.Block{}
+	if len(controls) > 0 {
+		blocks = ControlBlocks(text, threadTS, ts, controls)
+	}
+	_, _, _, err := r.client.UpdateMessageContext(ctx, channelID, ts, slack.MsgOptionText(text, false), slack.MsgOptionBlocks(blocks...))
+	if err != nil {
+		return fmt.Errorf("failed to set controls: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteMessage deletes a message posted by the bot
+func (r *SlackRepositoryImpl) DeleteMessage(ctx context.Context, channelID, ts string) error {
+	if _, _, err := r.client.DeleteMessageContext(ctx, channelID, ts); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	return nil
+}
+
+// GetThreadTS returns the timestamp of the thread the message belongs to
+func (r *SlackRepositoryImpl) GetThreadTS(ctx context.Context, channelID, ts string) (string, error) {
+	messages, _, _, err := r.client.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+		ChannelID: channelID,
+		Timestamp: ts,
+		Inclusive: true,
+		Limit:     1,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get message: %w", err)
+	}
+	if len(messages) == 0 || messages[0].ThreadTimestamp == "" {
+		return ts, nil
+	}
+	return messages[0].ThreadTimestamp, nil
+}
+
 // GetBotUserID returns the bot's user ID
 func (r *SlackRepositoryImpl) GetBotUserID(ctx context.Context) (string, error) {
 	return r.botUserID, nil
 }
 
+// GetChannelName returns the name of the channel
+func (r *SlackRepositoryImpl) GetChannelName(ctx context.Context, channelID string) (string, error) {
+	channel, err := r.client.GetConversationInfoContext(ctx, &slack.GetConversationInfoInput{ChannelID: channelID})
+	if err != nil {
+		return "", fmt.Errorf("failed to get channel info: %w", err)
+	}
+	return channel.Name, nil
+}
+
+// PostEphemeral posts a message only the given user can see
+func (r *SlackRepositoryImpl) PostEphemeral(ctx context.Context, channelID, userID, text, threadTS string) error {
+	options := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+	}
+	if threadTS != "" {
+		options = append(options, slack.MsgOptionTS(threadTS))
+	}
+
+	if _, err := r.client.PostEphemeralContext(ctx, channelID, userID, options...); err != nil {
+		return fmt.Errorf("failed to post ephemeral message: %w", err)
+	}
+	return nil
+}
+
+// RespondToCommand replies to a slash command through its response URL
+func (r *SlackRepositoryImpl) RespondToCommand(ctx context.Context, responseURL, text string) error {
+	if err := slack.PostWebhookContext(ctx, responseURL, &slack.WebhookMessage{
+		Text:         text,
+		ResponseType: slack.ResponseTypeEphemeral,
+	}); err != nil {
+		return fmt.Errorf("failed to respond to command: %w", err)
+	}
+	return nil
+}
+
+// GetUserGroupMembers returns the IDs of the users in the user group
+func (r *SlackRepositoryImpl) GetUserGroupMembers(ctx context.Context, groupID string) ([]string, error) {
+	members, err := r.client.GetUserGroupMembersContext(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user group members: %w", err)
+	}
+	return members, nil
+}
+
+// GetThreadReplies returns the messages of a thread posted before the given timestamp
+func (r *SlackRepositoryImpl) GetThreadReplies(ctx context.Context, channelID, threadTS, before string) ([]*domain.Message, error) {
+	params := &slack.GetConversationRepliesParameters{
+		ChannelID: channelID,
+		Timestamp: threadTS,
+		Latest:    before,
+		Limit:     200,
+	}
+
+	var messages []*domain.Message
+	for {
+		replies, hasMore, cursor, err := r.client.GetConversationRepliesContext(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get thread replies: %w", err)
+		}
+		for _, reply := range replies {
+			// Slack always returns the thread root, even when it is the message itself
+			if before != "" && reply.Timestamp == before {
+				continue
+			}
+			userID := reply.User
+			if userID == "" {
+				userID = reply.BotID
+			}
+			msg := domain.NewMessage(reply.Timestamp, userID, channelID, reply.Text, threadTS, time.Time{})
+			messages = append(messages, msg)
+		}
+		if !hasMore || cursor == "" {
+			return messages, nil
+		}
+		params.Cursor = cursor
+	}
+}
+
+// GetUserName returns the display name of the user, falling back to the real name and the user name
+func (r *SlackRepositoryImpl) GetUserName(ctx context.Context, userID string) (string, error) {
+	user, err := r.client.GetUserInfoContext(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user info: %w", err)
+	}
+	for _, name := range []string{user.Profile.DisplayName, user.RealName, user.Name} {
+		if name != "" {
+			return name, nil
+		}
+	}
+	return userID, nil
+}
+
+// DownloadFile writes the file at a private Slack URL to w, authenticating with the bot token
+func (r *SlackRepositoryImpl) DownloadFile(ctx context.Context, url string, w io.Writer) error {
+	if err := r.client.GetFileContext(ctx, url, w); err != nil {
+		return fmt.Errorf("failed to download file: %w", err)
+	}
+	return nil
+}
+
+// UploadFile uploads a file to the thread with Slack's external upload API
+func (r *SlackRepositoryImpl) UploadFile(ctx context.Context, channelID, threadTS, filename string, size int64, content io.Reader) error {
+	_, err := r.client.UploadFileV2Context(ctx, slack.UploadFileV2Parameters{
+		Reader:          content,
+		FileSize:        int(size),
+		Filename:        filename,
+		Channel:         channelID,
+		ThreadTimestamp: threadTS,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	return nil
+}
+
+// CheckAuth verifies that the bot token is still accepted by Slack
+func (r *SlackRepositoryImpl) CheckAuth(ctx context.Context) error {
+	if _, err := r.client.AuthTestContext(ctx); err != nil {
+		return fmt.Errorf("auth test failed: %w", err)
+	}
+	return nil
+}
+
 // GetClient returns the Slack client (for event handling)
 func (r *SlackRepositoryImpl) GetClient() *slack.Client {
 	return r.client
@@ -81,6 +281,13 @@ func (r *SlackRepositoryImpl) GetSocketClient() *socketmode.Client {
 	return r.socketClient
 }
 
+// messageSubtypes are the subtypes of message events posted by users
+var messageSubtypes = map[string]bool{
+	"":                 true,
+	"file_share":       true,
+	"thread_broadcast": true,
+}
+
 // ExtractMessageFromEvent extracts message information from Slack events
 func ExtractMessageFromEvent(event slackevents.EventsAPIEvent) (string, string, string, string, bool) {
 	switch ev := event.InnerEvent.Data.(type) {
@@ -90,6 +297,10 @@ func ExtractMessageFromEvent(event slackevents.EventsAPIEvent) (string, string,
 			log.Printf("Skipping bot message from BotID: %s", ev.BotID)
 			return "", "", "", "", false
 		}
+		// Skip edits, deletions and other changes, which also follow the bot's own updates
+		if !messageSubtypes[ev.SubType] || ev.User == "" {
+			return "", "", "", "", false
+		}
 		// Use ThreadTimeStamp if it exists, otherwise use the message TimeStamp itself
 		threadTS := ev.ThreadTimeStamp
 		if threadTS == "" {
@@ -114,3 +325,138 @@ func ExtractMessageFromEvent(event slackevents.EventsAPIEvent) (string, string,
 		return "", "", "", "", false
 	}
 }
+
+// ExtractMessageTSFromEvent returns the timestamp of a message or mention event
+func ExtractMessageTSFromEvent(event slackevents.EventsAPIEvent) string {
+	switch ev := event.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		return ev.TimeStamp
+	case *slackevents.AppMentionEvent:
+		return ev.TimeStamp
+	default:
+		return ""
+	}
+}
+
+// ExtractFilesFromEvent returns the files shared with a message or mention event
+func ExtractFilesFromEvent(event slackevents.EventsAPIEvent) []domain.File {
+	var files []slack.File
+	switch ev := event.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		if ev.Message != nil {
+			files = ev.Message.Files
+		}
+	case *slackevents.AppMentionEvent:
+		// slackevents.AppMentionEvent has no files field, so read them from the raw event
+		if callback, ok := event.Data.(*slackevents.EventsAPICallbackEvent); ok && callback.InnerEvent != nil {
+			var raw struct {
+				Files []slack.File `json:"files"`
+			}
+			if err := json.Unmarshal(*callback.InnerEvent, &raw); err != nil {
+				log.Printf("Error decoding files of app mention: %v", err)
+			}
+			files = raw.Files
+		}
+	}
+
+	if len(files) == 0 {
+		return nil
+	}
+	result := make([]domain.File, 0, len(files))
+	for _, f := range files {
+		url := f.URLPrivateDownload
+		if url == "" {
+			url = f.URLPrivate
+		}
+		result = append(result, domain.File{
+			ID:       f.ID,
+			Name:     f.Name,
+			MimeType: f.Mimetype,
+			Size:     int64(f.Size),
+			URL:      url,
+		})
+	}
+	return result
+}
+
+// ExtractReactionFromEvent extracts reaction information from Slack events
+func ExtractReactionFromEvent(event slackevents.EventsAPIEvent) (*domain.Reaction, bool) {
+	ev, ok := event.InnerEvent.Data.(*slackevents.ReactionAddedEvent)
+	if !ok {
+		return nil, false
+	}
+	// Only reactions on messages can refer to a thread
+	if ev.Item.Type != "message" {
+		return nil, false
+	}
+	reaction := domain.NewReaction(ev.User, ev.Item.Channel, ev.Item.Timestamp, ev.Reaction)
+	reaction.TeamID = event.TeamID
+	reaction.ItemUserID = ev.ItemUser
+	return reaction, true
+}
+
+// sectionTextLimit is the most text a section block can hold
+const sectionTextLimit = 3000
+
+// ControlBlocks builds the Block Kit layout of a message with buttons for the controls.
+// Each button's value is the thread, followed by the answer for controls that refer to an answer.
+func ControlBlocks(text, threadTS, answerTS string, controls []domain.Control) []slack.Block {
+	value := threadTS
+	if answerTS != "" {
+		value += " " + answerTS
+	}
+
+	buttons := make([]slack.BlockElement, 0, len(controls))
+	for _, control := range controls {
+		button := slack.NewButtonBlockElement(string(control), value,
+			slack.NewTextBlockObject(slack.PlainTextType, control.Label(), true, false))
+		if control == domain.ControlStop {
+			button = button.WithStyle(slack.StyleDanger)
+		}
+		buttons = append(buttons, button)
+	}
+
+	// Blocks replace the message text, so the text is shown in sections
+	var blocks []slack.Block
+	for _, chunk := range SplitMessage(text, sectionTextLimit) {
+		blocks = append(blocks, slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, chunk, false, false), nil, nil))
+	}
+	return append(blocks, slack.NewActionBlock("controls", buttons...))
+}
+
+// ExtractActionsFromInteraction extracts clicks on the bot's controls from an interaction payload
+func ExtractActionsFromInteraction(callback slack.InteractionCallback) []*domain.Action {
+	if callback.Type != slack.InteractionTypeBlockActions {
+		return nil
+	}
+
+	channelID := callback.Channel.ID
+	if channelID == "" {
+		channelID = callback.Container.ChannelID
+	}
+
+	var actions []*domain.Action
+	for _, blockAction := range callback.ActionCallback.BlockActions {
+		// The button value carries the thread and the answer; fall back to the message's thread
+		threadTS, answerTS, _ := strings.Cut(blockAction.Value, " ")
+		if answerTS == "" {
+			answerTS = callback.Container.MessageTs
+		}
+		if threadTS == "" {
+			threadTS = callback.Container.ThreadTs
+		}
+		if threadTS == "" {
+			threadTS = callback.Message.ThreadTimestamp
+		}
+		actions = append(actions, &domain.Action{
+			TeamID:    callback.Team.ID,
+			UserID:    callback.User.ID,
+			ChannelID: channelID,
+			ThreadTS:  threadTS,
+			MessageTS: callback.Container.MessageTs,
+			AnswerTS:  answerTS,
+			Control:   domain.Control(blockAction.ActionID),
+		})
+	}
+	return actions
+}
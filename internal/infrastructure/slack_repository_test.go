@@ -1,9 +1,13 @@
 package infrastructure_test
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
+	"github.com/takutakahashi/slack-agent/internal/domain"
 	"github.com/takutakahashi/slack-agent/internal/infrastructure"
 )
 
@@ -98,6 +102,51 @@ func TestExtractMessageFromEvent(t *testing.T) {
 			expectedThread:  "",
 			expectedOK:      false,
 		},
+		{
+			name: "edited message (should be filtered)",
+			event: slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Data: &slackevents.MessageEvent{
+						Channel: "D789012",
+						SubType: "message_changed",
+						Message: &slack.Msg{User: "U123456", Text: "Edited"},
+					},
+				},
+			},
+			expectedOK: false,
+		},
+		{
+			name: "deleted message (should be filtered)",
+			event: slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Data: &slackevents.MessageEvent{
+						Channel:          "D789012",
+						SubType:          "message_deleted",
+						DeletedTimeStamp: "1234567890.123456",
+					},
+				},
+			},
+			expectedOK: false,
+		},
+		{
+			name: "file share",
+			event: slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Data: &slackevents.MessageEvent{
+						User:      "U123456",
+						Channel:   "D789012",
+						Text:      "See attached",
+						SubType:   "file_share",
+						TimeStamp: "1234567890.123456",
+					},
+				},
+			},
+			expectedUser:    "U123456",
+			expectedChannel: "D789012",
+			expectedText:    "See attached",
+			expectedThread:  "1234567890.123456",
+			expectedOK:      true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -122,3 +171,190 @@ func TestExtractMessageFromEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractReactionFromEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		event      slackevents.EventsAPIEvent
+		expectedTS string
+		expectedOK bool
+	}{
+		{
+			name: "reaction on message",
+			event: slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Data: &slackevents.ReactionAddedEvent{
+						User:     "U123456",
+						ItemUser: "UBOT",
+						Reaction: "octagonal_sign",
+						Item: slackevents.Item{
+							Type:      "message",
+							Channel:   "C789012",
+							Timestamp: "1234567890.123456",
+						},
+					},
+				},
+			},
+			expectedTS: "1234567890.123456",
+			expectedOK: true,
+		},
+		{
+			name: "reaction on file",
+			event: slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Data: &slackevents.ReactionAddedEvent{
+						User:     "U123456",
+						Reaction: "octagonal_sign",
+						Item: slackevents.Item{
+							Type: "file",
+						},
+					},
+				},
+			},
+			expectedOK: false,
+		},
+		{
+			name: "message event",
+			event: slackevents.EventsAPIEvent{
+				InnerEvent: slackevents.EventsAPIInnerEvent{
+					Data: &slackevents.MessageEvent{
+						User: "U123456",
+					},
+				},
+			},
+			expectedOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reaction, ok := infrastructure.ExtractReactionFromEvent(tt.event)
+
+			if ok != tt.expectedOK {
+				t.Fatalf("expected ok %t, got %t", tt.expectedOK, ok)
+			}
+			if !ok {
+				return
+			}
+			if reaction.ItemTS != tt.expectedTS {
+				t.Errorf("expected item ts %s, got %s", tt.expectedTS, reaction.ItemTS)
+			}
+			if reaction.ItemUserID != "UBOT" {
+				t.Errorf("expected item user UBOT, got %s", reaction.ItemUserID)
+			}
+			if reaction.Name != "octagonal_sign" {
+				t.Errorf("expected reaction octagonal_sign, got %s", reaction.Name)
+			}
+		})
+	}
+}
+
+func TestExtractFilesFromEvent(t *testing.T) {
+	const file = `{"id":"F1","name":"app.log","mimetype":"text/plain","size":42,"url_private":"https://files.slack.com/F1","url_private_download":"https://files.slack.com/F1/download"}`
+
+	tests := []struct {
+		name          string
+		inner         string
+		expectedFiles int
+	}{
+		{
+			name:          "app mention with file",
+			inner:         `{"type":"app_mention","user":"U1","channel":"C1","text":"<@UBOT> what's wrong?","ts":"1.1","files":[` + file + `]}`,
+			expectedFiles: 1,
+		},
+		{
+			name:          "file share message",
+			inner:         `{"type":"message","subtype":"file_share","user":"U1","channel":"D1","text":"what's wrong?","ts":"1.1","files":[` + file + `]}`,
+			expectedFiles: 1,
+		},
+		{
+			name:  "message without files",
+			inner: `{"type":"message","user":"U1","channel":"D1","text":"hello","ts":"1.1"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"type":"event_callback","team_id":"T1","event":` + tt.inner + `}`
+			event, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+			if err != nil {
+				t.Fatalf("failed to parse event: %v", err)
+			}
+
+			files := infrastructure.ExtractFilesFromEvent(event)
+			if len(files) != tt.expectedFiles {
+				t.Fatalf("expected %d files, got %d", tt.expectedFiles, len(files))
+			}
+			if tt.expectedFiles == 0 {
+				return
+			}
+			expected := domain.File{ID: "F1", Name: "app.log", MimeType: "text/plain", Size: 42, URL: "https://files.slack.com/F1/download"}
+			if files[0] != expected {
+				t.Errorf("expected %+v, got %+v", expected, files[0])
+			}
+		})
+	}
+}
+
+func TestExtractActionsFromInteraction(t *testing.T) {
+	callback := slack.InteractionCallback{
+		Type:    slack.InteractionTypeBlockActions,
+		Team:    slack.Team{ID: "T1"},
+		User:    slack.User{ID: "U1"},
+		Channel: slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C1"}}},
+		Container: slack.Container{
+			MessageTs: "1.2",
+			ThreadTs:  "1.0",
+		},
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{
+				{ActionID: "regenerate", Value: "1.1 1.2"},
+				{ActionID: "feedback_good"},
+			},
+		},
+	}
+
+	actions := infrastructure.ExtractActionsFromInteraction(callback)
+	if len(actions) != 2 {
+		t.Fatalf("expected 2 actions, got %d", len(actions))
+	}
+	want := domain.Action{TeamID: "T1", UserID: "U1", ChannelID: "C1", ThreadTS: "1.1", MessageTS: "1.2", AnswerTS: "1.2", Control: domain.ControlRegenerate}
+	if *actions[0] != want {
+		t.Errorf("expected %+v, got %+v", want, *actions[0])
+	}
+	// Without a value the thread of the container is used
+	if actions[1].ThreadTS != "1.0" || actions[1].AnswerTS != "1.2" || actions[1].Control != domain.ControlGood {
+		t.Errorf("unexpected action: %+v", *actions[1])
+	}
+
+	callback.Type = slack.InteractionTypeViewSubmission
+	if actions := infrastructure.ExtractActionsFromInteraction(callback); len(actions) != 0 {
+		t.Errorf("expected other interactions to be ignored, got %d actions", len(actions))
+	}
+}
+
+func TestControlBlocks(t *testing.T) {
+	blocks := infrastructure.ControlBlocks("Working on it", "1.1", "", []domain.Control{domain.ControlStop})
+	if len(blocks) != 2 {
+		t.Fatalf("expected a section and an actions block, got %d blocks", len(blocks))
+	}
+
+	actions, ok := blocks[1].(*slack.ActionBlock)
+	if !ok || len(actions.Elements.ElementSet) != 1 {
+		t.Fatalf("expected 1 button, got %+v", blocks[1])
+	}
+	stop := actions.Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if stop.ActionID != "stop" || stop.Value != "1.1" || stop.Style != slack.StyleDanger {
+		t.Errorf("unexpected stop button: %+v", stop)
+	}
+
+	// Long answers are split over several sections and the buttons refer to the answer
+	blocks = infrastructure.ControlBlocks(strings.Repeat("a", 4000), "1.1", "1.2", []domain.Control{domain.ControlGood})
+	if len(blocks) != 3 {
+		t.Fatalf("expected two sections and an actions block, got %d blocks", len(blocks))
+	}
+	good := blocks[2].(*slack.ActionBlock).Elements.ElementSet[0].(*slack.ButtonBlockElement)
+	if good.ActionID != "feedback_good" || good.Value != "1.1 1.2" {
+		t.Errorf("unexpected feedback button: %+v", good)
+	}
+}
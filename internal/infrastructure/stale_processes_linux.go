@@ -0,0 +1,139 @@
+//go:build linux
+
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// processRecord identifies the process group of a running agent and the bot process that started it.
+// Processes are identified by their ID and start time, since IDs are reused once a process exits,
+// and start times are only comparable within the same boot.
+type processRecord struct {
+	BootID     string `json:"boot_id"`
+	Pgid       int    `json:"pgid"`
+	Start      uint64 `json:"start"`
+	OwnerPid   int    `json:"owner_pid"`
+	OwnerStart uint64 `json:"owner_start"`
+}
+
+var (
+	selfOnce   sync.Once
+	selfRecord processRecord
+	selfErr    error
+)
+
+// self returns the boot and start time of this process, looked up once
+func self() (processRecord, error) {
+	selfOnce.Do(func() {
+		bootID, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+		if err != nil {
+			selfErr = fmt.Errorf("failed to read the boot ID: %w", err)
+			return
+		}
+		pid := os.Getpid()
+		start, err := processStart(pid)
+		if err != nil {
+			selfErr = err
+			return
+		}
+		selfRecord = processRecord{BootID: strings.TrimSpace(string(bootID)), OwnerPid: pid, OwnerStart: start}
+	})
+	return selfRecord, selfErr
+}
+
+// recordProcessGroup records the process group of a started command in ProcessesDir, so that
+// ReapStaleProcesses can kill it if the bot crashes. The returned function removes the record.
+func recordProcessGroup(cmd *exec.Cmd) func() {
+	record, err := self()
+	if err == nil {
+		record.Pgid = cmd.Process.Pid
+		record.Start, err = processStart(record.Pgid)
+	}
+	if err != nil {
+		log.Printf("Failed to record the process group of %s: %v", cmd.Path, err)
+		return func() {}
+	}
+
+	path := filepath.Join(ProcessesDir, fmt.Sprintf("%d-%d-%d.json", record.OwnerPid, record.Pgid, record.Start))
+	if err := writeJSONFile(path, record); err != nil {
+		log.Printf("Failed to record the process group of %s: %v", cmd.Path, err)
+		return func() {}
+	}
+	return func() {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to remove %s: %v", path, err)
+		}
+	}
+}
+
+// ReapStaleProcesses kills the process groups recorded in dir by bot processes that are no longer
+// running, such as agents left behind by a previous instance that crashed. Only groups whose leader
+// is still the recorded process are killed. It returns how many process groups were killed.
+func ReapStaleProcesses(dir string) (int, error) {
+	current, err := self()
+	if err != nil {
+		return 0, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	reaped := 0
+	for _, entry := range entries {
+		// Skip files that are still being written
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		var record processRecord
+		if err := readJSONFile(path, &record); err != nil {
+			log.Printf("Dropping unreadable process record %s: %v", path, err)
+			_ = os.Remove(path)
+			continue
+		}
+
+		// Groups of bot processes that still run are theirs to clean up
+		sameBoot := record.BootID == current.BootID
+		if start, err := processStart(record.OwnerPid); sameBoot && err == nil && start == record.OwnerStart {
+			continue
+		}
+		// Records from earlier boots don't refer to processes running now
+		if start, err := processStart(record.Pgid); sameBoot && err == nil && start == record.Start {
+			if err := syscall.Kill(-record.Pgid, syscall.SIGKILL); err == nil {
+				reaped++
+			}
+		}
+		_ = os.Remove(path)
+	}
+	return reaped, nil
+}
+
+// processStart returns the time the process started, in clock ticks after boot
+func processStart(pid int) (uint64, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, err
+	}
+	// The command name in parentheses may contain spaces; the start time is the 22nd field
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 20 {
+		return 0, fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
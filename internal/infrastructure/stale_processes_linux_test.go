@@ -0,0 +1,100 @@
+//go:build linux
+
+package infrastructure_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+)
+
+func TestReapStaleProcesses(t *testing.T) {
+	dir := t.TempDir()
+	bootID, err := os.ReadFile("/proc/sys/kernel/random/boot_id")
+	if err != nil {
+		t.Skipf("boot ID not available: %v", err)
+	}
+
+	// Agents in their own process group, as started by the backends
+	start := func() *exec.Cmd {
+		cmd := exec.Command("sleep", "30")
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start process: %v", err)
+		}
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		return cmd
+	}
+	record := func(name string, pgid int, pgidStart string, owner int, ownerStart string) {
+		data, err := json.Marshal(map[string]any{
+			"boot_id": strings.TrimSpace(string(bootID)), "pgid": pgid, "start": json.Number(pgidStart),
+			"owner_pid": owner, "owner_start": json.Number(ownerStart),
+		})
+		if err != nil {
+			t.Fatalf("failed to encode record: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+			t.Fatalf("failed to write record: %v", err)
+		}
+	}
+
+	// The bot process that started this agent is gone
+	stale := start()
+	record("stale", stale.Process.Pid, startTime(t, stale.Process.Pid), os.Getpid(), "1")
+	// This one belongs to a bot process that still runs
+	running := start()
+	record("running", running.Process.Pid, startTime(t, running.Process.Pid), os.Getpid(), startTime(t, os.Getpid()))
+	// The process ID of this one was reused by another process
+	reused := start()
+	record("reused", reused.Process.Pid, "1", os.Getpid(), "1")
+	// Processes that were never recorded are left alone
+	other := start()
+
+	reaped, err := infrastructure.ReapStaleProcesses(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reaped != 1 {
+		t.Errorf("expected 1 stale process group, got %d", reaped)
+	}
+
+	err = stale.Wait()
+	if status, ok := stale.ProcessState.Sys().(syscall.WaitStatus); !ok || !status.Signaled() || status.Signal() != syscall.SIGKILL {
+		t.Errorf("expected the stale process to be killed, got %v", err)
+	}
+	for _, cmd := range []*exec.Cmd{running, reused, other} {
+		if err := cmd.Process.Signal(syscall.Signal(0)); err != nil {
+			t.Errorf("expected process %d to keep running, got %v", cmd.Process.Pid, err)
+		}
+	}
+
+	// Only the record of the running bot process is kept
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list records: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "running.json" {
+		t.Errorf("expected only the running record to be kept, got %v", entries)
+	}
+}
+
+// startTime returns the start time of the process as recorded in /proc
+func startTime(t *testing.T, pid int) string {
+	t.Helper()
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		t.Fatalf("failed to read the stat of process %d: %v", pid, err)
+	}
+	stat := string(data)
+	return strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])[19]
+}
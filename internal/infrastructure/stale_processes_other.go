@@ -0,0 +1,15 @@
+//go:build !linux
+
+package infrastructure
+
+import "os/exec"
+
+// recordProcessGroup does nothing on platforms where stale processes cannot be reaped
+func recordProcessGroup(cmd *exec.Cmd) func() {
+	return func() {}
+}
+
+// ReapStaleProcesses is only supported on Linux, where running processes can be found through /proc
+func ReapStaleProcesses(dir string) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,312 @@
+package infrastructure
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+const (
+	// slackMessageLimit is the chunk size for long messages.
+	// Slack truncates text beyond 40,000 characters but recommends staying under 4,000.
+	slackMessageLimit = 3900
+	// maxStreamLineSize bounds a single stream-json line; tool results can be large
+	maxStreamLineSize = 16 << 20
+	// DefaultUpdateInterval is the minimum time between edits of the live message.
+	// chat.update is rate limited per workspace, so edits are coalesced.
+	DefaultUpdateInterval = 2 * time.Second
+	// placeholderText is shown until the agent produces output
+	placeholderText = "💭 Thinking..."
+)
+
+// StreamPoster posts Claude's stream-json output to a Slack thread as it arrives.
+// A placeholder message is posted right away and edited in place while the agent works;
+// at the end it is replaced with the final answer and any overflow is posted as separate messages.
+type StreamPoster struct {
+	slackRepo      usecase.SlackRepository
+	channelID      string
+	threadTS       string
+	updateInterval time.Duration
+	debug          bool
+
+	mu        sync.Mutex
+	messageTS string
+	text      string
+	activity  string
+	dirty     bool
+	rendered  string
+}
+
+// NewStreamPoster creates a new StreamPoster instance
+func NewStreamPoster(slackRepo usecase.SlackRepository, channelID, threadTS string) *StreamPoster {
+	return &StreamPoster{
+		slackRepo:      slackRepo,
+		channelID:      channelID,
+		threadTS:       threadTS,
+		updateInterval: DefaultUpdateInterval,
+	}
+}
+
+// SetDebug sets the debug flag
+func (p *StreamPoster) SetDebug(debug bool) {
+	p.debug = debug
+}
+
+// SetUpdateInterval sets the minimum time between edits of the live message
+func (p *StreamPoster) SetUpdateInterval(interval time.Duration) {
+	if interval > 0 {
+		p.updateInterval = interval
+	}
+}
+
+// Run reads stream-json events until EOF, keeping the live message up to date, and posts the final answer.
+// Reading continues after a failed edit so that the producing process is never blocked on a full pipe.
+// The returned result is marked as posted; its Error is a *domain.AgentError when something went wrong.
+func (p *StreamPoster) Run(ctx context.Context, r io.Reader) *domain.AgentResult {
+	result := domain.NewAgentResult("", nil)
+	result.Posted = true
+
+	ts, err := p.slackRepo.PostMessage(ctx, p.channelID, placeholderText, p.threadTS)
+	if err != nil {
+		log.Printf("Error posting placeholder message: %v", err)
+	}
+	p.mu.Lock()
+	p.messageTS = ts
+	p.rendered = placeholderText
+	p.mu.Unlock()
+
+	// Flush pending changes at a throttled rate while the stream is being read
+	done := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		ticker := time.NewTicker(p.updateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.flush(ctx)
+			}
+		}
+	}()
+
+	gotResult := false
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(strings.TrimSpace(string(line))) == 0 {
+			continue
+		}
+
+		event, err := ParseStreamEvent(line)
+		if err != nil {
+			// claude may print non-JSON lines (e.g. warnings); they are not fatal
+			log.Printf("Skipping unparsable agent output: %v", err)
+			continue
+		}
+		if p.debug {
+			log.Printf("Stream event: type=%s subtype=%s", event.Type, event.Subtype)
+		}
+		if event.SessionID != "" {
+			result.SessionID = event.SessionID
+		}
+
+		switch event.Type {
+		case StreamEventAssistant:
+			if event.Message == nil {
+				continue
+			}
+			if text := event.Message.Text(); text != "" {
+				p.appendText(text)
+			}
+			for _, use := range event.Message.ToolUses() {
+				p.setActivity(use.ToolSummary())
+			}
+
+		case StreamEventResult:
+			gotResult = true
+			result.Response = event.Result
+			result.NumTurns = event.NumTurns
+			result.CostUSD = event.TotalCostUSD
+			result.Duration = time.Duration(event.DurationMS) * time.Millisecond
+
+			if event.IsError {
+				message := event.Result
+				if message == "" {
+					message = fmt.Sprintf("agent finished with %s", event.Subtype)
+				}
+				result.Error = &domain.AgentError{Kind: domain.AgentErrorResult, Message: message}
+			}
+		}
+	}
+	if scanner.Err() != nil {
+		// Keep reading so that the agent does not block on a full pipe until it times out
+		_, _ = io.Copy(io.Discard, r)
+	}
+
+	close(done)
+	<-flushed
+
+	// The final edit must go out even if the run was cancelled
+	finalCtx := context.WithoutCancel(ctx)
+	final := result.Response
+	if result.Error != nil || strings.TrimSpace(final) == "" {
+		// Keep whatever was streamed so far
+		final = p.currentText()
+	}
+	var postErr error
+	result.PostedTS, result.PostedText, postErr = p.finish(finalCtx, final)
+
+	switch {
+	case result.Error != nil:
+	case postErr != nil:
+		result.Error = &domain.AgentError{Kind: domain.AgentErrorPost, Message: "failed to post agent output", Err: postErr}
+	case scanner.Err() != nil:
+		result.Error = &domain.AgentError{Kind: domain.AgentErrorStream, Message: "failed to read agent output", Err: scanner.Err()}
+	case !gotResult:
+		result.Error = &domain.AgentError{Kind: domain.AgentErrorStream, Message: "agent output ended without a result"}
+	}
+
+	return result
+}
+
+// appendText adds assistant text to the live message
+func (p *StreamPoster) appendText(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.text != "" {
+		p.text += "\n\n"
+	}
+	p.text += text
+	p.activity = ""
+	p.dirty = true
+}
+
+// setActivity shows what the agent is currently doing below the streamed text
+func (p *StreamPoster) setActivity(activity string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.activity = activity
+	p.dirty = true
+}
+
+// currentText returns the assistant text streamed so far
+func (p *StreamPoster) currentText() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.text
+}
+
+// render builds the in-progress view of the live message; p.mu must be held
+func (p *StreamPoster) render() string {
+	text := p.text
+	// Show the most recent part of long output; the full text is posted at the end
+	if runes := []rune(text); len(runes) > slackMessageLimit-200 {
+		text = "…" + string(runes[len(runes)-(slackMessageLimit-200):])
+	}
+
+	status := p.activity
+	if status == "" {
+		status = placeholderText
+	}
+	if text == "" {
+		return status
+	}
+	return text + "\n\n" + status
+}
+
+// flush edits the live message if anything changed since the last edit
+func (p *StreamPoster) flush(ctx context.Context) {
+	p.mu.Lock()
+	if !p.dirty || p.messageTS == "" {
+		p.mu.Unlock()
+		return
+	}
+	text := p.render()
+	ts := p.messageTS
+	p.dirty = false
+	p.mu.Unlock()
+
+	if text == p.rendered {
+		return
+	}
+	if err := p.slackRepo.UpdateMessage(ctx, p.channelID, ts, text); err != nil {
+		// A missed intermediate edit is harmless; the next one catches up
+		log.Printf("Error updating live message: %v", err)
+		return
+	}
+
+	p.mu.Lock()
+	p.rendered = text
+	p.mu.Unlock()
+}
+
+// finish swaps the live message for the final text and posts the overflow as separate messages.
+// It returns the messages the answer was posted as and the text of the last one.
+func (p *StreamPoster) finish(ctx context.Context, final string) ([]string, string, error) {
+	chunks := SplitMessage(final, slackMessageLimit)
+	if len(chunks) == 0 {
+		chunks = []string{"⚠️ No answer was produced."}
+	}
+
+	p.mu.Lock()
+	ts := p.messageTS
+	p.mu.Unlock()
+
+	var posted []string
+	last := chunks[len(chunks)-1]
+	if ts != "" {
+		if err := p.slackRepo.UpdateMessage(ctx, p.channelID, ts, chunks[0]); err != nil {
+			return posted, last, err
+		}
+		posted = append(posted, ts)
+		chunks = chunks[1:]
+	}
+	for _, chunk := range chunks {
+		ts, err := p.slackRepo.PostMessage(ctx, p.channelID, chunk, p.threadTS)
+		if err != nil {
+			return posted, last, err
+		}
+		posted = append(posted, ts)
+	}
+	return posted, last, nil
+}
+
+// SplitMessage splits text into chunks of at most limit runes, preferring line boundaries
+func SplitMessage(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var chunks []string
+	for {
+		runes := []rune(text)
+		if len(runes) <= limit {
+			return append(chunks, text)
+		}
+
+		cut := limit
+		for i := limit - 1; i > 0; i-- {
+			if runes[i] == '\n' {
+				cut = i
+				break
+			}
+		}
+		chunks = append(chunks, strings.TrimRight(string(runes[:cut]), "\n"))
+		text = strings.TrimLeft(string(runes[cut:]), "\n")
+	}
+}
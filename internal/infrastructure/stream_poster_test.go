@@ -0,0 +1,225 @@
+package infrastructure_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+const sampleStream = `{"type":"system","subtype":"init","session_id":"sess-1","model":"claude","tools":["Bash"]}
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Let me check."},{"type":"tool_use","id":"t1","name":"Bash","input":{"command":"ls -la\necho done"}}]},"session_id":"sess-1"}
+{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"file.txt"}]},"session_id":"sess-1"}
+not json at all
+{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"There is one file."}]},"session_id":"sess-1"}
+{"type":"result","subtype":"success","is_error":false,"result":"There is one file.","session_id":"sess-1","num_turns":2,"duration_ms":1500,"total_cost_usd":0.01}
+`
+
+// threadRecorder records what a StreamPoster posts and edits
+type threadRecorder struct {
+	mu      sync.Mutex
+	posts   []string
+	updates []string
+}
+
+func (rec *threadRecorder) expect(slackRepo *mocks.MockSlackRepository, postErr, updateErr error) {
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C1", gomock.Any(), "1.1").DoAndReturn(
+		func(ctx context.Context, channelID, text, threadTS string) (string, error) {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.posts = append(rec.posts, text)
+			if postErr != nil {
+				return "", postErr
+			}
+			return "2.2", nil
+		}).AnyTimes()
+	slackRepo.EXPECT().UpdateMessage(gomock.Any(), "C1", "2.2", gomock.Any()).DoAndReturn(
+		func(ctx context.Context, channelID, ts, text string) error {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.updates = append(rec.updates, text)
+			return updateErr
+		}).AnyTimes()
+}
+
+func TestStreamPoster_Run(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	rec := &threadRecorder{}
+	rec.expect(slackRepo, nil, nil)
+
+	result := infrastructure.NewStreamPoster(slackRepo, "C1", "1.1").Run(context.Background(), strings.NewReader(sampleStream))
+
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	if len(rec.posts) != 1 || !strings.Contains(rec.posts[0], "Thinking") {
+		t.Errorf("expected only the placeholder to be posted, got %q", rec.posts)
+	}
+	if len(rec.updates) == 0 || rec.updates[len(rec.updates)-1] != "There is one file." {
+		t.Errorf("expected the placeholder to be replaced with the final answer, got %q", rec.updates)
+	}
+	if !result.Posted || result.SessionID != "sess-1" || result.NumTurns != 2 || result.Response != "There is one file." {
+		t.Errorf("unexpected result metadata: %+v", result)
+	}
+	if len(result.PostedTS) != 1 || result.PostedTS[0] != "2.2" || result.PostedText != "There is one file." {
+		t.Errorf("expected the answer to be the live message, got %q %q", result.PostedTS, result.PostedText)
+	}
+}
+
+func TestStreamPoster_LiveUpdates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	rec := &threadRecorder{}
+	rec.expect(slackRepo, nil, nil)
+
+	reader, writer := io.Pipe()
+	poster := infrastructure.NewStreamPoster(slackRepo, "C1", "1.1")
+	poster.SetUpdateInterval(10 * time.Millisecond)
+
+	done := make(chan *domain.AgentResult)
+	go func() { done <- poster.Run(context.Background(), reader) }()
+
+	_, _ = writer.Write([]byte(`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Working on it"},{"type":"tool_use","name":"Read","input":{"file_path":"main.go"}}]}}` + "\n"))
+	time.Sleep(100 * time.Millisecond)
+	_, _ = writer.Write([]byte(`{"type":"result","subtype":"success","result":"Done."}` + "\n"))
+	_ = writer.Close()
+	<-done
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.updates) < 2 {
+		t.Fatalf("expected an intermediate and a final update, got %q", rec.updates)
+	}
+	if !strings.Contains(rec.updates[0], "Working on it") || !strings.Contains(rec.updates[0], "*Read*: `main.go`") {
+		t.Errorf("expected intermediate update to show text and tool use, got %q", rec.updates[0])
+	}
+	if rec.updates[len(rec.updates)-1] != "Done." {
+		t.Errorf("expected final update, got %q", rec.updates[len(rec.updates)-1])
+	}
+}
+
+func TestStreamPoster_Overflow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	rec := &threadRecorder{}
+	rec.expect(slackRepo, nil, nil)
+
+	long := strings.Repeat("a", 5000)
+	stream := `{"type":"result","subtype":"success","result":"` + long + `"}`
+	result := infrastructure.NewStreamPoster(slackRepo, "C1", "1.1").Run(context.Background(), strings.NewReader(stream))
+
+	if result.IsError() {
+		t.Fatalf("unexpected error: %v", result.Error)
+	}
+	// placeholder + overflow
+	if len(rec.posts) != 2 || len(rec.posts[1]) != 5000-3900 {
+		t.Errorf("expected the overflow to be posted separately, got %d posts", len(rec.posts))
+	}
+	if last := rec.updates[len(rec.updates)-1]; len(last) != 3900 {
+		t.Errorf("expected the first chunk in the live message, got %d chars", len(last))
+	}
+	if len(result.PostedTS) != 2 || len(result.PostedText) != 5000-3900 {
+		t.Errorf("expected the answer to span both messages and end with the overflow, got %q", result.PostedTS)
+	}
+}
+
+func TestStreamPoster_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    string
+		updateErr error
+		expected  domain.AgentErrorKind
+	}{
+		{
+			name:     "error result",
+			stream:   `{"type":"result","subtype":"error_max_turns","is_error":true}`,
+			expected: domain.AgentErrorResult,
+		},
+		{
+			name:     "missing result",
+			stream:   `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"hi"}]}}`,
+			expected: domain.AgentErrorStream,
+		},
+		{
+			name:      "final update failure",
+			stream:    `{"type":"result","subtype":"success","result":"bye"}`,
+			updateErr: errors.New("rate_limited"),
+			expected:  domain.AgentErrorPost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			rec := &threadRecorder{}
+			rec.expect(slackRepo, nil, tt.updateErr)
+
+			result := infrastructure.NewStreamPoster(slackRepo, "C1", "1.1").Run(context.Background(), strings.NewReader(tt.stream))
+			if result.AgentErrorKind() != tt.expected {
+				t.Errorf("expected error kind %q, got %q (%v)", tt.expected, result.AgentErrorKind(), result.Error)
+			}
+		})
+	}
+}
+
+func TestStreamPoster_OversizedLine(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	rec := &threadRecorder{}
+	rec.expect(slackRepo, nil, nil)
+
+	// The agent keeps writing after a line the scanner cannot hold, like claude does through a pipe
+	r, w := io.Pipe()
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		_, _ = io.WriteString(w, `{"type":"user","content":"`+strings.Repeat("x", 17<<20)+"\"}\n")
+		_, _ = io.WriteString(w, `{"type":"result","subtype":"success","result":"done"}`+"\n")
+		w.Close()
+	}()
+
+	result := infrastructure.NewStreamPoster(slackRepo, "C1", "1.1").Run(context.Background(), r)
+	if result.AgentErrorKind() != domain.AgentErrorStream {
+		t.Errorf("expected a stream error, got %q (%v)", result.AgentErrorKind(), result.Error)
+	}
+	select {
+	case <-written:
+	case <-time.After(10 * time.Second):
+		r.Close()
+		t.Fatal("expected the rest of the output to be drained")
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		limit    int
+		expected []string
+	}{
+		{name: "empty", text: "  ", limit: 10, expected: nil},
+		{name: "short", text: "hello", limit: 10, expected: []string{"hello"}},
+		{name: "split on newline", text: "aaaa\nbbbb\ncccc", limit: 10, expected: []string{"aaaa\nbbbb", "cccc"}},
+		{name: "hard split", text: "abcdefghijkl", limit: 5, expected: []string{"abcde", "fghij", "kl"}},
+		{name: "multibyte", text: "あいうえおかきくけこ", limit: 4, expected: []string{"あいうえ", "おかきく", "けこ"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := infrastructure.SplitMessage(tt.text, tt.limit)
+			if strings.Join(chunks, "|") != strings.Join(tt.expected, "|") || len(chunks) != len(tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, chunks)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+	"github.com/takutakahashi/slack-agent/pkg/config"
+)
+
+var (
+	feedbackSince  string
+	feedbackFormat string
+)
+
+// feedbackCmd represents the feedback command
+var feedbackCmd = &cobra.Command{
+	Use:   "feedback",
+	Short: "Review feedback on the agent's answers",
+}
+
+// feedbackExportCmd represents the feedback export command
+var feedbackExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the recorded feedback to stdout",
+	Long: `Write the 👍/👎 feedback recorded from reactions and buttons to stdout,
+together with the prompt, a summary of the answer, the latency and the channel profile.
+--since takes a duration such as 24h or 7d, or a date such as 2026-01-31.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseSince(feedbackSince, time.Now())
+		if err != nil {
+			return err
+		}
+		if feedbackFormat != "csv" && feedbackFormat != "jsonl" {
+			return fmt.Errorf("unknown format %q: use csv or jsonl", feedbackFormat)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("Failed to load configuration: %v", err)
+		}
+		feedbackRepo, err := openFeedbackRepository(cfg)
+		if err != nil {
+			return err
+		}
+
+		feedback, err := feedbackRepo.List(context.Background(), since)
+		if err != nil {
+			return err
+		}
+		if feedbackFormat == "csv" {
+			return writeFeedbackCSV(cmd.OutOrStdout(), feedback)
+		}
+		return writeFeedbackJSONL(cmd.OutOrStdout(), feedback)
+	},
+}
+
+func init() {
+	feedbackExportCmd.Flags().StringVar(&feedbackSince, "since", "", "only export feedback newer than a duration (e.g. 7d) or date (e.g. 2026-01-31)")
+	feedbackExportCmd.Flags().StringVar(&feedbackFormat, "format", "csv", "output format: csv or jsonl")
+	feedbackCmd.AddCommand(feedbackExportCmd)
+	rootCmd.AddCommand(feedbackCmd)
+}
+
+// openFeedbackRepository opens the feedback store in the configured state directory
+func openFeedbackRepository(cfg *config.Config) (*infrastructure.FileFeedbackRepository, error) {
+	feedbackRepo, err := infrastructure.NewFileFeedbackRepository(filepath.Join(cfg.App.StateDir, "feedback.jsonl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create feedback repository: %w", err)
+	}
+	return feedbackRepo, nil
+}
+
+// parseSince parses a duration before now, such as 24h or 7d, or an RFC 3339 time or date.
+// An empty value means the beginning of time.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration like 7d or a date like 2026-01-31", value)
+}
+
+// feedbackExport is the exported form of feedback
+type feedbackExport struct {
+	Time           time.Time `json:"time"`
+	TeamID         string    `json:"team_id"`
+	ChannelID      string    `json:"channel_id"`
+	ThreadTS       string    `json:"thread_ts"`
+	MessageTS      string    `json:"message_ts"`
+	UserID         string    `json:"user_id"`
+	Rating         string    `json:"rating"`
+	Source         string    `json:"source"`
+	Profile        string    `json:"profile"`
+	LatencySeconds float64   `json:"latency_seconds"`
+	Prompt         string    `json:"prompt"`
+	Response       string    `json:"response"`
+}
+
+// newFeedbackExport converts feedback into its exported form
+func newFeedbackExport(feedback *domain.Feedback) feedbackExport {
+	return feedbackExport{
+		Time:           feedback.Time,
+		TeamID:         feedback.Key.TeamID,
+		ChannelID:      feedback.Key.ChannelID,
+		ThreadTS:       feedback.Key.ThreadTS,
+		MessageTS:      feedback.MessageTS,
+		UserID:         feedback.UserID,
+		Rating:         string(feedback.Rating),
+		Source:         string(feedback.Source),
+		Profile:        feedback.Profile,
+		LatencySeconds: feedback.Latency.Seconds(),
+		Prompt:         feedback.Prompt,
+		Response:       feedback.Response,
+	}
+}
+
+// writeFeedbackJSONL writes one JSON object per line
+func writeFeedbackJSONL(w io.Writer, feedback []*domain.Feedback) error {
+	encoder := json.NewEncoder(w)
+	for _, f := range feedback {
+		if err := encoder.Encode(newFeedbackExport(f)); err != nil {
+			return fmt.Errorf("failed to write feedback: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeFeedbackCSV writes the feedback as CSV with a header row
+func writeFeedbackCSV(w io.Writer, feedback []*domain.Feedback) error {
+	writer := csv.NewWriter(w)
+	_ = writer.Write([]string{"time", "team_id", "channel_id", "thread_ts", "message_ts", "user_id",
+		"rating", "source", "profile", "latency_seconds", "prompt", "response"})
+	for _, f := range feedback {
+		e := newFeedbackExport(f)
+		_ = writer.Write([]string{e.Time.Format(time.RFC3339), e.TeamID, e.ChannelID, e.ThreadTS, e.MessageTS, e.UserID,
+			e.Rating, e.Source, e.Profile, strconv.FormatFloat(e.LatencySeconds, 'f', 1, 64), e.Prompt, e.Response})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write feedback: %w", err)
+	}
+	return nil
+}
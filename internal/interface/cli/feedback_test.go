@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value     string
+		want      time.Time
+		expectErr bool
+	}{
+		{value: "", want: time.Time{}},
+		{value: "24h", want: now.Add(-24 * time.Hour)},
+		{value: "7d", want: now.AddDate(0, 0, -7)},
+		{value: "2026-03-01T00:00:00Z", want: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2026-03-01", want: time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)},
+		{value: "last week", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSince(tt.value, now)
+			if tt.expectErr {
+				if err == nil {
+					t.Errorf("expected error for %q", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWriteFeedback(t *testing.T) {
+	feedback := []*domain.Feedback{{
+		Time:     time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC),
+		Key:      domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: "1.1"},
+		UserID:   "U1",
+		Rating:   domain.RatingBad,
+		Source:   domain.FeedbackReaction,
+		Profile:  "infra",
+		Prompt:   "why is CI red?",
+		Response: "line one,\nline two",
+		Latency:  1500 * time.Millisecond,
+	}}
+
+	var csvOut bytes.Buffer
+	if err := writeFeedbackCSV(&csvOut, feedback); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(csvOut.String(), "time,team_id,channel_id,thread_ts,") {
+		t.Errorf("expected a header row, got %q", csvOut.String())
+	}
+	if !strings.Contains(csvOut.String(), `2026-03-01T09:30:00Z,T1,C1,1.1,,U1,bad,reaction,infra,1.5,why is CI red?,"line one,`) {
+		t.Errorf("unexpected CSV: %q", csvOut.String())
+	}
+
+	var jsonOut bytes.Buffer
+	if err := writeFeedbackJSONL(&jsonOut, feedback); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(jsonOut.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"latency_seconds":1.5`) {
+		t.Errorf("unexpected JSON Lines: %q", jsonOut.String())
+	}
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+)
+
+var (
+	postBotToken       string
+	postChannelID      string
+	postThreadTS       string
+	postUpdateInterval time.Duration
+)
+
+// postCmd represents the post command
+var postCmd = &cobra.Command{
+	Use:   "post",
+	Short: "Post Claude stream-json output from stdin to a Slack thread",
+	Long: `Read Claude's --output-format stream-json output from stdin and post assistant text,
+tool-use summaries and the final result to a Slack thread.
+
+Example:
+  claude -p --output-format stream-json --verbose "hello" | slack-agent post --channel-id C123 --thread-ts 1700000000.000100`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if postBotToken == "" {
+			postBotToken = os.Getenv("SLACK_BOT_TOKEN")
+		}
+		if postBotToken == "" || postChannelID == "" {
+			return fmt.Errorf("--bot-token (or SLACK_BOT_TOKEN) and --channel-id are required")
+		}
+
+		slackRepo, err := infrastructure.NewSlackRepository(postBotToken, "")
+		if err != nil {
+			return fmt.Errorf("failed to create slack repository: %w", err)
+		}
+
+		poster := infrastructure.NewStreamPoster(slackRepo, postChannelID, postThreadTS)
+		poster.SetUpdateInterval(postUpdateInterval)
+		result := poster.Run(context.Background(), cmd.InOrStdin())
+		if result.IsError() {
+			return result.Error
+		}
+		return nil
+	},
+}
+
+func init() {
+	postCmd.Flags().StringVar(&postBotToken, "bot-token", "", "Slack bot token (default $SLACK_BOT_TOKEN)")
+	postCmd.Flags().StringVar(&postChannelID, "channel-id", "", "channel to post to")
+	postCmd.Flags().StringVar(&postThreadTS, "thread-ts", "", "thread to reply in")
+	postCmd.Flags().DurationVar(&postUpdateInterval, "update-interval", infrastructure.DefaultUpdateInterval, "minimum time between edits of the live message")
+	rootCmd.AddCommand(postCmd)
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"github.com/takutakahashi/slack-agent/pkg/config"
+)
+
+var pruneDryRun bool
+
+// sessionsCmd represents the sessions command
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "Manage stored agent sessions",
+}
+
+// sessionsPruneCmd represents the sessions prune command
+var sessionsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Evict agent sessions according to the retention policy",
+	Long: `Apply the same retention policy as the background janitor once:
+sessions inactive for longer than SESSION_TTL are removed, then the least recently used
+sessions are removed until SESSION_MAX_COUNT and SESSION_MAX_DISK_MB are met.
+Running sessions are never removed.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("Failed to load configuration: %v", err)
+		}
+
+		sessionRepo, err := openSessionRepository(cfg)
+		if err != nil {
+			return err
+		}
+		// Channel names are not looked up, so name-matched profiles only apply to sessions that recorded their directory
+		workspaces := newSessionWorkspace(sessionRepo, usecase.NewProfileResolver(buildProfiles(cfg), nil))
+		cluster, err := newCluster(cfg)
+		if err != nil {
+			return err
+		}
+		janitor := newSessionJanitor(cfg, sessionRepo, workspaces, cluster)
+
+		report, err := janitor.Prune(context.Background(), time.Now(), pruneDryRun)
+		if report != nil {
+			printPruneReport(cmd, report, pruneDryRun)
+		}
+		return err
+	},
+}
+
+func init() {
+	sessionsPruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "only show which sessions would be removed")
+	sessionsCmd.AddCommand(sessionsPruneCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+// newSessionWorkspace manages the sessions' working directories, resolving them as the agent backends do
+func newSessionWorkspace(sessionRepo usecase.SessionRepository, profiles *usecase.ProfileResolver) *infrastructure.SessionWorkspace {
+	workspaces := infrastructure.NewSessionWorkspace(infrastructure.SessionsDir)
+	workspaces.SetProfiles(profiles)
+	workspaces.SetSessions(sessionRepo)
+	return workspaces
+}
+
+// openSessionRepository opens the session store in the configured state directory
+func openSessionRepository(cfg *config.Config) (*infrastructure.FileSessionRepository, error) {
+	sessionRepo, err := infrastructure.NewFileSessionRepository(filepath.Join(cfg.App.StateDir, "sessions"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session repository: %w", err)
+	}
+	return sessionRepo, nil
+}
+
+// newSessionJanitor creates the session janitor with the configured retention policy.
+// cluster may be nil when the bot runs as a single replica.
+func newSessionJanitor(cfg *config.Config, sessionRepo usecase.SessionRepository, workspaces usecase.WorkspaceRepository, cluster *usecase.Cluster) *usecase.SessionJanitor {
+	janitor := usecase.NewSessionJanitor(sessionRepo, workspaces, usecase.RetentionPolicy{
+		TTL:           cfg.App.SessionTTL,
+		MaxTotalBytes: cfg.App.SessionMaxDiskMB * 1024 * 1024,
+		MaxSessions:   cfg.App.SessionMaxCount,
+	})
+	janitor.SetCluster(cluster)
+	return janitor
+}
+
+// printPruneReport writes the evicted sessions as a table
+func printPruneReport(cmd *cobra.Command, report *usecase.PruneReport, dryRun bool) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tLAST ACTIVITY\tBYTES\tREASON")
+	for _, pruned := range report.Pruned {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", pruned.Session.Key, pruned.Session.LastActivity.Format(time.RFC3339), pruned.Bytes, pruned.Reason)
+	}
+	w.Flush()
+
+	verb := "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s %d sessions; %d sessions using %d bytes remain\n", verb, len(report.Pruned), report.Remaining, report.RemainingBytes)
+}
@@ -2,22 +2,40 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
 	"github.com/spf13/cobra"
 	"github.com/takutakahashi/slack-agent/internal/domain"
 	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+	"github.com/takutakahashi/slack-agent/internal/interface/dedup"
+	"github.com/takutakahashi/slack-agent/internal/interface/dispatcher"
+	"github.com/takutakahashi/slack-agent/internal/interface/health"
+	"github.com/takutakahashi/slack-agent/internal/interface/webapi"
 	"github.com/takutakahashi/slack-agent/internal/usecase"
 	"github.com/takutakahashi/slack-agent/pkg/config"
 )
 
+// eventLoopStallTimeout is how long the Socket Mode event loop may be blocked before the liveness probe fails
+const eventLoopStallTimeout = 2 * time.Minute
+
+// shutdownNoticeTimeout bounds how long cancelled runs may take to tell their threads about the restart
+const shutdownNoticeTimeout = 30 * time.Second
+
+// clusterPollInterval is how often a replica checks its inbox for events handed off by other replicas
+const clusterPollInterval = time.Second
+
 // startCmd represents the start command
 var startCmd = &cobra.Command{
 	Use:   "start",
@@ -56,13 +74,33 @@ func startApp(cfg *config.Config) error {
 		return fmt.Errorf("failed to create slack repository: %w", err)
 	}
 
-	agentRepo := infrastructure.NewAgentRepository(
-		cfg.AI.DefaultSystemPrompt,
-		cfg.AI.AgentScriptPath,
-		strings.Fields(cfg.AI.ClaudeExtraArgs),
-		strings.Split(cfg.AI.DisallowedTools, ","),
-	)
-	agentRepo.SetDebug(cfg.App.Debug)
+	profiles := usecase.NewProfileResolver(buildProfiles(cfg), slackRepo)
+	authorizer := usecase.NewAuthorizer(accessPolicy(cfg.Access), slackRepo)
+	agentRepo, err := infrastructure.NewAgentBackend(cfg.AI.Backend, infrastructure.AgentBackendConfig{
+		SlackRepo:       slackRepo,
+		Profiles:        profiles,
+		Authorizer:      authorizer,
+		SystemPrompt:    cfg.AI.DefaultSystemPrompt,
+		AgentScriptPath: cfg.AI.AgentScriptPath,
+		ClaudeExtraArgs: strings.Fields(cfg.AI.ClaudeExtraArgs),
+		DisallowedTools: strings.Split(cfg.AI.DisallowedTools, ","),
+		OpenAIAPIKey:    cfg.AI.OpenAIAPIKey,
+		OpenAIBaseURL:   cfg.AI.OpenAIBaseURL,
+		OpenAIModel:     cfg.AI.OpenAIModel,
+		UpdateInterval:  cfg.App.UpdateInterval,
+		KillGracePeriod: cfg.AI.KillGracePeriod,
+		Debug:           cfg.App.Debug,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create agent backend: %w", err)
+	}
+
+	// Kill agents that a previous instance left running when it crashed
+	if reaped, err := infrastructure.ReapStaleProcesses(infrastructure.ProcessesDir); err != nil {
+		log.Printf("Failed to look for stale agent processes: %v", err)
+	} else if reaped > 0 {
+		log.Printf("🧹 Killed %d stale agent process groups", reaped)
+	}
 
 	// Get bot user ID
 	botUserID, err := slackRepo.GetBotUserID(context.Background())
@@ -72,43 +110,173 @@ func startApp(cfg *config.Config) error {
 
 	bot := domain.NewBot(botUserID)
 
+	sessionRepo, err := openSessionRepository(cfg)
+	if err != nil {
+		return err
+	}
+
+	cluster, err := newCluster(cfg)
+	if err != nil {
+		return err
+	}
+
+	// Runs that were interrupted by the previous process are not running anymore
+	if recovered, err := usecase.RecoverSessions(context.Background(), sessionRepo, cluster); err != nil {
+		log.Printf("Failed to recover interrupted sessions: %v", err)
+	} else if recovered > 0 {
+		log.Printf("🩹 Marked %d sessions interrupted by the previous run as failed", recovered)
+	}
+
+	workspaces := newSessionWorkspace(sessionRepo, profiles)
+
+	// Evict old sessions in the background
+	if cfg.App.SessionGCInterval > 0 {
+		go newSessionJanitor(cfg, sessionRepo, workspaces, cluster).Run(context.Background(), cfg.App.SessionGCInterval)
+	}
+
+	rateLimiter, err := newRateLimiter(cfg)
+	if err != nil {
+		return err
+	}
+
+	feedbackRepo, err := openFeedbackRepository(cfg)
+	if err != nil {
+		return err
+	}
+
 	// Create use case
-	messageHandler := usecase.NewMessageHandler(slackRepo, agentRepo, bot)
+	agentPool := usecase.NewAgentPool(cfg.App.MaxConcurrent, cfg.App.MaxQueued)
+	messageHandler := usecase.NewMessageHandler(slackRepo, agentRepo, bot,
+		usecase.WithCancelReaction(cfg.App.CancelReaction),
+		usecase.WithMergeQueuedMessages(cfg.App.MergeQueued),
+		usecase.WithAgentPool(agentPool),
+		usecase.WithSessionRepository(sessionRepo),
+		usecase.WithProfiles(profiles),
+		usecase.WithAuthorizer(authorizer),
+		usecase.WithRateLimiter(rateLimiter),
+		usecase.WithAttachments(workspaces, domain.AttachmentPolicy{
+			MaxBytes:     cfg.App.AttachmentMaxMB * 1024 * 1024,
+			AllowedTypes: cfg.App.AttachmentTypes,
+		}),
+		usecase.WithArtifactUploads(workspaces, domain.ArtifactPolicy{
+			Patterns: cfg.App.UploadPatterns,
+			MaxBytes: cfg.App.UploadMaxMB * 1024 * 1024,
+			MaxFiles: cfg.App.UploadMaxFiles,
+		}),
+		usecase.WithThreadHistory(cfg.App.ThreadHistoryChars),
+		usecase.WithThreadFollowing(cfg.App.FollowThreads, cfg.App.FollowIdleTimeout),
+		usecase.WithControls(cfg.App.InteractiveControls),
+		usecase.WithFeedback(feedbackRepo),
+		usecase.WithCluster(cluster),
+	)
+	commandHandler := usecase.NewCommandHandler(slackRepo, messageHandler, bot,
+		usecase.WithCommandAgentPool(agentPool),
+		usecase.WithCommandSessions(sessionRepo, workspaces),
+		usecase.WithCommandAgentRepository(agentRepo),
+		usecase.WithCommandAuthorizer(authorizer, profiles),
+		usecase.WithCommandCluster(cluster),
+	)
+	deduplicator, err := newDeduplicator(cfg)
+	if err != nil {
+		return err
+	}
+	eventDispatcher := dispatcher.New(messageHandler, commandHandler, deduplicator)
+
+	// Register readiness checks and metrics shared by both modes
+	healthServer := health.NewServer()
+	healthServer.AddReadinessCheck(health.Cached(health.NewCheck("slack_auth", slackRepo.CheckAuth), time.Minute))
+	if cfg.AI.Backend == "" || cfg.AI.Backend == infrastructure.BackendClaudeCLI {
+		healthServer.AddReadinessCheck(health.BinaryCheck("mise", "claude"))
+	}
+	healthServer.Metrics().AddGauge("slack_agent_active_agents", "Agents currently running.", func() float64 {
+		return float64(agentPool.Active())
+	})
+	healthServer.Metrics().AddGauge("slack_agent_queue_depth", "Agent runs waiting for a free slot.", func() float64 {
+		return float64(agentPool.QueueDepth())
+	})
+
+	// Stop on Ctrl-C and on SIGTERM, e.g. from Kubernetes
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cluster != nil {
+		go cluster.Run(ctx, messageHandler, clusterPollInterval)
+	}
+
+	// Take the replica out of service while the running agents are drained
+	healthServer.AddReadinessCheck(health.NewCheck("shutdown", func(context.Context) error {
+		if ctx.Err() != nil {
+			return errors.New("shutting down")
+		}
+		return nil
+	}))
+
+	// Once no more events are received, let the running agents finish
+	drain := func() {
+		stop() // a second signal exits right away
+		eventDispatcher.Close()
+		log.Printf("🛑 Shutting down; waiting up to %s for running agents...", cfg.App.ShutdownDrainTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.App.ShutdownDrainTimeout+cfg.AI.KillGracePeriod+shutdownNoticeTimeout)
+		defer cancel()
+		if err := messageHandler.Shutdown(ctx, cfg.App.ShutdownDrainTimeout); err != nil {
+			log.Printf("Agents still running at exit: %v", err)
+		}
+	}
 
 	// Determine mode and start
 	if cfg.Slack.AppToken != "" {
 		log.Println("🔌 Starting in Socket Mode...")
-		return startSocketMode(slackRepo, messageHandler)
+		return startSocketMode(ctx, slackRepo, eventDispatcher, healthServer, cfg.App.Port, drain)
 	}
 
 	log.Println("🌐 Starting in Web API Mode...")
-	return startWebAPIMode(slackRepo, messageHandler, cfg.App.Port)
+	return startWebAPIMode(ctx, eventDispatcher, healthServer, cfg.Slack.SigningSecret, cfg.App.Port, drain)
 }
 
-func startSocketMode(slackRepo *infrastructure.SlackRepositoryImpl, handler usecase.MessageHandler) error {
+// startSocketMode receives events over Socket Mode until ctx ends, then calls drain
+func startSocketMode(ctx context.Context, slackRepo *infrastructure.SlackRepositoryImpl, eventDispatcher *dispatcher.Dispatcher, healthServer *health.Server, port int, drain func()) error {
 	socketClient := slackRepo.GetSocketClient()
 	if socketClient == nil {
 		return fmt.Errorf("socket client not initialized")
 	}
 
-	// Create a map to track processed messages (deduplication)
-	processedMessages := make(map[string]time.Time)
-	// Cleanup old entries periodically
+	// Track connection state and event loop progress for the probes
+	connection := health.NewConnectionState("socket_mode")
+	heartbeat := health.NewHeartbeat("event_loop", eventLoopStallTimeout)
+	healthServer.AddReadinessCheck(connection)
+	healthServer.AddLivenessCheck(heartbeat)
+
+	// Serve the probes; Socket Mode has no other HTTP endpoints
+	mux := http.NewServeMux()
+	healthServer.Register(mux)
+	statusServer := newHTTPServer(port, mux)
 	go func() {
-		ticker := time.NewTicker(5 * time.Minute)
-		defer ticker.Stop()
-		for range ticker.C {
-			now := time.Now()
-			for key, timestamp := range processedMessages {
-				if now.Sub(timestamp) > 10*time.Minute {
-					delete(processedMessages, key)
-				}
-			}
+		log.Printf("🩺 Status server started on port %d", port)
+		if err := statusServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Status server error: %v", err)
 		}
 	}()
 
 	go func() {
-		for evt := range socketClient.Events {
+		// The ticker keeps the heartbeat going while no events arrive,
+		// so only a loop that is stuck handling an event is reported as stalled
+		ticker := time.NewTicker(eventLoopStallTimeout / 4)
+		defer ticker.Stop()
+
+		for {
+			heartbeat.Beat()
+
+			var evt socketmode.Event
+			select {
+			case e, ok := <-socketClient.Events:
+				if !ok {
+					return
+				}
+				evt = e
+			case <-ticker.C:
+				continue
+			}
+
 			switch evt.Type {
 			case socketmode.EventTypeEventsAPI:
 				eventsAPIEvent, ok := evt.Data.(slackevents.EventsAPIEvent)
@@ -121,42 +289,53 @@ func startSocketMode(slackRepo *infrastructure.SlackRepositoryImpl, handler usec
 				// Acknowledge the event immediately to prevent retries
 				socketClient.Ack(*evt.Request)
 
-				// Handle message events asynchronously
-				userID, channelID, text, threadTS, ok := infrastructure.ExtractMessageFromEvent(eventsAPIEvent)
-				if ok {
-					// Create a unique message key for deduplication
-					messageKey := fmt.Sprintf("%s:%s:%s:%s", userID, channelID, threadTS, text)
-
-					// Check if we've already processed this message recently
-					if lastProcessed, exists := processedMessages[messageKey]; exists {
-						if time.Since(lastProcessed) < 30*time.Second {
-							log.Printf("Skipping duplicate message (processed %v ago): %s", time.Since(lastProcessed), messageKey)
-							continue
-						}
-					}
-
-					// Mark message as processed
-					processedMessages[messageKey] = time.Now()
-
-					msg := domain.NewMessage(
-						"", // ID not available in events
-						userID,
-						channelID,
-						text,
-						threadTS,
-						time.Now(),
-					)
-
-					// Process message in a goroutine to avoid blocking
-					go func() {
-						if err := handler.HandleMessage(context.Background(), msg); err != nil {
-							log.Printf("Error handling message: %v", err)
-						}
-					}()
+				// Duplicates are dropped by the dispatcher
+				eventDispatcher.DispatchEventsAPI(eventsAPIEvent, dedup.Retry{Num: evt.Request.RetryAttempt, Reason: evt.Request.RetryReason})
+
+			case socketmode.EventTypeSlashCommand:
+				command, ok := evt.Data.(slack.SlashCommand)
+				if !ok {
+					log.Printf("Ignored %+v\n", evt)
+					socketClient.Ack(*evt.Request)
+					continue
+				}
+
+				// Acknowledge without a body; replies are posted by the command handler
+				socketClient.Ack(*evt.Request)
+				eventDispatcher.DispatchSlashCommand(command)
+
+			case socketmode.EventTypeInteractive:
+				callback, ok := evt.Data.(slack.InteractionCallback)
+				if !ok {
+					log.Printf("Ignored %+v\n", evt)
+					socketClient.Ack(*evt.Request)
+					continue
 				}
 
+				socketClient.Ack(*evt.Request)
+				eventDispatcher.DispatchInteraction(callback)
+
+			case socketmode.EventTypeConnecting:
+				log.Println("Connecting to Slack with Socket Mode...")
+
+			case socketmode.EventTypeConnected:
+				log.Println("Connected to Slack with Socket Mode")
+				connection.SetConnected()
+
 			case socketmode.EventTypeConnectionError:
 				log.Println("Connection failed. Retrying later...")
+				connection.SetDisconnected("connection error")
+
+			case socketmode.EventTypeInvalidAuth:
+				log.Println("Socket Mode authentication failed")
+				connection.SetDisconnected("invalid auth")
+
+			case socketmode.EventTypeDisconnect:
+				log.Println("Disconnected by Slack. Reconnecting...")
+				connection.SetDisconnected("disconnected by server")
+
+			case socketmode.EventTypeHello:
+				// Sent by Slack after every (re)connection; nothing to do
 
 			default:
 				log.Printf("Unexpected event type received: %s\n", evt.Type)
@@ -164,36 +343,203 @@ func startSocketMode(slackRepo *infrastructure.SlackRepositoryImpl, handler usec
 		}
 	}()
 
-	// Handle graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// The probes keep being served while the running agents are drained
+	defer shutdownHTTPServer(statusServer)
+	defer drain()
 
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
+	if err := socketClient.RunContext(ctx); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("socket mode error: %w", err)
+	}
+
+	return nil
+}
+
+// startWebAPIMode serves the request URLs until ctx ends, then calls drain
+func startWebAPIMode(ctx context.Context, eventDispatcher *dispatcher.Dispatcher, healthServer *health.Server, signingSecret string, port int, drain func()) error {
+	mux := http.NewServeMux()
+	mux.Handle("/slack/events", unlessShuttingDown(ctx, webapi.NewEventsHandler(signingSecret, eventDispatcher)))
+	mux.Handle("/slack/commands", unlessShuttingDown(ctx, webapi.NewCommandsHandler(signingSecret, eventDispatcher)))
+	mux.Handle("/slack/interactivity", unlessShuttingDown(ctx, webapi.NewInteractivityHandler(signingSecret, eventDispatcher)))
+	healthServer.Register(mux)
 
+	server := newHTTPServer(port, mux)
+	serveErr := make(chan error, 1)
 	go func() {
-		<-sigChan
-		log.Println("🛑 Shutting down...")
-		cancel()
+		log.Printf("⚡️ Web API Mode started on port %d", port)
+		serveErr <- server.ListenAndServe()
 	}()
 
-	if err := socketClient.RunContext(ctx); err != nil {
-		return fmt.Errorf("socket mode error: %w", err)
+	// The probes keep being served while the running agents are drained
+	defer shutdownHTTPServer(server)
+	defer drain()
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("http server error: %w", err)
+		}
 	}
 
 	return nil
 }
 
-func startWebAPIMode(slackRepo *infrastructure.SlackRepositoryImpl, handler usecase.MessageHandler, port int) error {
-	// This is a simplified version - in production, you'd want to implement
-	// proper event handling with verification, etc.
-	log.Printf("⚡️ Web API Mode started on port %d", port)
+// unlessShuttingDown rejects Slack requests once ctx ends, so that Slack retries them later,
+// possibly on another replica
+func unlessShuttingDown(ctx context.Context, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ctx.Err() != nil {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
 
-	// Keep the application running
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt)
-	<-sigChan
+// buildProfiles creates the per-channel agent profiles, using the ai section as the defaults
+func buildProfiles(cfg *config.Config) *domain.Profiles {
+	channels := make(map[string]domain.Profile, len(cfg.Channels))
+	for name, channel := range cfg.Channels {
+		followThreads := cfg.App.FollowThreads
+		if channel.FollowThreads != nil {
+			followThreads = *channel.FollowThreads
+		}
+		timeout, idleTimeout := cfg.AI.Timeout, cfg.AI.IdleTimeout
+		if channel.Timeout != nil {
+			timeout = *channel.Timeout
+		}
+		if channel.IdleTimeout != nil {
+			idleTimeout = *channel.IdleTimeout
+		}
+		channels[name] = domain.Profile{
+			SystemPrompt:    channel.SystemPrompt,
+			AllowedTools:    channel.AllowedTools,
+			DisallowedTools: channel.DisallowedTools,
+			ExtraArgs:       channel.ClaudeExtraArgs,
+			Model:           channel.Model,
+			WorkdirTemplate: channel.Workdir,
+			FollowThreads:   followThreads,
+			Timeout:         timeout,
+			IdleTimeout:     idleTimeout,
+			Access:          accessPolicy(channel.Access),
+			ToolRules:       toolRules(channel.ToolRules),
+		}
+	}
 
-	log.Println("🛑 Shutting down...")
-	return nil
+	return domain.NewProfiles(domain.Profile{
+		SystemPrompt:    cfg.AI.DefaultSystemPrompt,
+		DisallowedTools: strings.Split(cfg.AI.DisallowedTools, ","),
+		ExtraArgs:       strings.Fields(cfg.AI.ClaudeExtraArgs),
+		FollowThreads:   cfg.App.FollowThreads,
+		Timeout:         cfg.AI.Timeout,
+		IdleTimeout:     cfg.AI.IdleTimeout,
+	}, channels)
+}
+
+// toolRules converts configured tool rules into domain tool rules
+func toolRules(rules []config.ToolRuleConfig) []domain.ToolRule {
+	var converted []domain.ToolRule
+	for _, rule := range rules {
+		converted = append(converted, domain.ToolRule{
+			Match:           domain.AccessRule{Users: rule.Users, UserGroups: rule.UserGroups, Channels: rule.Channels},
+			AllowedTools:    rule.AllowedTools,
+			DisallowedTools: rule.DisallowedTools,
+		})
+	}
+	return converted
+}
+
+// newDeduplicator creates the event deduplicator, sharing it through app.dedup_dir when set.
+// Replicas in a cluster share it through the cluster directory unless app.dedup_dir says otherwise.
+func newDeduplicator(cfg *config.Config) (*dedup.Deduplicator, error) {
+	dir := cfg.App.DedupDir
+	if dir == "" && cfg.Cluster.Dir != "" {
+		dir = filepath.Join(cfg.Cluster.Dir, "events")
+	}
+	if dir == "" {
+		return dedup.New(dedup.NewMemoryStore(cfg.App.DedupMaxEntries), cfg.App.DedupTTL), nil
+	}
+	store, err := dedup.NewFileStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	return dedup.New(store, cfg.App.DedupTTL), nil
+}
+
+// newCluster coordinates this replica with the others sharing cluster.dir, or returns nil when it is not set
+func newCluster(cfg *config.Config) (*usecase.Cluster, error) {
+	if cfg.Cluster.Dir == "" {
+		return nil, nil
+	}
+	replica := cfg.Cluster.ReplicaID
+	if replica == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine the replica ID: %w", err)
+		}
+		replica = hostname
+	}
+	leases, err := infrastructure.NewFileLeaseRepository(filepath.Join(cfg.Cluster.Dir, "leases"))
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🤝 Coordinating with other replicas in %s as %s", cfg.Cluster.Dir, replica)
+	return usecase.NewCluster(replica, leases,
+		infrastructure.NewFileHandoffRepository(filepath.Join(cfg.Cluster.Dir, "handoffs")),
+		cfg.Cluster.ThreadLeaseTTL), nil
+}
+
+// accessPolicy converts configured access lists into a domain access policy
+func accessPolicy(access config.AccessConfig) domain.AccessPolicy {
+	return domain.AccessPolicy{
+		Allow: domain.AccessRule{Users: access.Allow.Users, UserGroups: access.Allow.UserGroups, Channels: access.Allow.Channels},
+		Deny:  domain.AccessRule{Users: access.Deny.Users, UserGroups: access.Deny.UserGroups, Channels: access.Deny.Channels},
+	}
+}
+
+// newRateLimiter creates the rate limiter, or returns nil when no limits are configured
+func newRateLimiter(cfg *config.Config) (*usecase.RateLimiter, error) {
+	var limits usecase.RateLimits
+	for _, limit := range []struct {
+		name  string
+		value string
+		dest  *domain.RateLimit
+	}{
+		{name: "user", value: cfg.RateLimits.User, dest: &limits.User},
+		{name: "channel", value: cfg.RateLimits.Channel, dest: &limits.Channel},
+		{name: "workspace", value: cfg.RateLimits.Workspace, dest: &limits.Workspace},
+	} {
+		parsed, err := domain.ParseRateLimit(limit.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s rate limit: %w", limit.name, err)
+		}
+		*limit.dest = parsed
+	}
+	if limits == (usecase.RateLimits{}) {
+		return nil, nil
+	}
+
+	repo, err := infrastructure.NewFileRateLimitRepository(filepath.Join(cfg.App.StateDir, "ratelimits.json"))
+	if err != nil {
+		return nil, err
+	}
+	return usecase.NewRateLimiter(repo, limits), nil
+}
+
+// newHTTPServer creates an HTTP server listening on the given port
+func newHTTPServer(port int, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+}
+
+// shutdownHTTPServer stops the server, waiting briefly for in-flight requests
+func shutdownHTTPServer(server *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("HTTP server shutdown error: %v", err)
+	}
 }
@@ -2,9 +2,13 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/takutakahashi/slack-agent/pkg/config"
@@ -265,3 +269,43 @@ func TestExtractArgsAndTools(t *testing.T) {
 		})
 	}
 }
+
+func TestUnlessShuttingDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := unlessShuttingDown(ctx, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/slack/events", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 while running, got %d", rec.Code)
+	}
+
+	// Slack retries requests that are rejected while the bot drains
+	cancel()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/slack/events", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 while shutting down, got %d", rec.Code)
+	}
+}
+
+func TestBuildProfiles_Timeouts(t *testing.T) {
+	disabled := time.Duration(0)
+	cfg := &config.Config{
+		AI: config.AIConfig{Timeout: time.Hour, IdleTimeout: 5 * time.Minute},
+		Channels: map[string]config.ChannelConfig{
+			"#inherited": {},
+			"#unlimited": {Timeout: &disabled, IdleTimeout: &disabled},
+		},
+	}
+	profiles := buildProfiles(cfg)
+
+	if profile := profiles.Lookup("", "inherited"); profile.Timeout != time.Hour || profile.IdleTimeout != 5*time.Minute {
+		t.Errorf("expected the ai timeouts, got %s and %s", profile.Timeout, profile.IdleTimeout)
+	}
+	if profile := profiles.Lookup("", "unlimited"); profile.Timeout != 0 || profile.IdleTimeout != 0 {
+		t.Errorf("expected the timeouts to be disabled, got %s and %s", profile.Timeout, profile.IdleTimeout)
+	}
+}
@@ -0,0 +1,93 @@
+// Package dedup drops Slack events that are delivered more than once, such as
+// retries of events that were not acknowledged in time.
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+// DefaultTTL is how long events are remembered; Slack gives up retrying well before that
+const DefaultTTL = 10 * time.Minute
+
+// Store remembers keys for a while
+type Store interface {
+	// Add records the key and reports whether it was new, i.e. not recorded within the TTL
+	Add(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// Retry describes a redelivery of an event by Slack
+type Retry struct {
+	Num    int
+	Reason string
+}
+
+// RetryFromHeader reads the X-Slack-Retry-Num and X-Slack-Retry-Reason headers of an Events API request
+func RetryFromHeader(header http.Header) Retry {
+	num, _ := strconv.Atoi(header.Get("X-Slack-Retry-Num"))
+	return Retry{Num: num, Reason: header.Get("X-Slack-Retry-Reason")}
+}
+
+// String describes the delivery for logs
+func (r Retry) String() string {
+	if r.Num == 0 {
+		return "first delivery"
+	}
+	return fmt.Sprintf("retry #%d, %s", r.Num, r.Reason)
+}
+
+// Deduplicator recognizes events that have been seen before
+type Deduplicator struct {
+	store Store
+	ttl   time.Duration
+}
+
+// New creates a new Deduplicator instance; a ttl of zero or less uses DefaultTTL
+func New(store Store, ttl time.Duration) *Deduplicator {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Deduplicator{store: store, ttl: ttl}
+}
+
+// Duplicate reports whether any of the keys has been seen before and records them all.
+// Events are let through when the store fails, since dropping them would lose messages.
+func (d *Deduplicator) Duplicate(ctx context.Context, keys ...string) bool {
+	duplicate := false
+	for _, key := range keys {
+		added, err := d.store.Add(ctx, key, d.ttl)
+		if err != nil {
+			log.Printf("Failed to record event %s: %v", key, err)
+			continue
+		}
+		if !added {
+			duplicate = true
+		}
+	}
+	return duplicate
+}
+
+// EventKeys returns the keys that identify an Events API event: its event ID and, for messages,
+// the channel and timestamp of the message, since a mention arrives both as an app_mention and
+// a message event with different event IDs. The client_msg_id is not used for this because
+// app_mention events do not carry it.
+func EventKeys(event slackevents.EventsAPIEvent) []string {
+	var keys []string
+	if callback, ok := event.Data.(*slackevents.EventsAPICallbackEvent); ok && callback.EventID != "" {
+		keys = append(keys, "event:"+callback.EventID)
+	}
+
+	switch ev := event.InnerEvent.Data.(type) {
+	case *slackevents.MessageEvent:
+		keys = append(keys, "message:"+ev.Channel+":"+ev.TimeStamp)
+	case *slackevents.AppMentionEvent:
+		keys = append(keys, "message:"+ev.Channel+":"+ev.TimeStamp)
+	}
+	return keys
+}
@@ -0,0 +1,74 @@
+package dedup_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+	"github.com/takutakahashi/slack-agent/internal/interface/dedup"
+)
+
+func parseEvent(t *testing.T, eventID, inner string) slackevents.EventsAPIEvent {
+	t.Helper()
+	body := `{"type":"event_callback","team_id":"T1","event_id":"` + eventID + `","event":` + inner + `}`
+	event, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		t.Fatalf("failed to parse event: %v", err)
+	}
+	return event
+}
+
+func TestDeduplicator_Events(t *testing.T) {
+	mention := `{"type":"app_mention","user":"U1","channel":"C1","text":"<@UBOT> hi","ts":"1.1"}`
+	message := `{"type":"message","user":"U1","channel":"C1","text":"<@UBOT> hi","ts":"1.1","client_msg_id":"m1"}`
+	reaction := `{"type":"reaction_added","user":"U1","reaction":"eyes","item":{"type":"message","channel":"C1","ts":"1.1"}}`
+
+	tests := []struct {
+		name       string
+		events     []slackevents.EventsAPIEvent
+		duplicates []bool
+	}{
+		{
+			name:       "retried event",
+			events:     []slackevents.EventsAPIEvent{parseEvent(t, "Ev1", reaction), parseEvent(t, "Ev1", reaction)},
+			duplicates: []bool{false, true},
+		},
+		{
+			name:       "mention delivered as app_mention and message",
+			events:     []slackevents.EventsAPIEvent{parseEvent(t, "Ev1", mention), parseEvent(t, "Ev2", message)},
+			duplicates: []bool{false, true},
+		},
+		{
+			name:       "different events",
+			events:     []slackevents.EventsAPIEvent{parseEvent(t, "Ev1", reaction), parseEvent(t, "Ev2", reaction)},
+			duplicates: []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deduplicator := dedup.New(dedup.NewMemoryStore(0), time.Minute)
+			for i, event := range tt.events {
+				if got := deduplicator.Duplicate(context.Background(), dedup.EventKeys(event)...); got != tt.duplicates[i] {
+					t.Errorf("event %d: expected duplicate=%v, got %v", i, tt.duplicates[i], got)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryFromHeader(t *testing.T) {
+	header := http.Header{}
+	if retry := dedup.RetryFromHeader(header); retry.Num != 0 || retry.String() != "first delivery" {
+		t.Errorf("expected a first delivery, got %+v", retry)
+	}
+
+	header.Set("X-Slack-Retry-Num", "2")
+	header.Set("X-Slack-Retry-Reason", "http_timeout")
+	if retry := dedup.RetryFromHeader(header); retry.String() != "retry #2, http_timeout" {
+		t.Errorf("unexpected retry %q", retry)
+	}
+}
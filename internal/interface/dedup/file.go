@@ -0,0 +1,134 @@
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lockSuffix is appended to the name of a record to get the name of its lock file
+const lockSuffix = ".lock"
+
+// FileStore records keys as files in a directory. Creating the files is atomic, so replicas
+// sharing the directory on a volume see each other's events.
+type FileStore struct {
+	dir       string
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewFileStore creates a new FileStore instance, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create dedup directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Add records the key and reports whether it was new
+func (s *FileStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.sweep(ttl)
+
+	sum := sha256.Sum256([]byte(key))
+	path := filepath.Join(s.dir, hex.EncodeToString(sum[:16]))
+	added, err := createExclusive(path)
+	if added || err != nil {
+		return added, err
+	}
+
+	// The key was recorded before; it only counts while it has not expired. Replicas only replace
+	// an expired record while holding its lock, so that the removal cannot delete a record another
+	// replica has just created in its place.
+	unlock, locked, err := lock(path)
+	if err != nil || !locked {
+		// Another replica is recording the key right now
+		return false, err
+	}
+	defer unlock()
+	removed, err := removeExpired(path, ttl)
+	if err != nil || !removed {
+		return false, err
+	}
+	return createExclusive(path)
+}
+
+// sweep removes expired files, at most once per ttl
+func (s *FileStore) sweep(ttl time.Duration) {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < ttl {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(s.dir, entry.Name())
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < ttl {
+			continue
+		}
+		// Locks are only held for a moment, so old ones were left behind by a replica that crashed
+		if strings.HasSuffix(path, lockSuffix) {
+			_ = os.Remove(path)
+			continue
+		}
+		if unlock, locked, err := lock(path); err == nil && locked {
+			_, _ = removeExpired(path, ttl)
+			unlock()
+		}
+	}
+}
+
+// lock creates the lock file of a record and reports false if another replica holds it.
+// The returned function releases the lock.
+func lock(path string) (func(), bool, error) {
+	locked, err := createExclusive(path + lockSuffix)
+	if err != nil || !locked {
+		return nil, false, err
+	}
+	return func() { _ = os.Remove(path + lockSuffix) }, true, nil
+}
+
+// removeExpired removes the record if it is older than ttl and reports whether it is gone.
+// The caller must hold the lock of the record.
+func removeExpired(path string, ttl time.Duration) (bool, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", path, err)
+	}
+	if time.Since(info.ModTime()) < ttl {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to remove expired %s: %w", path, err)
+	}
+	return true, nil
+}
+
+// createExclusive creates the file and reports false if it already exists
+func createExclusive(path string) (bool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	return true, f.Close()
+}
@@ -0,0 +1,80 @@
+package dedup
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries bounds the number of keys kept by a MemoryStore
+const DefaultMaxEntries = 10000
+
+// MemoryStore keeps keys in memory until they expire, dropping the oldest ones beyond its capacity
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	// order holds the entries from newest to oldest
+	order *list.List
+}
+
+// memoryEntry is a key and the time it expires
+type memoryEntry struct {
+	key     string
+	expires time.Time
+}
+
+// NewMemoryStore creates a new MemoryStore instance; a maxEntries of zero or less uses DefaultMaxEntries
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Add records the key and reports whether it was new
+func (s *MemoryStore) Add(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.evictExpired(now)
+	if el, ok := s.entries[key]; ok {
+		if now.Before(el.Value.(*memoryEntry).expires) {
+			return false, nil
+		}
+		// Entries with a shorter TTL may expire before older ones
+		s.remove(el)
+	}
+
+	s.entries[key] = s.order.PushFront(&memoryEntry{key: key, expires: now.Add(ttl)})
+	for s.order.Len() > s.maxEntries {
+		s.remove(s.order.Back())
+	}
+	return true, nil
+}
+
+// Len returns the number of keys kept
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// evictExpired drops the oldest entries while they have expired; s.mu must be held
+func (s *MemoryStore) evictExpired(now time.Time) {
+	for el := s.order.Back(); el != nil && !now.Before(el.Value.(*memoryEntry).expires); el = s.order.Back() {
+		s.remove(el)
+	}
+}
+
+// remove drops an entry; s.mu must be held
+func (s *MemoryStore) remove(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
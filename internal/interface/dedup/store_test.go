@@ -0,0 +1,136 @@
+package dedup_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/interface/dedup"
+)
+
+func TestStores(t *testing.T) {
+	newFileStore := func(t *testing.T, dir string) dedup.Store {
+		store, err := dedup.NewFileStore(dir)
+		if err != nil {
+			t.Fatalf("failed to create store: %v", err)
+		}
+		return store
+	}
+
+	// Each case returns the stores of two replicas
+	tests := []struct {
+		name     string
+		replicas func(t *testing.T) (dedup.Store, dedup.Store)
+	}{
+		{name: "memory", replicas: func(t *testing.T) (dedup.Store, dedup.Store) {
+			store := dedup.NewMemoryStore(0)
+			return store, store
+		}},
+		{name: "file", replicas: func(t *testing.T) (dedup.Store, dedup.Store) {
+			store := newFileStore(t, t.TempDir())
+			return store, store
+		}},
+		{name: "file shared by replicas", replicas: func(t *testing.T) (dedup.Store, dedup.Store) {
+			dir := t.TempDir()
+			return newFileStore(t, dir), newFileStore(t, dir)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := tt.replicas(t)
+			ctx := context.Background()
+
+			if added, err := first.Add(ctx, "event:Ev1", time.Minute); err != nil || !added {
+				t.Fatalf("expected a new key, got %v, %v", added, err)
+			}
+			if added, err := second.Add(ctx, "event:Ev1", time.Minute); err != nil || added {
+				t.Errorf("expected a known key, got %v, %v", added, err)
+			}
+
+			// Keys are forgotten once they expire
+			if added, _ := first.Add(ctx, "event:Ev2", 20*time.Millisecond); !added {
+				t.Fatal("expected a new key")
+			}
+			time.Sleep(50 * time.Millisecond)
+			if added, err := second.Add(ctx, "event:Ev2", 20*time.Millisecond); err != nil || !added {
+				t.Errorf("expected the expired key to count as new, got %v, %v", added, err)
+			}
+		})
+	}
+}
+
+func TestFileStore_ExpiredKeyRace(t *testing.T) {
+	ctx := context.Background()
+	for round := 0; round < 50; round++ {
+		dir := t.TempDir()
+		// Each replica sweeps on its first key, so that the sweep doesn't remove the record below
+		var replicas []*dedup.FileStore
+		for i := 0; i < 8; i++ {
+			replica, err := dedup.NewFileStore(dir)
+			if err != nil {
+				t.Fatalf("failed to create store: %v", err)
+			}
+			if _, err := replica.Add(ctx, fmt.Sprintf("event:Warmup%d", i), time.Minute); err != nil {
+				t.Fatalf("failed to add: %v", err)
+			}
+			replicas = append(replicas, replica)
+		}
+		if added, err := replicas[0].Add(ctx, "event:Ev1", time.Minute); err != nil || !added {
+			t.Fatalf("expected a new key, got %v, %v", added, err)
+		}
+		// Let the records expire
+		files, _ := filepath.Glob(filepath.Join(dir, "*"))
+		for _, file := range files {
+			old := time.Now().Add(-time.Hour)
+			if err := os.Chtimes(file, old, old); err != nil {
+				t.Fatalf("failed to age %s: %v", file, err)
+			}
+		}
+
+		// Replicas receiving the event again at the same time must record it only once
+		var wg sync.WaitGroup
+		var added atomic.Int32
+		for _, replica := range replicas {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ok, err := replica.Add(ctx, "event:Ev1", time.Minute)
+				if err != nil {
+					t.Errorf("failed to add: %v", err)
+				}
+				if ok {
+					added.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := added.Load(); n != 1 {
+			t.Fatalf("round %d: expected one replica to record the key, got %d", round, n)
+		}
+	}
+}
+
+func TestMemoryStore_Capacity(t *testing.T) {
+	store := dedup.NewMemoryStore(2)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		_, _ = store.Add(ctx, key, time.Minute)
+	}
+
+	if store.Len() != 2 {
+		t.Errorf("expected 2 keys, got %d", store.Len())
+	}
+	// The oldest key was dropped
+	if added, _ := store.Add(ctx, "a", time.Minute); !added {
+		t.Error("expected the oldest key to have been evicted")
+	}
+	if added, _ := store.Add(ctx, "c", time.Minute); added {
+		t.Error("expected the newest key to be kept")
+	}
+}
@@ -0,0 +1,150 @@
+package dispatcher
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/infrastructure"
+	"github.com/takutakahashi/slack-agent/internal/interface/dedup"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+// Dispatcher routes Slack events to the use case layer.
+// It is shared by Socket Mode and Web API mode so both transports behave the same.
+type Dispatcher struct {
+	handler  usecase.MessageHandler
+	commands usecase.CommandHandler
+	// dedup drops events delivered more than once; nil disables it
+	dedup  *dedup.Deduplicator
+	closed atomic.Bool
+}
+
+// New creates a new Dispatcher instance
+func New(handler usecase.MessageHandler, commands usecase.CommandHandler, deduplicator *dedup.Deduplicator) *Dispatcher {
+	return &Dispatcher{
+		handler:  handler,
+		commands: commands,
+		dedup:    deduplicator,
+	}
+}
+
+// Close stops dispatching events, e.g. while the bot shuts down
+func (d *Dispatcher) Close() {
+	d.closed.Store(true)
+}
+
+// accepting reports whether events are still dispatched, logging the event dropped otherwise
+func (d *Dispatcher) accepting(kind string) bool {
+	if d.closed.Load() {
+		log.Printf("Shutting down; ignoring %s", kind)
+		return false
+	}
+	return true
+}
+
+// MessageFromEvent converts an Events API payload into a domain message
+func MessageFromEvent(event slackevents.EventsAPIEvent) (*domain.Message, bool) {
+	userID, channelID, text, threadTS, ok := infrastructure.ExtractMessageFromEvent(event)
+	if !ok {
+		return nil, false
+	}
+
+	msg := domain.NewMessage(
+		infrastructure.ExtractMessageTSFromEvent(event),
+		userID,
+		channelID,
+		text,
+		threadTS,
+		time.Now(),
+	)
+	msg.TeamID = event.TeamID
+	msg.Files = infrastructure.ExtractFilesFromEvent(event)
+	return msg, true
+}
+
+// DispatchEventsAPI handles an Events API payload, skipping events that have already been handled
+func (d *Dispatcher) DispatchEventsAPI(event slackevents.EventsAPIEvent, retry dedup.Retry) {
+	// Events dropped while shutting down are not marked as seen, so that the retries reach another replica
+	if !d.accepting(event.InnerEvent.Type) {
+		return
+	}
+	if d.dedup != nil && d.dedup.Duplicate(context.Background(), dedup.EventKeys(event)...) {
+		log.Printf("Skipping duplicate %s event (%s)", event.InnerEvent.Type, retry)
+		return
+	}
+	if msg, ok := MessageFromEvent(event); ok {
+		d.DispatchMessage(msg)
+		return
+	}
+	if reaction, ok := infrastructure.ExtractReactionFromEvent(event); ok {
+		d.DispatchReaction(reaction)
+	}
+}
+
+// DispatchMessage passes a message to the message handler.
+// The message is processed in a goroutine so that the caller can acknowledge the event immediately.
+func (d *Dispatcher) DispatchMessage(msg *domain.Message) {
+	if !d.accepting("message") {
+		return
+	}
+	go func() {
+		if err := d.handler.HandleMessage(context.Background(), msg); err != nil {
+			log.Printf("Error handling message: %v", err)
+		}
+	}()
+}
+
+// DispatchReaction passes a reaction to the message handler
+func (d *Dispatcher) DispatchReaction(reaction *domain.Reaction) {
+	if !d.accepting("reaction") {
+		return
+	}
+	go func() {
+		if err := d.handler.HandleReaction(context.Background(), reaction); err != nil {
+			log.Printf("Error handling reaction: %v", err)
+		}
+	}()
+}
+
+// DispatchInteraction passes clicks on the bot's controls to the message handler
+func (d *Dispatcher) DispatchInteraction(callback slack.InteractionCallback) {
+	if !d.accepting("interaction") {
+		return
+	}
+	for _, action := range infrastructure.ExtractActionsFromInteraction(callback) {
+		go func() {
+			if err := d.handler.HandleAction(context.Background(), action); err != nil {
+				log.Printf("Error handling action: %v", err)
+			}
+		}()
+	}
+}
+
+// CommandFromSlashCommand converts a slash command payload into a domain command
+func CommandFromSlashCommand(command slack.SlashCommand) *domain.SlashCommand {
+	return &domain.SlashCommand{
+		TeamID:      command.TeamID,
+		ChannelID:   command.ChannelID,
+		UserID:      command.UserID,
+		Command:     command.Command,
+		Text:        command.Text,
+		ResponseURL: command.ResponseURL,
+	}
+}
+
+// DispatchSlashCommand passes a slash command to the command handler
+func (d *Dispatcher) DispatchSlashCommand(command slack.SlashCommand) {
+	if !d.accepting("slash command") {
+		return
+	}
+	go func() {
+		if err := d.commands.HandleCommand(context.Background(), CommandFromSlashCommand(command)); err != nil {
+			log.Printf("Error handling slash command: %v", err)
+		}
+	}()
+}
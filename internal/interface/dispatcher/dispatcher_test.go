@@ -0,0 +1,34 @@
+package dispatcher_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+	"github.com/takutakahashi/slack-agent/internal/interface/dedup"
+	"github.com/takutakahashi/slack-agent/internal/interface/dispatcher"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDispatcher_ClosedDoesNotMarkEventsAsSeen(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	deduplicator := dedup.New(dedup.NewMemoryStore(0), time.Minute)
+	d := dispatcher.New(mocks.NewMockMessageHandler(ctrl), mocks.NewMockCommandHandler(ctrl), deduplicator)
+	d.Close()
+
+	event := slackevents.EventsAPIEvent{
+		TeamID: "T1",
+		InnerEvent: slackevents.EventsAPIInnerEvent{
+			Type: "app_mention",
+			Data: &slackevents.AppMentionEvent{User: "U1", Channel: "C1", Text: "<@UBOT> hello", TimeStamp: "1.1"},
+		},
+	}
+	d.DispatchEventsAPI(event, dedup.Retry{})
+
+	// The retry is handled by a replica that is still running
+	if deduplicator.Duplicate(context.Background(), dedup.EventKeys(event)...) {
+		t.Error("expected an event dropped while shutting down not to be marked as seen")
+	}
+}
@@ -0,0 +1,278 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// checkTimeout bounds how long a single probe request may take
+const checkTimeout = 5 * time.Second
+
+// Checker reports the state of a single component
+type Checker interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// checkFunc adapts a function to the Checker interface
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// NewCheck creates a Checker from a function
+func NewCheck(name string, fn func(ctx context.Context) error) Checker {
+	return &checkFunc{name: name, fn: fn}
+}
+
+// Name returns the name of the check
+func (c *checkFunc) Name() string {
+	return c.name
+}
+
+// Check runs the check
+func (c *checkFunc) Check(ctx context.Context) error {
+	return c.fn(ctx)
+}
+
+// cachedChecker remembers the result of an expensive check for a while
+type cachedChecker struct {
+	checker Checker
+	ttl     time.Duration
+
+	mu      sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// Cached wraps a checker so that it is evaluated at most once per ttl.
+// Use it for checks that call external APIs.
+func Cached(checker Checker, ttl time.Duration) Checker {
+	return &cachedChecker{checker: checker, ttl: ttl}
+}
+
+// Name returns the name of the wrapped check
+func (c *cachedChecker) Name() string {
+	return c.checker.Name()
+}
+
+// Check returns the cached result or runs the wrapped check
+func (c *cachedChecker) Check(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.checked.IsZero() && time.Since(c.checked) < c.ttl {
+		return c.err
+	}
+	c.err = c.checker.Check(ctx)
+	c.checked = time.Now()
+	return c.err
+}
+
+// BinaryCheck reports whether the given executables can be found on PATH
+func BinaryCheck(names ...string) Checker {
+	return NewCheck("binaries", func(ctx context.Context) error {
+		var missing []string
+		for _, name := range names {
+			if _, err := exec.LookPath(name); err != nil {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("not found on PATH: %v", missing)
+		}
+		return nil
+	})
+}
+
+// Heartbeat detects a stalled loop.
+// The loop calls Beat on every iteration; the check fails when no beat arrived within the timeout.
+type Heartbeat struct {
+	name    string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewHeartbeat creates a new Heartbeat instance
+func NewHeartbeat(name string, timeout time.Duration) *Heartbeat {
+	return &Heartbeat{
+		name:    name,
+		timeout: timeout,
+		last:    time.Now(),
+	}
+}
+
+// Beat records that the loop is alive
+func (h *Heartbeat) Beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = time.Now()
+}
+
+// Name returns the name of the check
+func (h *Heartbeat) Name() string {
+	return h.name
+}
+
+// Check fails when the last beat is older than the timeout
+func (h *Heartbeat) Check(ctx context.Context) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if since := time.Since(h.last); since > h.timeout {
+		return fmt.Errorf("no heartbeat for %s", since.Round(time.Second))
+	}
+	return nil
+}
+
+// ConnectionState tracks whether a long-lived connection is established
+type ConnectionState struct {
+	name string
+
+	mu        sync.Mutex
+	connected bool
+	lastErr   string
+}
+
+// NewConnectionState creates a new ConnectionState instance.
+// The connection is reported as down until SetConnected is called.
+func NewConnectionState(name string) *ConnectionState {
+	return &ConnectionState{
+		name:    name,
+		lastErr: "not connected yet",
+	}
+}
+
+// SetConnected marks the connection as established
+func (s *ConnectionState) SetConnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = true
+	s.lastErr = ""
+}
+
+// SetDisconnected marks the connection as down with the given reason
+func (s *ConnectionState) SetDisconnected(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.connected = false
+	s.lastErr = reason
+}
+
+// Name returns the name of the check
+func (s *ConnectionState) Name() string {
+	return s.name
+}
+
+// Check fails while the connection is down
+func (s *ConnectionState) Check(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.connected {
+		return fmt.Errorf("disconnected: %s", s.lastErr)
+	}
+	return nil
+}
+
+// Server serves liveness and readiness endpoints along with metrics
+type Server struct {
+	mu        sync.RWMutex
+	liveness  []Checker
+	readiness []Checker
+	metrics   *Metrics
+}
+
+// NewServer creates a new Server instance
+func NewServer() *Server {
+	return &Server{
+		metrics: NewMetrics(),
+	}
+}
+
+// Metrics returns the metrics served on /metrics
+func (s *Server) Metrics() *Metrics {
+	return s.metrics
+}
+
+// AddLivenessCheck registers a check that must pass for the process to be considered alive
+func (s *Server) AddLivenessCheck(c Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.liveness = append(s.liveness, c)
+}
+
+// AddReadinessCheck registers a check that must pass before the process accepts work
+func (s *Server) AddReadinessCheck(c Checker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readiness = append(s.readiness, c)
+}
+
+// Register mounts the probe endpoints on the given mux.
+// /livez and /readyz run the respective checks, /health runs all of them.
+func (s *Server) Register(mux *http.ServeMux) {
+	mux.Handle("/metrics", s.metrics)
+	mux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, s.checks(true, false))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, s.checks(false, true))
+	})
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		s.serve(w, r, s.checks(true, true))
+	})
+}
+
+// checks returns a snapshot of the registered checks
+func (s *Server) checks(liveness, readiness bool) []Checker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var checks []Checker
+	if liveness {
+		checks = append(checks, s.liveness...)
+	}
+	if readiness {
+		checks = append(checks, s.readiness...)
+	}
+	return checks
+}
+
+// report is the JSON body returned by the probe endpoints
+type report struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// serve runs the checks and writes the report
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, checks []Checker) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	result := report{Status: "ok", Checks: make(map[string]string, len(checks))}
+	for _, c := range checks {
+		if err := c.Check(ctx); err != nil {
+			result.Status = "error"
+			result.Checks[c.Name()] = err.Error()
+			continue
+		}
+		result.Checks[c.Name()] = "ok"
+	}
+
+	status := http.StatusOK
+	if result.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,126 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/interface/health"
+)
+
+func probe(t *testing.T, mux *http.ServeMux, path string) (int, map[string]string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var body struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return rec.Code, body.Checks
+}
+
+func TestServer(t *testing.T) {
+	server := health.NewServer()
+	connection := health.NewConnectionState("socket_mode")
+	server.AddReadinessCheck(connection)
+	server.AddLivenessCheck(health.NewCheck("loop", func(ctx context.Context) error { return nil }))
+
+	mux := http.NewServeMux()
+	server.Register(mux)
+
+	code, checks := probe(t, mux, "/livez")
+	if code != http.StatusOK {
+		t.Errorf("expected /livez to be 200, got %d", code)
+	}
+	if _, ok := checks["socket_mode"]; ok {
+		t.Error("expected /livez not to run readiness checks")
+	}
+
+	code, checks = probe(t, mux, "/readyz")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected /readyz to be 503 before connecting, got %d", code)
+	}
+	if checks["socket_mode"] == "ok" {
+		t.Error("expected socket_mode check to fail before connecting")
+	}
+
+	connection.SetConnected()
+	code, _ = probe(t, mux, "/readyz")
+	if code != http.StatusOK {
+		t.Errorf("expected /readyz to be 200 after connecting, got %d", code)
+	}
+
+	connection.SetDisconnected("connection error")
+	code, checks = probe(t, mux, "/health")
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected /health to be 503 after disconnecting, got %d", code)
+	}
+	if checks["loop"] != "ok" {
+		t.Errorf("expected /health to include liveness checks, got %v", checks)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	heartbeat := health.NewHeartbeat("loop", 50*time.Millisecond)
+	if err := heartbeat.Check(context.Background()); err != nil {
+		t.Errorf("expected fresh heartbeat to pass, got %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := heartbeat.Check(context.Background()); err == nil {
+		t.Error("expected stalled heartbeat to fail")
+	}
+
+	heartbeat.Beat()
+	if err := heartbeat.Check(context.Background()); err != nil {
+		t.Errorf("expected heartbeat to recover after Beat, got %v", err)
+	}
+}
+
+func TestCached(t *testing.T) {
+	calls := 0
+	checker := health.Cached(health.NewCheck("auth", func(ctx context.Context) error {
+		calls++
+		return errors.New("failed")
+	}), time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if err := checker.Check(context.Background()); err == nil {
+			t.Error("expected cached error to be returned")
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected wrapped check to run once, ran %d times", calls)
+	}
+}
+
+func TestBinaryCheck(t *testing.T) {
+	if err := health.BinaryCheck("sh").Check(context.Background()); err != nil {
+		t.Errorf("expected sh to be found, got %v", err)
+	}
+	if err := health.BinaryCheck("definitely-not-a-real-binary").Check(context.Background()); err == nil {
+		t.Error("expected missing binary to fail")
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := health.NewMetrics()
+	metrics.AddGauge("slack_agent_queue_depth", "Agent runs waiting for a free slot.", func() float64 { return 3 })
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	expected := "# HELP slack_agent_queue_depth Agent runs waiting for a free slot.\n# TYPE slack_agent_queue_depth gauge\nslack_agent_queue_depth 3\n"
+	if rec.Body.String() != expected {
+		t.Errorf("unexpected metrics output: %q", rec.Body.String())
+	}
+}
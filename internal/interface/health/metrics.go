@@ -0,0 +1,52 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// gauge is a value sampled when metrics are scraped
+type gauge struct {
+	help  string
+	value func() float64
+}
+
+// Metrics serves gauges in the Prometheus text exposition format
+type Metrics struct {
+	mu     sync.RWMutex
+	gauges map[string]gauge
+}
+
+// NewMetrics creates a new Metrics instance
+func NewMetrics() *Metrics {
+	return &Metrics{
+		gauges: make(map[string]gauge),
+	}
+}
+
+// AddGauge registers a gauge whose value is read on every scrape
+func (m *Metrics) AddGauge(name, help string, value func() float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = gauge{help: help, value: value}
+}
+
+// ServeHTTP writes all gauges
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.gauges))
+	for name := range m.gauges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, name := range names {
+		g := m.gauges[name]
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %g\n", name, g.help, name, name, g.value())
+	}
+}
@@ -0,0 +1,54 @@
+package webapi
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/slack-go/slack"
+)
+
+// CommandDispatcher receives verified slash command payloads
+type CommandDispatcher interface {
+	DispatchSlashCommand(command slack.SlashCommand)
+}
+
+// commandsHandler serves the slash command request URL
+type commandsHandler struct {
+	signingSecret string
+	dispatcher    CommandDispatcher
+}
+
+// NewCommandsHandler creates an http.Handler for the Slack slash command request URL
+func NewCommandsHandler(signingSecret string, dispatcher CommandDispatcher) http.Handler {
+	return &commandsHandler{
+		signingSecret: signingSecret,
+		dispatcher:    dispatcher,
+	}
+}
+
+// ServeHTTP verifies the request signature and handles the command
+func (h *commandsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := verifyRequest(r, h.signingSecret); err != nil {
+		log.Printf("Rejected slash command request: %v", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	command, err := slack.SlashCommandParse(r)
+	if err != nil {
+		log.Printf("Failed to parse slash command payload: %v", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Acknowledge with an empty body so that Slack doesn't echo anything;
+	// replies are posted by the command handler
+	w.WriteHeader(http.StatusOK)
+	h.dispatcher.DispatchSlashCommand(command)
+}
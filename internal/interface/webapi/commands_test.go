@@ -0,0 +1,88 @@
+package webapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/takutakahashi/slack-agent/internal/interface/webapi"
+)
+
+type fakeCommandDispatcher struct {
+	commands []slack.SlashCommand
+}
+
+func (d *fakeCommandDispatcher) DispatchSlashCommand(command slack.SlashCommand) {
+	d.commands = append(d.commands, command)
+}
+
+func TestCommandsHandler(t *testing.T) {
+	body := url.Values{
+		"team_id":    {"T123"},
+		"channel_id": {"C123"},
+		"user_id":    {"U123"},
+		"command":    {"/agent"},
+		"text":       {"ask hello"},
+	}.Encode()
+
+	tests := []struct {
+		name       string
+		request    func() *http.Request
+		wantStatus int
+		wantCount  int
+	}{
+		{
+			name: "should dispatch signed command",
+			request: func() *http.Request {
+				return signedRequest(t, testSigningSecret, body, time.Now())
+			},
+			wantStatus: http.StatusOK,
+			wantCount:  1,
+		},
+		{
+			name: "should reject invalid signature",
+			request: func() *http.Request {
+				return signedRequest(t, "other-secret", body, time.Now())
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "should reject other methods",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/slack/commands", nil)
+			},
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := &fakeCommandDispatcher{}
+			handler := webapi.NewCommandsHandler(testSigningSecret, dispatcher)
+
+			req := tt.request()
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if len(dispatcher.commands) != tt.wantCount {
+				t.Fatalf("expected %d dispatched commands, got %d", tt.wantCount, len(dispatcher.commands))
+			}
+			if tt.wantCount > 0 {
+				command := dispatcher.commands[0]
+				if command.Command != "/agent" || command.Text != "ask hello" || command.ChannelID != "C123" {
+					t.Errorf("unexpected command: %+v", command)
+				}
+				if rec.Body.Len() != 0 {
+					t.Errorf("expected an empty body, got %q", rec.Body.String())
+				}
+			}
+		})
+	}
+}
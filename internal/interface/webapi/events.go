@@ -0,0 +1,111 @@
+package webapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/takutakahashi/slack-agent/internal/interface/dedup"
+)
+
+// maxBodySize limits the size of request bodies accepted from Slack
+const maxBodySize = 1 << 20
+
+// EventDispatcher receives verified Events API payloads
+type EventDispatcher interface {
+	DispatchEventsAPI(event slackevents.EventsAPIEvent, retry dedup.Retry)
+}
+
+// eventsHandler serves the Events API request URL
+type eventsHandler struct {
+	signingSecret string
+	dispatcher    EventDispatcher
+}
+
+// NewEventsHandler creates an http.Handler for the Slack Events API request URL
+func NewEventsHandler(signingSecret string, dispatcher EventDispatcher) http.Handler {
+	return &eventsHandler{
+		signingSecret: signingSecret,
+		dispatcher:    dispatcher,
+	}
+}
+
+// ServeHTTP verifies the request signature and handles the payload
+func (h *eventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := verifyRequest(r, h.signingSecret)
+	if err != nil {
+		log.Printf("Rejected Events API request: %v", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Signature has already been verified above
+	event, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		log.Printf("Failed to parse Events API payload: %v", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case slackevents.URLVerification:
+		var challenge slackevents.ChallengeResponse
+		if err := json.Unmarshal(body, &challenge); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(challenge.Challenge))
+
+	case slackevents.CallbackEvent:
+		// Acknowledge immediately; Slack retries if it gets no response within 3 seconds
+		w.WriteHeader(http.StatusOK)
+		h.dispatcher.DispatchEventsAPI(event, dedup.RetryFromHeader(r.Header))
+
+	default:
+		log.Printf("Unexpected Events API payload type received: %s", event.Type)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// verifyRequest reads the request body and checks it against the
+// X-Slack-Signature and X-Slack-Request-Timestamp headers
+func verifyRequest(r *http.Request, signingSecret string) ([]byte, error) {
+	if signingSecret == "" {
+		return nil, errors.New("signing secret is not configured")
+	}
+
+	verifier, err := slack.NewSecretsVerifier(r.Header, signingSecret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature headers: %w", err)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+
+	if _, err := verifier.Write(body); err != nil {
+		return nil, fmt.Errorf("failed to hash body: %w", err)
+	}
+	if err := verifier.Ensure(); err != nil {
+		return nil, fmt.Errorf("signature mismatch: %w", err)
+	}
+
+	// Allow downstream parsers (e.g. form decoding) to read the body again
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
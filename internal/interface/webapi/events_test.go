@@ -0,0 +1,163 @@
+package webapi_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack/slackevents"
+	"github.com/takutakahashi/slack-agent/internal/interface/dedup"
+	"github.com/takutakahashi/slack-agent/internal/interface/webapi"
+)
+
+const testSigningSecret = "test-signing-secret"
+
+type fakeDispatcher struct {
+	events  []slackevents.EventsAPIEvent
+	retries []dedup.Retry
+}
+
+func (d *fakeDispatcher) DispatchEventsAPI(event slackevents.EventsAPIEvent, retry dedup.Retry) {
+	d.events = append(d.events, event)
+	d.retries = append(d.retries, retry)
+}
+
+// signedRequest builds a request signed the same way Slack signs its requests
+func signedRequest(t *testing.T, secret, body string, timestamp time.Time) *http.Request {
+	t.Helper()
+
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(fmt.Sprintf("v0:%s:%s", ts, body)))
+
+	req := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestEventsHandler_URLVerification(t *testing.T) {
+	dispatcher := &fakeDispatcher{}
+	handler := webapi.NewEventsHandler(testSigningSecret, dispatcher)
+
+	body := `{"token":"x","challenge":"challenge-value","type":"url_verification"}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, signedRequest(t, testSigningSecret, body, time.Now()))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if rec.Body.String() != "challenge-value" {
+		t.Errorf("expected challenge to be echoed, got %q", rec.Body.String())
+	}
+	if len(dispatcher.events) != 0 {
+		t.Errorf("expected no dispatched events, got %d", len(dispatcher.events))
+	}
+}
+
+func TestEventsHandler_EventCallback(t *testing.T) {
+	dispatcher := &fakeDispatcher{}
+	handler := webapi.NewEventsHandler(testSigningSecret, dispatcher)
+
+	body := `{
+		"token": "x",
+		"team_id": "T123",
+		"api_app_id": "A123",
+		"type": "event_callback",
+		"event_id": "Ev123",
+		"event_time": 1234567890,
+		"event": {
+			"type": "app_mention",
+			"user": "U123",
+			"channel": "C123",
+			"text": "<@UBOT> hello",
+			"ts": "1234567890.123456"
+		}
+	}`
+	req := signedRequest(t, testSigningSecret, body, time.Now())
+	req.Header.Set("X-Slack-Retry-Num", "1")
+	req.Header.Set("X-Slack-Retry-Reason", "http_timeout")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if len(dispatcher.events) != 1 {
+		t.Fatalf("expected 1 dispatched event, got %d", len(dispatcher.events))
+	}
+
+	mention, ok := dispatcher.events[0].InnerEvent.Data.(*slackevents.AppMentionEvent)
+	if !ok {
+		t.Fatalf("expected AppMentionEvent, got %T", dispatcher.events[0].InnerEvent.Data)
+	}
+	if mention.Channel != "C123" {
+		t.Errorf("expected channel C123, got %s", mention.Channel)
+	}
+	if dispatcher.retries[0] != (dedup.Retry{Num: 1, Reason: "http_timeout"}) {
+		t.Errorf("expected the retry headers to be passed on, got %+v", dispatcher.retries[0])
+	}
+}
+
+func TestEventsHandler_RejectsInvalidRequests(t *testing.T) {
+	body := `{"token":"x","challenge":"challenge-value","type":"url_verification"}`
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		status  int
+	}{
+		{
+			name: "wrong signing secret",
+			request: func() *http.Request {
+				return signedRequest(t, "other-secret", body, time.Now())
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "stale timestamp",
+			request: func() *http.Request {
+				return signedRequest(t, testSigningSecret, body, time.Now().Add(-10*time.Minute))
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "missing headers",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(body))
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong method",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/slack/events", nil)
+			},
+			status: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := &fakeDispatcher{}
+			handler := webapi.NewEventsHandler(testSigningSecret, dispatcher)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.request())
+
+			if rec.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, rec.Code)
+			}
+			if len(dispatcher.events) != 0 {
+				t.Errorf("expected no dispatched events, got %d", len(dispatcher.events))
+			}
+		})
+	}
+}
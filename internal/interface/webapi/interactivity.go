@@ -0,0 +1,55 @@
+package webapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/slack-go/slack"
+)
+
+// InteractionDispatcher receives verified interaction payloads such as button clicks
+type InteractionDispatcher interface {
+	DispatchInteraction(callback slack.InteractionCallback)
+}
+
+// interactivityHandler serves the interactivity request URL
+type interactivityHandler struct {
+	signingSecret string
+	dispatcher    InteractionDispatcher
+}
+
+// NewInteractivityHandler creates an http.Handler for the Slack interactivity request URL
+func NewInteractivityHandler(signingSecret string, dispatcher InteractionDispatcher) http.Handler {
+	return &interactivityHandler{
+		signingSecret: signingSecret,
+		dispatcher:    dispatcher,
+	}
+}
+
+// ServeHTTP verifies the request signature and handles the interaction
+func (h *interactivityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := verifyRequest(r, h.signingSecret); err != nil {
+		log.Printf("Rejected interactivity request: %v", err)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// The payload is JSON in the "payload" form field
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(r.FormValue("payload")), &callback); err != nil {
+		log.Printf("Failed to parse interaction payload: %v", err)
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+
+	// Acknowledge immediately; Slack shows an error if it gets no response within 3 seconds
+	w.WriteHeader(http.StatusOK)
+	h.dispatcher.DispatchInteraction(callback)
+}
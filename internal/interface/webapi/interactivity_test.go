@@ -0,0 +1,80 @@
+package webapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/takutakahashi/slack-agent/internal/interface/webapi"
+)
+
+type fakeInteractionDispatcher struct {
+	callbacks []slack.InteractionCallback
+}
+
+func (d *fakeInteractionDispatcher) DispatchInteraction(callback slack.InteractionCallback) {
+	d.callbacks = append(d.callbacks, callback)
+}
+
+func TestInteractivityHandler(t *testing.T) {
+	payload := `{"type":"block_actions","team":{"id":"T1"},"user":{"id":"U1"},"actions":[{"block_id":"controls","action_id":"stop","value":"1.1","type":"button"}]}`
+	body := url.Values{"payload": {payload}}.Encode()
+
+	tests := []struct {
+		name       string
+		request    func() *http.Request
+		wantStatus int
+		wantCount  int
+	}{
+		{
+			name: "should dispatch signed interaction",
+			request: func() *http.Request {
+				return signedRequest(t, testSigningSecret, body, time.Now())
+			},
+			wantStatus: http.StatusOK,
+			wantCount:  1,
+		},
+		{
+			name: "should reject invalid signature",
+			request: func() *http.Request {
+				return signedRequest(t, "other-secret", body, time.Now())
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "should reject malformed payload",
+			request: func() *http.Request {
+				return signedRequest(t, testSigningSecret, "payload=not-json", time.Now())
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dispatcher := &fakeInteractionDispatcher{}
+			handler := webapi.NewInteractivityHandler(testSigningSecret, dispatcher)
+
+			req := tt.request()
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if len(dispatcher.callbacks) != tt.wantCount {
+				t.Fatalf("expected %d dispatched interactions, got %d", tt.wantCount, len(dispatcher.callbacks))
+			}
+			if tt.wantCount > 0 {
+				callback := dispatcher.callbacks[0]
+				if callback.Type != slack.InteractionTypeBlockActions || len(callback.ActionCallback.BlockActions) != 1 {
+					t.Errorf("unexpected callback: %+v", callback)
+				}
+			}
+		})
+	}
+}
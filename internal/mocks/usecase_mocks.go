@@ -11,7 +11,9 @@ package mocks
 
 import (
 	context "context"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	domain "github.com/takutakahashi/slack-agent/internal/domain"
 	gomock "go.uber.org/mock/gomock"
@@ -41,6 +43,34 @@ func (m *MockSlackRepository) EXPECT() *MockSlackRepositoryMockRecorder {
 	return m.recorder
 }
 
+// DeleteMessage mocks base method.
+func (m *MockSlackRepository) DeleteMessage(ctx context.Context, channelID, ts string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteMessage", ctx, channelID, ts)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteMessage indicates an expected call of DeleteMessage.
+func (mr *MockSlackRepositoryMockRecorder) DeleteMessage(ctx, channelID, ts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteMessage", reflect.TypeOf((*MockSlackRepository)(nil).DeleteMessage), ctx, channelID, ts)
+}
+
+// DownloadFile mocks base method.
+func (m *MockSlackRepository) DownloadFile(ctx context.Context, url string, w io.Writer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DownloadFile", ctx, url, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DownloadFile indicates an expected call of DownloadFile.
+func (mr *MockSlackRepositoryMockRecorder) DownloadFile(ctx, url, w any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadFile", reflect.TypeOf((*MockSlackRepository)(nil).DownloadFile), ctx, url, w)
+}
+
 // GetBotUserID mocks base method.
 func (m *MockSlackRepository) GetBotUserID(ctx context.Context) (string, error) {
 	m.ctrl.T.Helper()
@@ -56,20 +86,181 @@ func (mr *MockSlackRepositoryMockRecorder) GetBotUserID(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBotUserID", reflect.TypeOf((*MockSlackRepository)(nil).GetBotUserID), ctx)
 }
 
-// PostMessage mocks base method.
-func (m *MockSlackRepository) PostMessage(ctx context.Context, channelID, text, threadTS string) error {
+// GetChannelName mocks base method.
+func (m *MockSlackRepository) GetChannelName(ctx context.Context, channelID string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PostMessage", ctx, channelID, text, threadTS)
+	ret := m.ctrl.Call(m, "GetChannelName", ctx, channelID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetChannelName indicates an expected call of GetChannelName.
+func (mr *MockSlackRepositoryMockRecorder) GetChannelName(ctx, channelID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetChannelName", reflect.TypeOf((*MockSlackRepository)(nil).GetChannelName), ctx, channelID)
+}
+
+// GetThreadReplies mocks base method.
+func (m *MockSlackRepository) GetThreadReplies(ctx context.Context, channelID, threadTS, before string) ([]*domain.Message, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetThreadReplies", ctx, channelID, threadTS, before)
+	ret0, _ := ret[0].([]*domain.Message)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetThreadReplies indicates an expected call of GetThreadReplies.
+func (mr *MockSlackRepositoryMockRecorder) GetThreadReplies(ctx, channelID, threadTS, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetThreadReplies", reflect.TypeOf((*MockSlackRepository)(nil).GetThreadReplies), ctx, channelID, threadTS, before)
+}
+
+// GetThreadTS mocks base method.
+func (m *MockSlackRepository) GetThreadTS(ctx context.Context, channelID, ts string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetThreadTS", ctx, channelID, ts)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetThreadTS indicates an expected call of GetThreadTS.
+func (mr *MockSlackRepositoryMockRecorder) GetThreadTS(ctx, channelID, ts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetThreadTS", reflect.TypeOf((*MockSlackRepository)(nil).GetThreadTS), ctx, channelID, ts)
+}
+
+// GetUserGroupMembers mocks base method.
+func (m *MockSlackRepository) GetUserGroupMembers(ctx context.Context, groupID string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserGroupMembers", ctx, groupID)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserGroupMembers indicates an expected call of GetUserGroupMembers.
+func (mr *MockSlackRepositoryMockRecorder) GetUserGroupMembers(ctx, groupID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserGroupMembers", reflect.TypeOf((*MockSlackRepository)(nil).GetUserGroupMembers), ctx, groupID)
+}
+
+// GetUserName mocks base method.
+func (m *MockSlackRepository) GetUserName(ctx context.Context, userID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserName", ctx, userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserName indicates an expected call of GetUserName.
+func (mr *MockSlackRepositoryMockRecorder) GetUserName(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserName", reflect.TypeOf((*MockSlackRepository)(nil).GetUserName), ctx, userID)
+}
+
+// PostControls mocks base method.
+func (m *MockSlackRepository) PostControls(ctx context.Context, channelID, threadTS, text string, controls []domain.Control) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostControls", ctx, channelID, threadTS, text, controls)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PostControls indicates an expected call of PostControls.
+func (mr *MockSlackRepositoryMockRecorder) PostControls(ctx, channelID, threadTS, text, controls any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostControls", reflect.TypeOf((*MockSlackRepository)(nil).PostControls), ctx, channelID, threadTS, text, controls)
+}
+
+// PostEphemeral mocks base method.
+func (m *MockSlackRepository) PostEphemeral(ctx context.Context, channelID, userID, text, threadTS string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostEphemeral", ctx, channelID, userID, text, threadTS)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
+// PostEphemeral indicates an expected call of PostEphemeral.
+func (mr *MockSlackRepositoryMockRecorder) PostEphemeral(ctx, channelID, userID, text, threadTS any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostEphemeral", reflect.TypeOf((*MockSlackRepository)(nil).PostEphemeral), ctx, channelID, userID, text, threadTS)
+}
+
+// PostMessage mocks base method.
+func (m *MockSlackRepository) PostMessage(ctx context.Context, channelID, text, threadTS string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PostMessage", ctx, channelID, text, threadTS)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
 // PostMessage indicates an expected call of PostMessage.
 func (mr *MockSlackRepositoryMockRecorder) PostMessage(ctx, channelID, text, threadTS any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PostMessage", reflect.TypeOf((*MockSlackRepository)(nil).PostMessage), ctx, channelID, text, threadTS)
 }
 
+// RespondToCommand mocks base method.
+func (m *MockSlackRepository) RespondToCommand(ctx context.Context, responseURL, text string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RespondToCommand", ctx, responseURL, text)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RespondToCommand indicates an expected call of RespondToCommand.
+func (mr *MockSlackRepositoryMockRecorder) RespondToCommand(ctx, responseURL, text any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RespondToCommand", reflect.TypeOf((*MockSlackRepository)(nil).RespondToCommand), ctx, responseURL, text)
+}
+
+// SetControls mocks base method.
+func (m *MockSlackRepository) SetControls(ctx context.Context, channelID, threadTS, ts, text string, controls []domain.Control) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetControls", ctx, channelID, threadTS, ts, text, controls)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetControls indicates an expected call of SetControls.
+func (mr *MockSlackRepositoryMockRecorder) SetControls(ctx, channelID, threadTS, ts, text, controls any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetControls", reflect.TypeOf((*MockSlackRepository)(nil).SetControls), ctx, channelID, threadTS, ts, text, controls)
+}
+
+// UpdateMessage mocks base method.
+func (m *MockSlackRepository) UpdateMessage(ctx context.Context, channelID, ts, text string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMessage", ctx, channelID, ts, text)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateMessage indicates an expected call of UpdateMessage.
+func (mr *MockSlackRepositoryMockRecorder) UpdateMessage(ctx, channelID, ts, text any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMessage", reflect.TypeOf((*MockSlackRepository)(nil).UpdateMessage), ctx, channelID, ts, text)
+}
+
+// UploadFile mocks base method.
+func (m *MockSlackRepository) UploadFile(ctx context.Context, channelID, threadTS, filename string, size int64, content io.Reader) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadFile", ctx, channelID, threadTS, filename, size, content)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UploadFile indicates an expected call of UploadFile.
+func (mr *MockSlackRepositoryMockRecorder) UploadFile(ctx, channelID, threadTS, filename, size, content any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadFile", reflect.TypeOf((*MockSlackRepository)(nil).UploadFile), ctx, channelID, threadTS, filename, size, content)
+}
+
 // MockAgentRepository is a mock of AgentRepository interface.
 type MockAgentRepository struct {
 	ctrl     *gomock.Controller
@@ -94,19 +285,374 @@ func (m *MockAgentRepository) EXPECT() *MockAgentRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CancelAll mocks base method.
+func (m *MockAgentRepository) CancelAll() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelAll")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// CancelAll indicates an expected call of CancelAll.
+func (mr *MockAgentRepositoryMockRecorder) CancelAll() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelAll", reflect.TypeOf((*MockAgentRepository)(nil).CancelAll))
+}
+
+// CancelSession mocks base method.
+func (m *MockAgentRepository) CancelSession(key domain.SessionKey) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelSession", key)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// CancelSession indicates an expected call of CancelSession.
+func (mr *MockAgentRepositoryMockRecorder) CancelSession(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelSession", reflect.TypeOf((*MockAgentRepository)(nil).CancelSession), key)
+}
+
+// ForgetSession mocks base method.
+func (m *MockAgentRepository) ForgetSession(key domain.SessionKey) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ForgetSession", key)
+}
+
+// ForgetSession indicates an expected call of ForgetSession.
+func (mr *MockAgentRepositoryMockRecorder) ForgetSession(key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForgetSession", reflect.TypeOf((*MockAgentRepository)(nil).ForgetSession), key)
+}
+
 // GenerateResponse mocks base method.
-func (m *MockAgentRepository) GenerateResponse(ctx context.Context, message *domain.Message) (*domain.AgentResult, error) {
+func (m *MockAgentRepository) GenerateResponse(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GenerateResponse", ctx, message)
+	ret := m.ctrl.Call(m, "GenerateResponse", ctx, session, message)
 	ret0, _ := ret[0].(*domain.AgentResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GenerateResponse indicates an expected call of GenerateResponse.
-func (mr *MockAgentRepositoryMockRecorder) GenerateResponse(ctx, message any) *gomock.Call {
+func (mr *MockAgentRepositoryMockRecorder) GenerateResponse(ctx, session, message any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateResponse", reflect.TypeOf((*MockAgentRepository)(nil).GenerateResponse), ctx, session, message)
+}
+
+// MockSessionRepository is a mock of SessionRepository interface.
+type MockSessionRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockSessionRepositoryMockRecorder is the mock recorder for MockSessionRepository.
+type MockSessionRepositoryMockRecorder struct {
+	mock *MockSessionRepository
+}
+
+// NewMockSessionRepository creates a new mock instance.
+func NewMockSessionRepository(ctrl *gomock.Controller) *MockSessionRepository {
+	mock := &MockSessionRepository{ctrl: ctrl}
+	mock.recorder = &MockSessionRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionRepository) EXPECT() *MockSessionRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockSessionRepository) Delete(ctx context.Context, key domain.SessionKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockSessionRepositoryMockRecorder) Delete(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockSessionRepository)(nil).Delete), ctx, key)
+}
+
+// Get mocks base method.
+func (m *MockSessionRepository) Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(*domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockSessionRepositoryMockRecorder) Get(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockSessionRepository)(nil).Get), ctx, key)
+}
+
+// List mocks base method.
+func (m *MockSessionRepository) List(ctx context.Context) ([]*domain.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx)
+	ret0, _ := ret[0].([]*domain.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockSessionRepositoryMockRecorder) List(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockSessionRepository)(nil).List), ctx)
+}
+
+// Save mocks base method.
+func (m *MockSessionRepository) Save(ctx context.Context, session *domain.Session) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, session)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockSessionRepositoryMockRecorder) Save(ctx, session any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockSessionRepository)(nil).Save), ctx, session)
+}
+
+// MockWorkspaceRepository is a mock of WorkspaceRepository interface.
+type MockWorkspaceRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockWorkspaceRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockWorkspaceRepositoryMockRecorder is the mock recorder for MockWorkspaceRepository.
+type MockWorkspaceRepositoryMockRecorder struct {
+	mock *MockWorkspaceRepository
+}
+
+// NewMockWorkspaceRepository creates a new mock instance.
+func NewMockWorkspaceRepository(ctrl *gomock.Controller) *MockWorkspaceRepository {
+	mock := &MockWorkspaceRepository{ctrl: ctrl}
+	mock.recorder = &MockWorkspaceRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockWorkspaceRepository) EXPECT() *MockWorkspaceRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Open mocks base method.
+func (m *MockWorkspaceRepository) Open(ctx context.Context, key domain.SessionKey, path string) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Open", ctx, key, path)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Open indicates an expected call of Open.
+func (mr *MockWorkspaceRepositoryMockRecorder) Open(ctx, key, path any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Open", reflect.TypeOf((*MockWorkspaceRepository)(nil).Open), ctx, key, path)
+}
+
+// Remove mocks base method.
+func (m *MockWorkspaceRepository) Remove(ctx context.Context, key domain.SessionKey) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Remove", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Remove indicates an expected call of Remove.
+func (mr *MockWorkspaceRepositoryMockRecorder) Remove(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Remove", reflect.TypeOf((*MockWorkspaceRepository)(nil).Remove), ctx, key)
+}
+
+// SaveAttachment mocks base method.
+func (m *MockWorkspaceRepository) SaveAttachment(ctx context.Context, key domain.SessionKey, name string, content io.Reader) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAttachment", ctx, key, name, content)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveAttachment indicates an expected call of SaveAttachment.
+func (mr *MockWorkspaceRepositoryMockRecorder) SaveAttachment(ctx, key, name, content any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAttachment", reflect.TypeOf((*MockWorkspaceRepository)(nil).SaveAttachment), ctx, key, name, content)
+}
+
+// Size mocks base method.
+func (m *MockWorkspaceRepository) Size(ctx context.Context, key domain.SessionKey) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Size", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Size indicates an expected call of Size.
+func (mr *MockWorkspaceRepositoryMockRecorder) Size(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Size", reflect.TypeOf((*MockWorkspaceRepository)(nil).Size), ctx, key)
+}
+
+// Snapshot mocks base method.
+func (m *MockWorkspaceRepository) Snapshot(ctx context.Context, key domain.SessionKey) (domain.WorkspaceSnapshot, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Snapshot", ctx, key)
+	ret0, _ := ret[0].(domain.WorkspaceSnapshot)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Snapshot indicates an expected call of Snapshot.
+func (mr *MockWorkspaceRepositoryMockRecorder) Snapshot(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Snapshot", reflect.TypeOf((*MockWorkspaceRepository)(nil).Snapshot), ctx, key)
+}
+
+// Workdir mocks base method.
+func (m *MockWorkspaceRepository) Workdir(ctx context.Context, key domain.SessionKey, userID string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Workdir", ctx, key, userID)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Workdir indicates an expected call of Workdir.
+func (mr *MockWorkspaceRepositoryMockRecorder) Workdir(ctx, key, userID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GenerateResponse", reflect.TypeOf((*MockAgentRepository)(nil).GenerateResponse), ctx, message)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Workdir", reflect.TypeOf((*MockWorkspaceRepository)(nil).Workdir), ctx, key, userID)
+}
+
+// MockRateLimitRepository is a mock of RateLimitRepository interface.
+type MockRateLimitRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockRateLimitRepositoryMockRecorder is the mock recorder for MockRateLimitRepository.
+type MockRateLimitRepositoryMockRecorder struct {
+	mock *MockRateLimitRepository
+}
+
+// NewMockRateLimitRepository creates a new mock instance.
+func NewMockRateLimitRepository(ctrl *gomock.Controller) *MockRateLimitRepository {
+	mock := &MockRateLimitRepository{ctrl: ctrl}
+	mock.recorder = &MockRateLimitRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitRepository) EXPECT() *MockRateLimitRepositoryMockRecorder {
+	return m.recorder
+}
+
+// DeleteIdle mocks base method.
+func (m *MockRateLimitRepository) DeleteIdle(ctx context.Context, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIdle", ctx, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteIdle indicates an expected call of DeleteIdle.
+func (mr *MockRateLimitRepositoryMockRecorder) DeleteIdle(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIdle", reflect.TypeOf((*MockRateLimitRepository)(nil).DeleteIdle), ctx, before)
+}
+
+// Get mocks base method.
+func (m *MockRateLimitRepository) Get(ctx context.Context, key string) (*domain.TokenBucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(*domain.TokenBucket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockRateLimitRepositoryMockRecorder) Get(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRateLimitRepository)(nil).Get), ctx, key)
+}
+
+// Save mocks base method.
+func (m *MockRateLimitRepository) Save(ctx context.Context, key string, bucket *domain.TokenBucket) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, key, bucket)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockRateLimitRepositoryMockRecorder) Save(ctx, key, bucket any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRateLimitRepository)(nil).Save), ctx, key, bucket)
+}
+
+// MockFeedbackRepository is a mock of FeedbackRepository interface.
+type MockFeedbackRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockFeedbackRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockFeedbackRepositoryMockRecorder is the mock recorder for MockFeedbackRepository.
+type MockFeedbackRepositoryMockRecorder struct {
+	mock *MockFeedbackRepository
+}
+
+// NewMockFeedbackRepository creates a new mock instance.
+func NewMockFeedbackRepository(ctrl *gomock.Controller) *MockFeedbackRepository {
+	mock := &MockFeedbackRepository{ctrl: ctrl}
+	mock.recorder = &MockFeedbackRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFeedbackRepository) EXPECT() *MockFeedbackRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockFeedbackRepository) Add(ctx context.Context, feedback *domain.Feedback) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ctx, feedback)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockFeedbackRepositoryMockRecorder) Add(ctx, feedback any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockFeedbackRepository)(nil).Add), ctx, feedback)
+}
+
+// List mocks base method.
+func (m *MockFeedbackRepository) List(ctx context.Context, since time.Time) ([]*domain.Feedback, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, since)
+	ret0, _ := ret[0].([]*domain.Feedback)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockFeedbackRepositoryMockRecorder) List(ctx, since any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockFeedbackRepository)(nil).List), ctx, since)
 }
 
 // MockMessageHandler is a mock of MessageHandler interface.
@@ -133,6 +679,20 @@ func (m *MockMessageHandler) EXPECT() *MockMessageHandlerMockRecorder {
 	return m.recorder
 }
 
+// HandleAction mocks base method.
+func (m *MockMessageHandler) HandleAction(ctx context.Context, action *domain.Action) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleAction", ctx, action)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleAction indicates an expected call of HandleAction.
+func (mr *MockMessageHandlerMockRecorder) HandleAction(ctx, action any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleAction", reflect.TypeOf((*MockMessageHandler)(nil).HandleAction), ctx, action)
+}
+
 // HandleMessage mocks base method.
 func (m *MockMessageHandler) HandleMessage(ctx context.Context, message *domain.Message) error {
 	m.ctrl.T.Helper()
@@ -146,3 +706,205 @@ func (mr *MockMessageHandlerMockRecorder) HandleMessage(ctx, message any) *gomoc
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleMessage", reflect.TypeOf((*MockMessageHandler)(nil).HandleMessage), ctx, message)
 }
+
+// HandleReaction mocks base method.
+func (m *MockMessageHandler) HandleReaction(ctx context.Context, reaction *domain.Reaction) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleReaction", ctx, reaction)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleReaction indicates an expected call of HandleReaction.
+func (mr *MockMessageHandlerMockRecorder) HandleReaction(ctx, reaction any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleReaction", reflect.TypeOf((*MockMessageHandler)(nil).HandleReaction), ctx, reaction)
+}
+
+// Shutdown mocks base method.
+func (m *MockMessageHandler) Shutdown(ctx context.Context, drain time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Shutdown", ctx, drain)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Shutdown indicates an expected call of Shutdown.
+func (mr *MockMessageHandlerMockRecorder) Shutdown(ctx, drain any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Shutdown", reflect.TypeOf((*MockMessageHandler)(nil).Shutdown), ctx, drain)
+}
+
+// MockCommandHandler is a mock of CommandHandler interface.
+type MockCommandHandler struct {
+	ctrl     *gomock.Controller
+	recorder *MockCommandHandlerMockRecorder
+	isgomock struct{}
+}
+
+// MockCommandHandlerMockRecorder is the mock recorder for MockCommandHandler.
+type MockCommandHandlerMockRecorder struct {
+	mock *MockCommandHandler
+}
+
+// NewMockCommandHandler creates a new mock instance.
+func NewMockCommandHandler(ctrl *gomock.Controller) *MockCommandHandler {
+	mock := &MockCommandHandler{ctrl: ctrl}
+	mock.recorder = &MockCommandHandlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockCommandHandler) EXPECT() *MockCommandHandlerMockRecorder {
+	return m.recorder
+}
+
+// HandleCommand mocks base method.
+func (m *MockCommandHandler) HandleCommand(ctx context.Context, command *domain.SlashCommand) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HandleCommand", ctx, command)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HandleCommand indicates an expected call of HandleCommand.
+func (mr *MockCommandHandlerMockRecorder) HandleCommand(ctx, command any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HandleCommand", reflect.TypeOf((*MockCommandHandler)(nil).HandleCommand), ctx, command)
+}
+
+// MockLeaseRepository is a mock of LeaseRepository interface.
+type MockLeaseRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockLeaseRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockLeaseRepositoryMockRecorder is the mock recorder for MockLeaseRepository.
+type MockLeaseRepositoryMockRecorder struct {
+	mock *MockLeaseRepository
+}
+
+// NewMockLeaseRepository creates a new mock instance.
+func NewMockLeaseRepository(ctrl *gomock.Controller) *MockLeaseRepository {
+	mock := &MockLeaseRepository{ctrl: ctrl}
+	mock.recorder = &MockLeaseRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLeaseRepository) EXPECT() *MockLeaseRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Acquire mocks base method.
+func (m *MockLeaseRepository) Acquire(ctx context.Context, name, holder string, ttl time.Duration) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Acquire", ctx, name, holder, ttl)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Acquire indicates an expected call of Acquire.
+func (mr *MockLeaseRepositoryMockRecorder) Acquire(ctx, name, holder, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Acquire", reflect.TypeOf((*MockLeaseRepository)(nil).Acquire), ctx, name, holder, ttl)
+}
+
+// Holder mocks base method.
+func (m *MockLeaseRepository) Holder(ctx context.Context, name string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Holder", ctx, name)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Holder indicates an expected call of Holder.
+func (mr *MockLeaseRepositoryMockRecorder) Holder(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Holder", reflect.TypeOf((*MockLeaseRepository)(nil).Holder), ctx, name)
+}
+
+// Release mocks base method.
+func (m *MockLeaseRepository) Release(ctx context.Context, name, holder string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, name, holder)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockLeaseRepositoryMockRecorder) Release(ctx, name, holder any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockLeaseRepository)(nil).Release), ctx, name, holder)
+}
+
+// MockHandoffRepository is a mock of HandoffRepository interface.
+type MockHandoffRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockHandoffRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockHandoffRepositoryMockRecorder is the mock recorder for MockHandoffRepository.
+type MockHandoffRepositoryMockRecorder struct {
+	mock *MockHandoffRepository
+}
+
+// NewMockHandoffRepository creates a new mock instance.
+func NewMockHandoffRepository(ctrl *gomock.Controller) *MockHandoffRepository {
+	mock := &MockHandoffRepository{ctrl: ctrl}
+	mock.recorder = &MockHandoffRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHandoffRepository) EXPECT() *MockHandoffRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Receive mocks base method.
+func (m *MockHandoffRepository) Receive(ctx context.Context, replica string) ([]*domain.Handoff, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Receive", ctx, replica)
+	ret0, _ := ret[0].([]*domain.Handoff)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Receive indicates an expected call of Receive.
+func (mr *MockHandoffRepositoryMockRecorder) Receive(ctx, replica any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Receive", reflect.TypeOf((*MockHandoffRepository)(nil).Receive), ctx, replica)
+}
+
+// Replicas mocks base method.
+func (m *MockHandoffRepository) Replicas(ctx context.Context) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replicas", ctx)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Replicas indicates an expected call of Replicas.
+func (mr *MockHandoffRepositoryMockRecorder) Replicas(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replicas", reflect.TypeOf((*MockHandoffRepository)(nil).Replicas), ctx)
+}
+
+// Send mocks base method.
+func (m *MockHandoffRepository) Send(ctx context.Context, replica string, handoff *domain.Handoff) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", ctx, replica, handoff)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Send indicates an expected call of Send.
+func (mr *MockHandoffRepositoryMockRecorder) Send(ctx, replica, handoff any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockHandoffRepository)(nil).Send), ctx, replica, handoff)
+}
@@ -0,0 +1,104 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrAgentQueueFull is returned when no agent slot is free and the wait queue is full
+var ErrAgentQueueFull = errors.New("agent queue is full")
+
+// AgentPool limits how many agents run at the same time.
+// Callers beyond the limit wait in a bounded FIFO queue.
+type AgentPool struct {
+	maxActive int
+	maxQueued int
+
+	mu      sync.Mutex
+	active  int
+	waiters []chan struct{}
+}
+
+// NewAgentPool creates a new AgentPool instance.
+// A maxActive of zero or less disables the limit.
+func NewAgentPool(maxActive, maxQueued int) *AgentPool {
+	return &AgentPool{
+		maxActive: maxActive,
+		maxQueued: maxQueued,
+	}
+}
+
+// Acquire waits for a free agent slot.
+// onQueued is called with the 1-based queue position when the caller has to wait.
+// The returned release function must be called when the agent has finished.
+func (p *AgentPool) Acquire(ctx context.Context, onQueued func(position int)) (func(), error) {
+	p.mu.Lock()
+	if p.maxActive <= 0 || p.active < p.maxActive {
+		p.active++
+		p.mu.Unlock()
+		return p.release, nil
+	}
+	if len(p.waiters) >= p.maxQueued {
+		p.mu.Unlock()
+		return nil, ErrAgentQueueFull
+	}
+
+	ready := make(chan struct{})
+	p.waiters = append(p.waiters, ready)
+	position := len(p.waiters)
+	p.mu.Unlock()
+
+	if onQueued != nil {
+		onQueued(position)
+	}
+
+	select {
+	case <-ready:
+		return p.release, nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		for i, w := range p.waiters {
+			if w == ready {
+				p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		// The slot was handed over just as the context ended; pass it on
+		p.releaseLocked()
+		return nil, ctx.Err()
+	}
+}
+
+// release frees a slot, handing it to the longest waiting caller if there is one
+func (p *AgentPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.releaseLocked()
+}
+
+// releaseLocked frees a slot; p.mu must be held
+func (p *AgentPool) releaseLocked() {
+	if len(p.waiters) > 0 {
+		next := p.waiters[0]
+		p.waiters = p.waiters[1:]
+		close(next)
+		return
+	}
+	p.active--
+}
+
+// Active returns the number of running agents
+func (p *AgentPool) Active() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.active
+}
+
+// QueueDepth returns the number of callers waiting for a slot
+func (p *AgentPool) QueueDepth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.waiters)
+}
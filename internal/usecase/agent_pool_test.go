@@ -0,0 +1,86 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+func TestAgentPool(t *testing.T) {
+	pool := usecase.NewAgentPool(1, 1)
+
+	release, err := pool.Acquire(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	positions := make(chan int, 1)
+	acquired := make(chan func())
+	go func() {
+		r, err := pool.Acquire(context.Background(), func(position int) { positions <- position })
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		acquired <- r
+	}()
+
+	if position := <-positions; position != 1 {
+		t.Errorf("expected position 1, got %d", position)
+	}
+	if depth := pool.QueueDepth(); depth != 1 {
+		t.Errorf("expected queue depth 1, got %d", depth)
+	}
+
+	// The queue is full now
+	if _, err := pool.Acquire(context.Background(), nil); !errors.Is(err, usecase.ErrAgentQueueFull) {
+		t.Errorf("expected ErrAgentQueueFull, got %v", err)
+	}
+
+	release()
+	second := <-acquired
+	if active := pool.Active(); active != 1 {
+		t.Errorf("expected slot to be handed over, got %d active", active)
+	}
+	if depth := pool.QueueDepth(); depth != 0 {
+		t.Errorf("expected empty queue, got %d", depth)
+	}
+
+	second()
+	if active := pool.Active(); active != 0 {
+		t.Errorf("expected no active agents, got %d", active)
+	}
+}
+
+func TestAgentPool_ContextCancelledWhileQueued(t *testing.T) {
+	pool := usecase.NewAgentPool(1, 5)
+	release, _ := pool.Acquire(context.Background(), nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if depth := pool.QueueDepth(); depth != 0 {
+		t.Errorf("expected waiter to be removed, got queue depth %d", depth)
+	}
+
+	release()
+	if active := pool.Active(); active != 0 {
+		t.Errorf("expected no active agents, got %d", active)
+	}
+}
+
+func TestAgentPool_Unlimited(t *testing.T) {
+	pool := usecase.NewAgentPool(0, 0)
+	for i := 0; i < 10; i++ {
+		if _, err := pool.Acquire(context.Background(), nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if active := pool.Active(); active != 10 {
+		t.Errorf("expected 10 active agents, got %d", active)
+	}
+}
@@ -0,0 +1,82 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// snapshotWorkspace records the session's files before a run so that the agent's output can be found afterwards.
+// It returns nil when uploads are disabled or the snapshot fails.
+func (h *messageHandlerImpl) snapshotWorkspace(ctx context.Context, key domain.SessionKey) domain.WorkspaceSnapshot {
+	if h.workspaces == nil || !h.artifactPolicy.Enabled() {
+		return nil
+	}
+	snapshot, err := h.workspaces.Snapshot(ctx, key)
+	if err != nil {
+		log.Printf("Error taking snapshot of session %s, files will not be uploaded: %v", key, err)
+		return nil
+	}
+	return snapshot
+}
+
+// uploadArtifacts uploads the files that the agent created or modified and that match the artifact policy
+func (h *messageHandlerImpl) uploadArtifacts(ctx context.Context, message *domain.Message, before domain.WorkspaceSnapshot) {
+	if before == nil {
+		return
+	}
+	key := message.SessionKey()
+	after, err := h.workspaces.Snapshot(ctx, key)
+	if err != nil {
+		log.Printf("Error taking snapshot of session %s: %v", key, err)
+		return
+	}
+
+	var skipped []string
+	uploaded := 0
+	for _, file := range before.Changed(after) {
+		if !h.artifactPolicy.Match(file) {
+			continue
+		}
+		if h.artifactPolicy.MaxBytes > 0 && file.Size > h.artifactPolicy.MaxBytes {
+			skipped = append(skipped, fmt.Sprintf("%s (too large)", file.Path))
+			continue
+		}
+		if h.artifactPolicy.MaxFiles > 0 && uploaded >= h.artifactPolicy.MaxFiles {
+			skipped = append(skipped, fmt.Sprintf("%s (too many files)", file.Path))
+			continue
+		}
+		if file.Size == 0 {
+			// Slack rejects empty uploads
+			continue
+		}
+		if err := h.uploadArtifact(ctx, message, file); err != nil {
+			log.Printf("Error uploading %s from session %s: %v", file.Path, key, err)
+			skipped = append(skipped, fmt.Sprintf("%s (upload failed)", file.Path))
+			continue
+		}
+		uploaded++
+	}
+
+	if len(skipped) > 0 {
+		if err := h.reply(ctx, message.ChannelID, "📎 Some files were not uploaded: "+strings.Join(skipped, ", "), message.ThreadTS); err != nil {
+			log.Printf("Error reporting skipped uploads: %v", err)
+		}
+	}
+}
+
+// uploadArtifact uploads a single file from the session to the thread
+func (h *messageHandlerImpl) uploadArtifact(ctx context.Context, message *domain.Message, file domain.WorkspaceFile) error {
+	content, err := h.workspaces.Open(ctx, message.SessionKey(), file.Path)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	log.Printf("Uploading %s (%d bytes) to thread %s", file.Path, file.Size, message.ThreadTS)
+	return h.slackRepo.UploadFile(ctx, message.ChannelID, message.ThreadTS, path.Base(file.Path), file.Size, content)
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// errAttachmentTooLarge is reported when a download exceeds the size limit
+var errAttachmentTooLarge = errors.New("the file is too large")
+
+// attachFiles downloads the files shared with the message into the session and returns
+// the message with their paths added to the prompt. Files that are rejected or fail to
+// download are reported to the user and left out.
+func (h *messageHandlerImpl) attachFiles(ctx context.Context, message *domain.Message) *domain.Message {
+	if h.workspaces == nil || len(message.Files) == 0 {
+		return message
+	}
+
+	var attached, skipped []string
+	for _, file := range message.Files {
+		if reason := h.attachmentPolicy.Check(file); reason != "" {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", file.Name, reason))
+			continue
+		}
+		path, err := h.downloadFile(ctx, message.SessionKey(), file)
+		if err != nil {
+			log.Printf("Error downloading file %s in thread %s: %v", file.ID, message.ThreadTS, err)
+			reason := "download failed"
+			if errors.Is(err, errAttachmentTooLarge) {
+				reason = err.Error()
+			}
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", file.Name, reason))
+			continue
+		}
+		log.Printf("Attached file %s to thread %s at %s", file.ID, message.ThreadTS, path)
+		attached = append(attached, fmt.Sprintf("- %s (%s, %s, %d bytes)", path, file.Name, file.MimeType, file.Size))
+	}
+
+	if len(skipped) > 0 {
+		if err := h.slackRepo.PostEphemeral(ctx, message.ChannelID, message.UserID,
+			"⚠️ I couldn't read some of your files: "+strings.Join(skipped, ", "),
+			message.ThreadTS); err != nil {
+			log.Printf("Error reporting skipped files: %v", err)
+		}
+	}
+	if len(attached) == 0 {
+		return message
+	}
+
+	withFiles := *message
+	withFiles.Text = message.Text + "\n\nThe user attached these files, which you can read from disk:\n" + strings.Join(attached, "\n")
+	return &withFiles
+}
+
+// downloadFile saves a shared file in the session's attachments directory.
+// The download is streamed to disk so that large files are never held in memory.
+func (h *messageHandlerImpl) downloadFile(ctx context.Context, key domain.SessionKey, file domain.File) (string, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(h.slackRepo.DownloadFile(ctx, file.URL, pw))
+	}()
+	// Stop the download when the file cannot be saved
+	defer pr.Close()
+
+	// The size in the event may be missing, so the limit is also enforced while downloading
+	var content io.Reader = pr
+	if limit := h.attachmentPolicy.MaxBytes; limit > 0 {
+		content = &limitedReader{r: io.LimitReader(pr, limit+1), max: limit}
+	}
+	return h.workspaces.SaveAttachment(ctx, key, file.LocalName(), content)
+}
+
+// limitedReader fails with errAttachmentTooLarge once more than max bytes have been read
+type limitedReader struct {
+	r    io.Reader
+	read int64
+	max  int64
+}
+
+// Read reads from the underlying reader
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.max {
+		return n, errAttachmentTooLarge
+	}
+	return n, err
+}
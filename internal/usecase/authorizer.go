@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// userGroupCacheTTL is how long user group members are cached
+const userGroupCacheTTL = 5 * time.Minute
+
+// userGroupMembers is a cached list of user group members
+type userGroupMembers struct {
+	members   []string
+	fetchedAt time.Time
+}
+
+// Authorizer decides who may invoke the agent.
+// The global policy is checked first, then the policy of the channel's profile.
+type Authorizer struct {
+	policy    domain.AccessPolicy
+	slackRepo SlackRepository
+
+	mu     sync.Mutex
+	groups map[string]userGroupMembers
+}
+
+// NewAuthorizer creates a new Authorizer instance
+func NewAuthorizer(policy domain.AccessPolicy, slackRepo SlackRepository) *Authorizer {
+	return &Authorizer{
+		policy:    policy,
+		slackRepo: slackRepo,
+		groups:    make(map[string]userGroupMembers),
+	}
+}
+
+// Authorize checks whether the user may invoke the agent in the channel and logs the decision.
+// profile may be nil when no profiles are configured.
+func (a *Authorizer) Authorize(ctx context.Context, userID, channelID string, profile *domain.Profile) domain.AccessDecision {
+	profileName := domain.DefaultProfileName
+	decision := a.check(ctx, a.policy, userID, channelID)
+	if profile != nil {
+		profileName = profile.Name
+		if decision.Allowed {
+			decision = a.check(ctx, profile.Access, userID, channelID)
+			if !decision.Allowed {
+				decision.Reason = fmt.Sprintf("profile %s: %s", profile.Name, decision.Reason)
+			}
+		}
+	}
+
+	verdict := "allowed"
+	if !decision.Allowed {
+		verdict = "denied"
+	}
+	log.Printf("Access %s for user %s in channel %s (profile %s): %s", verdict, userID, channelID, profileName, decision.Reason)
+	return decision
+}
+
+// check evaluates a single policy; deny rules take precedence over allow rules
+func (a *Authorizer) check(ctx context.Context, policy domain.AccessPolicy, userID, channelID string) domain.AccessDecision {
+	if policy.IsEmpty() {
+		return domain.AccessDecision{Allowed: true, Reason: "no restrictions"}
+	}
+
+	if slices.Contains(policy.Deny.Users, userID) {
+		return domain.AccessDecision{Reason: "user is denied"}
+	}
+	if slices.Contains(policy.Deny.Channels, channelID) {
+		return domain.AccessDecision{Reason: "channel is denied"}
+	}
+	for _, groupID := range policy.Deny.UserGroups {
+		member, err := a.isMember(ctx, groupID, userID)
+		if err != nil {
+			// Fail closed when a deny rule cannot be checked
+			return domain.AccessDecision{Reason: fmt.Sprintf("could not check user group %s: %v", groupID, err)}
+		}
+		if member {
+			return domain.AccessDecision{Reason: fmt.Sprintf("user group %s is denied", groupID)}
+		}
+	}
+
+	if policy.Allow.IsEmpty() {
+		return domain.AccessDecision{Allowed: true, Reason: "not denied"}
+	}
+	if slices.Contains(policy.Allow.Users, userID) {
+		return domain.AccessDecision{Allowed: true, Reason: "user is allowed"}
+	}
+	if slices.Contains(policy.Allow.Channels, channelID) {
+		return domain.AccessDecision{Allowed: true, Reason: "channel is allowed"}
+	}
+	for _, groupID := range policy.Allow.UserGroups {
+		member, err := a.isMember(ctx, groupID, userID)
+		if err != nil {
+			log.Printf("Error checking user group %s: %v", groupID, err)
+			continue
+		}
+		if member {
+			return domain.AccessDecision{Allowed: true, Reason: fmt.Sprintf("user group %s is allowed", groupID)}
+		}
+	}
+	return domain.AccessDecision{Reason: "not on the allow list"}
+}
+
+// ProfileFor returns the profile with the tool rules that match the user merged into its tool policy
+func (a *Authorizer) ProfileFor(ctx context.Context, userID, channelID string, profile *domain.Profile) *domain.Profile {
+	if len(profile.ToolRules) == 0 {
+		return profile
+	}
+
+	var rules []domain.ToolRule
+	for _, rule := range profile.ToolRules {
+		matched, err := a.matches(ctx, rule.Match, userID, channelID)
+		if err != nil {
+			// Fail closed: restrictions apply and tools are not granted when membership cannot be checked
+			log.Printf("Error checking user groups of tool rule: %v", err)
+			rules = append(rules, domain.ToolRule{Match: rule.Match, DisallowedTools: rule.DisallowedTools})
+			continue
+		}
+		if matched {
+			rules = append(rules, rule)
+		}
+	}
+	log.Printf("Applying %d of %d tool rules for user %s in channel %s (profile %s)", len(rules), len(profile.ToolRules), userID, channelID, profile.Name)
+	return profile.WithToolRules(rules)
+}
+
+// matches reports whether the rule lists the user, one of the user's groups or the channel.
+// It returns an error when the rule does not match otherwise and a group could not be checked.
+func (a *Authorizer) matches(ctx context.Context, rule domain.AccessRule, userID, channelID string) (bool, error) {
+	if slices.Contains(rule.Users, userID) || slices.Contains(rule.Channels, channelID) {
+		return true, nil
+	}
+	var errs []error
+	for _, groupID := range rule.UserGroups {
+		member, err := a.isMember(ctx, groupID, userID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user group %s: %w", groupID, err))
+			continue
+		}
+		if member {
+			return true, nil
+		}
+	}
+	return false, errors.Join(errs...)
+}
+
+// isMember reports whether the user belongs to the user group, using cached members when fresh
+func (a *Authorizer) isMember(ctx context.Context, groupID, userID string) (bool, error) {
+	a.mu.Lock()
+	cached, ok := a.groups[groupID]
+	a.mu.Unlock()
+
+	if !ok || time.Since(cached.fetchedAt) > userGroupCacheTTL {
+		members, err := a.slackRepo.GetUserGroupMembers(ctx, groupID)
+		if err != nil {
+			return false, err
+		}
+		cached = userGroupMembers{members: members, fetchedAt: time.Now()}
+
+		a.mu.Lock()
+		a.groups[groupID] = cached
+		a.mu.Unlock()
+	}
+
+	return slices.Contains(cached.members, userID), nil
+}
@@ -0,0 +1,169 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	sre := &domain.Profile{
+		Name:   "sre",
+		Access: domain.AccessPolicy{Allow: domain.AccessRule{UserGroups: []string{"SSRE"}}},
+	}
+
+	tests := []struct {
+		name      string
+		policy    domain.AccessPolicy
+		profile   *domain.Profile
+		userID    string
+		channelID string
+		allowed   bool
+	}{
+		{name: "no restrictions", userID: "U1", channelID: "C1", allowed: true},
+		{
+			name:      "denied user",
+			policy:    domain.AccessPolicy{Deny: domain.AccessRule{Users: []string{"U1"}}},
+			userID:    "U1",
+			channelID: "C1",
+		},
+		{
+			name:      "denied channel",
+			policy:    domain.AccessPolicy{Deny: domain.AccessRule{Channels: []string{"C1"}}},
+			userID:    "U1",
+			channelID: "C1",
+		},
+		{
+			name:      "denied user group wins over allowed user",
+			policy:    domain.AccessPolicy{Allow: domain.AccessRule{Users: []string{"U1"}}, Deny: domain.AccessRule{UserGroups: []string{"SBOTS"}}},
+			userID:    "U1",
+			channelID: "C1",
+		},
+		{
+			name:      "allowed channel",
+			policy:    domain.AccessPolicy{Allow: domain.AccessRule{Channels: []string{"C1"}}},
+			userID:    "U9",
+			channelID: "C1",
+			allowed:   true,
+		},
+		{
+			name:      "not on the allow list",
+			policy:    domain.AccessPolicy{Allow: domain.AccessRule{Users: []string{"U2"}}},
+			userID:    "U1",
+			channelID: "C1",
+		},
+		{
+			name:      "profile allows user group member",
+			profile:   sre,
+			userID:    "U2",
+			channelID: "C1",
+			allowed:   true,
+		},
+		{
+			name:      "profile denies non-member",
+			profile:   sre,
+			userID:    "U3",
+			channelID: "C1",
+		},
+		{
+			name:      "global deny applies before profile",
+			policy:    domain.AccessPolicy{Deny: domain.AccessRule{Users: []string{"U2"}}},
+			profile:   sre,
+			userID:    "U2",
+			channelID: "C1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			slackRepo.EXPECT().GetUserGroupMembers(gomock.Any(), "SSRE").Return([]string{"U2"}, nil).AnyTimes()
+			slackRepo.EXPECT().GetUserGroupMembers(gomock.Any(), "SBOTS").Return([]string{"U1"}, nil).AnyTimes()
+
+			authorizer := usecase.NewAuthorizer(tt.policy, slackRepo)
+			decision := authorizer.Authorize(context.Background(), tt.userID, tt.channelID, tt.profile)
+			if decision.Allowed != tt.allowed {
+				t.Errorf("expected allowed=%v, got %+v", tt.allowed, decision)
+			}
+		})
+	}
+}
+
+func TestAuthorizer_CachesUserGroups(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	slackRepo.EXPECT().GetUserGroupMembers(gomock.Any(), "SSRE").Return([]string{"U2"}, nil).Times(1)
+
+	authorizer := usecase.NewAuthorizer(domain.AccessPolicy{Allow: domain.AccessRule{UserGroups: []string{"SSRE"}}}, slackRepo)
+	for i := 0; i < 3; i++ {
+		authorizer.Authorize(context.Background(), "U2", "C1", nil)
+	}
+}
+
+func TestAuthorizer_DenyGroupLookupFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	slackRepo.EXPECT().GetUserGroupMembers(gomock.Any(), "SBOTS").Return(nil, errors.New("missing_scope"))
+
+	authorizer := usecase.NewAuthorizer(domain.AccessPolicy{Deny: domain.AccessRule{UserGroups: []string{"SBOTS"}}}, slackRepo)
+	if decision := authorizer.Authorize(context.Background(), "U1", "C1", nil); decision.Allowed {
+		t.Errorf("expected access to be denied when a deny group cannot be checked")
+	}
+}
+
+func TestAuthorizer_ProfileFor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	slackRepo.EXPECT().GetUserGroupMembers(gomock.Any(), "SSRE").Return([]string{"U2"}, nil)
+
+	// Only SRE members get Bash in the profile
+	profile := &domain.Profile{
+		Name:            "sre",
+		DisallowedTools: []string{"Bash"},
+		ToolRules: []domain.ToolRule{
+			{Match: domain.AccessRule{UserGroups: []string{"SSRE"}}, AllowedTools: []string{"Bash"}},
+		},
+	}
+	authorizer := usecase.NewAuthorizer(domain.AccessPolicy{}, slackRepo)
+
+	if got := authorizer.ProfileFor(context.Background(), "U2", "C1", profile); slices.Contains(got.DisallowedTools, "Bash") || !slices.Contains(got.AllowedTools, "Bash") {
+		t.Errorf("expected an SRE member to get Bash, got %+v", got)
+	}
+	if got := authorizer.ProfileFor(context.Background(), "U1", "C1", profile); !slices.Contains(got.DisallowedTools, "Bash") {
+		t.Errorf("expected other users not to get Bash, got %+v", got)
+	}
+}
+
+func TestAuthorizer_ProfileForFailsClosed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	slackRepo.EXPECT().GetUserGroupMembers(gomock.Any(), "SSRE").Return(nil, errors.New("ratelimited")).Times(2)
+
+	// SRE members get Bash, interns must not use WebFetch
+	profile := &domain.Profile{
+		Name:            "sre",
+		DisallowedTools: []string{"Bash"},
+		AllowedTools:    []string{"WebFetch"},
+		ToolRules: []domain.ToolRule{
+			{Match: domain.AccessRule{UserGroups: []string{"SSRE"}}, AllowedTools: []string{"Bash"}},
+			{Match: domain.AccessRule{UserGroups: []string{"SSRE"}}, DisallowedTools: []string{"WebFetch"}},
+		},
+	}
+	authorizer := usecase.NewAuthorizer(domain.AccessPolicy{}, slackRepo)
+
+	// Without the group membership, the restriction applies and nothing is granted
+	got := authorizer.ProfileFor(context.Background(), "U2", "C1", profile)
+	if !slices.Contains(got.DisallowedTools, "Bash") || slices.Contains(got.AllowedTools, "Bash") {
+		t.Errorf("expected Bash not to be granted, got %+v", got)
+	}
+	if !slices.Contains(got.DisallowedTools, "WebFetch") || slices.Contains(got.AllowedTools, "WebFetch") {
+		t.Errorf("expected WebFetch to be denied, got %+v", got)
+	}
+}
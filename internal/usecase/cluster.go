@@ -0,0 +1,286 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// replicaLeaseTTL is how long a replica counts as alive after its last heartbeat
+const replicaLeaseTTL = 30 * time.Second
+
+// Cluster coordinates replicas that share a volume. Each thread is owned by the replica that
+// last ran the agent in it, which keeps the thread's session, and events for the thread that
+// reach another replica are handed off to the owner.
+type Cluster struct {
+	replica   string
+	leases    LeaseRepository
+	handoffs  HandoffRepository
+	threadTTL time.Duration
+
+	mu    sync.Mutex
+	owned map[domain.SessionKey]bool
+}
+
+// NewCluster creates a new Cluster instance for the replica.
+// A thread stays with its owner until it has been idle for threadTTL.
+func NewCluster(replica string, leases LeaseRepository, handoffs HandoffRepository, threadTTL time.Duration) *Cluster {
+	return &Cluster{
+		replica:   replica,
+		leases:    leases,
+		handoffs:  handoffs,
+		threadTTL: threadTTL,
+		owned:     make(map[domain.SessionKey]bool),
+	}
+}
+
+// Run keeps the replica's heartbeat and passes the events handed to it to the handler until ctx ends.
+// Events are passed on in the order they were sent, including those left in the inboxes of replicas that are gone.
+func (c *Cluster) Run(ctx context.Context, handler MessageHandler, interval time.Duration) {
+	beating := make(chan struct{})
+	go func() {
+		defer close(beating)
+		c.heartbeat(ctx)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastAdopted time.Time
+	for {
+		var handoffs []*domain.Handoff
+		if time.Since(lastAdopted) >= replicaLeaseTTL/3 {
+			handoffs = c.adopt(ctx)
+			lastAdopted = time.Now()
+		}
+		received, err := c.handoffs.Receive(ctx, c.replica)
+		if err != nil {
+			log.Printf("Failed to receive handed off events: %v", err)
+		}
+		for _, handoff := range append(handoffs, received...) {
+			c.handle(handler, handoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			<-beating
+			// Let other replicas take over right away
+			if err := c.leases.Release(context.Background(), replicaLease(c.replica), c.replica); err != nil {
+				log.Printf("Failed to release the lease of replica %s: %v", c.replica, err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeat renews the replica's lease until ctx ends
+func (c *Cluster) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(replicaLeaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		if _, err := c.leases.Acquire(ctx, replicaLease(c.replica), c.replica, replicaLeaseTTL); err != nil {
+			log.Printf("Failed to renew the lease of replica %s: %v", c.replica, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// adopt takes the events out of the inboxes of replicas that are gone, so that they are handled
+// here or passed on to the threads' new owners instead of being lost
+func (c *Cluster) adopt(ctx context.Context) []*domain.Handoff {
+	replicas, err := c.handoffs.Replicas(ctx)
+	if err != nil {
+		log.Printf("Failed to list the inboxes of other replicas: %v", err)
+		return nil
+	}
+
+	var adopted []*domain.Handoff
+	for _, replica := range replicas {
+		if replica == c.replica {
+			continue
+		}
+		if alive, err := c.leases.Holder(ctx, replicaLease(replica)); err != nil || alive == replica {
+			continue
+		}
+		handoffs, err := c.handoffs.Receive(ctx, replica)
+		if err != nil {
+			log.Printf("Failed to receive the events handed off to replica %s: %v", replica, err)
+		}
+		if len(handoffs) > 0 {
+			log.Printf("Replica %s is gone; taking over %d handed off events", replica, len(handoffs))
+		}
+		adopted = append(adopted, handoffs...)
+	}
+	return adopted
+}
+
+// handle passes an event handed off by another replica to the handler. It returns once the handler
+// has queued the event, or has finished with it, so that the next event is not queued before it.
+func (c *Cluster) handle(handler MessageHandler, handoff *domain.Handoff) {
+	queued := make(chan struct{})
+	var once sync.Once
+	ctx := context.WithValue(context.Background(), queuedKey{}, func() {
+		once.Do(func() { close(queued) })
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var err error
+		switch {
+		case handoff.Message != nil:
+			err = handler.HandleMessage(ctx, handoff.Message)
+		case handoff.Reaction != nil:
+			err = handler.HandleReaction(ctx, handoff.Reaction)
+		case handoff.Action != nil:
+			err = handler.HandleAction(ctx, handoff.Action)
+		}
+		if err != nil {
+			log.Printf("Error handling handed off event: %v", err)
+		}
+	}()
+
+	select {
+	case <-queued:
+	case <-done:
+	}
+}
+
+// queuedKey is the context key of the function that tells Cluster.handle that an event has been queued
+type queuedKey struct{}
+
+// markQueued tells the replica passing on a handed off event that the event has been queued,
+// so that it may pass on the next one while the agent runs
+func markQueued(ctx context.Context) {
+	if queued, ok := ctx.Value(queuedKey{}).(func()); ok {
+		queued()
+	}
+}
+
+// owner returns the other live replica that owns the thread, or an empty string
+// when the thread is free or owned by this replica
+func (c *Cluster) owner(ctx context.Context, key domain.SessionKey) string {
+	holder, err := c.leases.Holder(ctx, threadLease(key))
+	if err != nil {
+		log.Printf("Failed to look up the owner of thread %s: %v", key, err)
+		return ""
+	}
+	if holder == "" || holder == c.replica {
+		return ""
+	}
+
+	// Take over threads of replicas that are gone
+	alive, err := c.leases.Holder(ctx, replicaLease(holder))
+	if err == nil && alive != holder {
+		log.Printf("Replica %s owning thread %s is gone; taking the thread over", holder, key)
+		if err := c.leases.Release(ctx, threadLease(key), holder); err != nil {
+			log.Printf("Failed to release thread %s: %v", key, err)
+		}
+		return ""
+	}
+	return holder
+}
+
+// locate returns the live replica that owns the thread, which may be this one, or an empty string
+// when no live replica does. Errors count as this replica so that the thread is left alone.
+func (c *Cluster) locate(ctx context.Context, key domain.SessionKey) string {
+	if owner := c.owner(ctx, key); owner != "" {
+		return owner
+	}
+	holder, err := c.leases.Holder(ctx, threadLease(key))
+	if err != nil {
+		log.Printf("Failed to look up the owner of thread %s: %v", key, err)
+		return c.replica
+	}
+	return holder
+}
+
+// claim takes the thread for this replica and returns an empty string, or returns the other replica
+// that owns it. Errors leave the thread with this replica so that events are not lost.
+func (c *Cluster) claim(ctx context.Context, key domain.SessionKey) string {
+	if owner := c.owner(ctx, key); owner != "" {
+		return owner
+	}
+	holder, err := c.leases.Acquire(ctx, threadLease(key), c.replica, c.threadTTL)
+	if err != nil {
+		log.Printf("Failed to claim thread %s: %v", key, err)
+		return ""
+	}
+	if holder != c.replica {
+		return holder
+	}
+
+	c.mu.Lock()
+	c.owned[key] = true
+	c.mu.Unlock()
+	return ""
+}
+
+// hold renews the thread's lease while the replica works on it, until the returned function is called
+func (c *Cluster) hold(key domain.SessionKey) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(c.threadTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.renew(key)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		// The thread stays with this replica for threadTTL after the last run
+		c.renew(key)
+	}
+}
+
+// renew extends the thread's lease
+func (c *Cluster) renew(key domain.SessionKey) {
+	if _, err := c.leases.Acquire(context.Background(), threadLease(key), c.replica, c.threadTTL); err != nil {
+		log.Printf("Failed to renew thread %s: %v", key, err)
+	}
+}
+
+// handOff passes the event on to the replica that owns the thread
+func (c *Cluster) handOff(ctx context.Context, owner string, handoff *domain.Handoff) error {
+	return c.handoffs.Send(ctx, owner, handoff)
+}
+
+// releaseAll gives up the threads owned by this replica, e.g. when it shuts down
+func (c *Cluster) releaseAll(ctx context.Context) {
+	c.mu.Lock()
+	keys := make([]domain.SessionKey, 0, len(c.owned))
+	for key := range c.owned {
+		keys = append(keys, key)
+	}
+	c.owned = make(map[domain.SessionKey]bool)
+	c.mu.Unlock()
+
+	for _, key := range keys {
+		if err := c.leases.Release(ctx, threadLease(key), c.replica); err != nil {
+			log.Printf("Failed to release thread %s: %v", key, err)
+		}
+	}
+}
+
+// threadLease returns the name of the lease on a thread
+func threadLease(key domain.SessionKey) string {
+	return "thread/" + key.String()
+}
+
+// replicaLease returns the name of a replica's heartbeat lease
+func replicaLease(replica string) string {
+	return "replica/" + replica
+}
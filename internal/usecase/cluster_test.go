@@ -0,0 +1,181 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCluster_HandsOffThreadsOwnedByOtherReplicas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	leases := mocks.NewMockLeaseRepository(ctrl)
+	handoffs := mocks.NewMockHandoffRepository(ctrl)
+	cluster := usecase.NewCluster("a", leases, handoffs, time.Minute)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"), usecase.WithCluster(cluster))
+
+	msg := domain.NewMessage("1.2", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	reaction := &domain.Reaction{UserID: "U123", ChannelID: "C123", ItemTS: "1.1", Name: "octagonal_sign"}
+	action := &domain.Action{UserID: "U123", ChannelID: "C123", ThreadTS: "1.1", MessageTS: "1.3", Control: domain.ControlStop}
+
+	// Replica b runs the thread and is alive; no agent runs here
+	leases.EXPECT().Holder(gomock.Any(), "thread/"+msg.SessionKey().String()).Return("b", nil).Times(3)
+	leases.EXPECT().Holder(gomock.Any(), "replica/b").Return("b", nil).Times(3)
+	handoffs.EXPECT().Send(gomock.Any(), "b", &domain.Handoff{Message: msg}).Return(nil)
+	handoffs.EXPECT().Send(gomock.Any(), "b", &domain.Handoff{Reaction: reaction}).Return(nil)
+	handoffs.EXPECT().Send(gomock.Any(), "b", &domain.Handoff{Action: action}).Return(nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := handler.HandleReaction(context.Background(), reaction); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := handler.HandleAction(context.Background(), action); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCluster_TakesOverThreadsOfGoneReplicas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	leases := mocks.NewMockLeaseRepository(ctrl)
+	handoffs := mocks.NewMockHandoffRepository(ctrl)
+	cluster := usecase.NewCluster("a", leases, handoffs, time.Minute)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"), usecase.WithCluster(cluster))
+
+	msg := domain.NewMessage("1.2", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	thread := "thread/" + msg.SessionKey().String()
+
+	// Replica b stopped sending heartbeats, so its thread is released and claimed
+	gomock.InOrder(
+		leases.EXPECT().Holder(gomock.Any(), thread).Return("b", nil),
+		leases.EXPECT().Holder(gomock.Any(), "replica/b").Return("", nil),
+		leases.EXPECT().Release(gomock.Any(), thread, "b").Return(nil),
+		leases.EXPECT().Holder(gomock.Any(), thread).Return("", nil),
+		leases.EXPECT().Acquire(gomock.Any(), thread, "a", time.Minute).Return("a", nil),
+	)
+	// The lease is renewed after the run and released on shutdown
+	leases.EXPECT().Acquire(gomock.Any(), thread, "a", time.Minute).Return("a", nil)
+	leases.EXPECT().Release(gomock.Any(), thread, "a").Return(nil)
+
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(domain.NewAgentResult("done", nil), nil)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "done", "1.1").Return("", nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := handler.Shutdown(context.Background(), time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCluster_RunHandlesHandedOffEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	leases := mocks.NewMockLeaseRepository(ctrl)
+	handoffs := mocks.NewMockHandoffRepository(ctrl)
+	handler := mocks.NewMockMessageHandler(ctrl)
+	cluster := usecase.NewCluster("a", leases, handoffs, time.Minute)
+
+	msg := domain.NewMessage("1.2", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	handled := make(chan struct{})
+	leases.EXPECT().Acquire(gomock.Any(), "replica/a", "a", gomock.Any()).Return("a", nil)
+	handoffs.EXPECT().Receive(gomock.Any(), "a").Return([]*domain.Handoff{{Message: msg}}, nil)
+	handoffs.EXPECT().Receive(gomock.Any(), "a").Return(nil, nil).AnyTimes()
+	handoffs.EXPECT().Replicas(gomock.Any()).Return([]string{"a"}, nil).AnyTimes()
+	handler.EXPECT().HandleMessage(gomock.Any(), msg).DoAndReturn(func(ctx context.Context, message *domain.Message) error {
+		close(handled)
+		return nil
+	})
+	// Leaving releases the replica's lease so that others take over right away
+	leases.EXPECT().Release(gomock.Any(), "replica/a", "a").Return(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cluster.Run(ctx, handler, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the handed off message to be handled")
+	}
+	cancel()
+	<-done
+}
+
+func TestCluster_RunTakesOverTheInboxOfGoneReplicasInOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	leases := mocks.NewMockLeaseRepository(ctrl)
+	handoffs := mocks.NewMockHandoffRepository(ctrl)
+	cluster := usecase.NewCluster("a", leases, handoffs, time.Minute)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"), usecase.WithCluster(cluster))
+
+	first := domain.NewMessage("1.2", "U123", "C123", "<@UBOT> first", "1.1", time.Now())
+	second := domain.NewMessage("1.3", "U123", "C123", "<@UBOT> second", "1.1", time.Now())
+	thread := "thread/" + first.SessionKey().String()
+
+	// Replica b is gone and left two messages for the same thread in its inbox
+	handoffs.EXPECT().Replicas(gomock.Any()).Return([]string{"a", "b"}, nil).AnyTimes()
+	leases.EXPECT().Holder(gomock.Any(), "replica/b").Return("", nil).AnyTimes()
+	handoffs.EXPECT().Receive(gomock.Any(), "b").Return([]*domain.Handoff{{Message: first}, {Message: second}}, nil)
+	handoffs.EXPECT().Receive(gomock.Any(), "b").Return(nil, nil).AnyTimes()
+	handoffs.EXPECT().Receive(gomock.Any(), "a").Return(nil, nil).AnyTimes()
+	leases.EXPECT().Acquire(gomock.Any(), "replica/a", "a", gomock.Any()).Return("a", nil).AnyTimes()
+	leases.EXPECT().Release(gomock.Any(), "replica/a", "a").Return(nil)
+	leases.EXPECT().Holder(gomock.Any(), thread).Return("", nil).AnyTimes()
+	leases.EXPECT().Acquire(gomock.Any(), thread, "a", time.Minute).Return("a", nil).AnyTimes()
+
+	// The second message is queued behind the first one instead of racing it
+	queued := make(chan struct{})
+	answered := make(chan struct{})
+	answers := 0
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").DoAndReturn(
+		func(_ context.Context, _, text, _ string) (string, error) {
+			if strings.HasPrefix(text, "⏳") {
+				close(queued)
+			} else if answers++; answers == 2 {
+				close(answered)
+			}
+			return "", nil
+		}).Times(3)
+	var prompts []string
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+			prompts = append(prompts, message.Text)
+			if len(prompts) == 1 {
+				<-queued
+			}
+			return domain.NewAgentResult("done", nil), nil
+		}).Times(2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		cluster.Run(ctx, handler, 10*time.Millisecond)
+		close(done)
+	}()
+
+	select {
+	case <-answered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected both handed off messages to be answered")
+	}
+	cancel()
+	<-done
+	if len(prompts) != 2 || prompts[0] != first.Text || prompts[1] != second.Text {
+		t.Errorf("expected the messages to run in order, got %v", prompts)
+	}
+}
@@ -0,0 +1,279 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// commandHelp is shown by "/agent help" and for unknown subcommands
+const commandHelp = "*Usage*\n" +
+	"• `%[1]s ask <prompt>` starts a new thread and asks the agent in a fresh session\n" +
+	"• `%[1]s status` shows the agents that are running\n" +
+	"• `%[1]s reset` forgets the conversations in this channel\n" +
+	"• `%[1]s help` shows this message"
+
+// commandHandlerImpl implements the CommandHandler interface
+type commandHandlerImpl struct {
+	slackRepo  SlackRepository
+	messages   MessageHandler
+	bot        *domain.Bot
+	pool       *AgentPool
+	sessions   SessionRepository
+	workspaces WorkspaceRepository
+	agentRepo  AgentRepository
+	authorizer *Authorizer
+	profiles   *ProfileResolver
+	cluster    *Cluster
+}
+
+// CommandOption configures optional behaviour of the command handler
+type CommandOption func(*commandHandlerImpl)
+
+// WithCommandAgentPool reports the pool's usage in "/agent status"
+func WithCommandAgentPool(pool *AgentPool) CommandOption {
+	return func(h *commandHandlerImpl) {
+		h.pool = pool
+	}
+}
+
+// WithCommandSessions lets "/agent status" and "/agent reset" see and remove sessions
+func WithCommandSessions(sessions SessionRepository, workspaces WorkspaceRepository) CommandOption {
+	return func(h *commandHandlerImpl) {
+		h.sessions = sessions
+		h.workspaces = workspaces
+	}
+}
+
+// WithCommandAgentRepository lets "/agent reset" clear the conversations the agent backend remembers
+func WithCommandAgentRepository(agentRepo AgentRepository) CommandOption {
+	return func(h *commandHandlerImpl) {
+		h.agentRepo = agentRepo
+	}
+}
+
+// WithCommandAuthorizer restricts "/agent ask" and "/agent reset" to users who may invoke the agent
+func WithCommandAuthorizer(authorizer *Authorizer, profiles *ProfileResolver) CommandOption {
+	return func(h *commandHandlerImpl) {
+		h.authorizer = authorizer
+		h.profiles = profiles
+	}
+}
+
+// WithCommandCluster makes "/agent status" and "/agent reset" aware of the other replicas:
+// threads that another replica owns are left to it, and runs of replicas that are gone count as ended
+func WithCommandCluster(cluster *Cluster) CommandOption {
+	return func(h *commandHandlerImpl) {
+		h.cluster = cluster
+	}
+}
+
+// NewCommandHandler creates a new CommandHandler instance.
+// Prompts from "/agent ask" are passed to the message handler so that they are
+// authorized, rate limited and queued like mentions.
+func NewCommandHandler(slackRepo SlackRepository, messages MessageHandler, bot *domain.Bot, opts ...CommandOption) CommandHandler {
+	h := &commandHandlerImpl{
+		slackRepo: slackRepo,
+		messages:  messages,
+		bot:       bot,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// HandleCommand runs a slash command; replies only the invoking user needs are ephemeral
+func (h *commandHandlerImpl) HandleCommand(ctx context.Context, command *domain.SlashCommand) error {
+	name, args := command.Subcommand()
+	log.Printf("Handling %s %s from user %s in channel %s", command.Command, name, command.UserID, command.ChannelID)
+
+	switch name {
+	case "ask":
+		return h.ask(ctx, command, args)
+	case "status":
+		return h.status(ctx, command)
+	case "reset":
+		return h.reset(ctx, command)
+	case "", "help":
+		return h.respond(ctx, command, fmt.Sprintf(commandHelp, command.Command))
+	default:
+		return h.respond(ctx, command, fmt.Sprintf("Unknown command `%s`.\n\n"+commandHelp, name, command.Command))
+	}
+}
+
+// ask posts the prompt as a new thread and runs the agent in it
+func (h *commandHandlerImpl) ask(ctx context.Context, command *domain.SlashCommand, prompt string) error {
+	if prompt == "" {
+		return h.respond(ctx, command, fmt.Sprintf("Please tell me what to ask, e.g. `%s ask why is the build failing?`", command.Command))
+	}
+
+	// Check before posting so that denied users don't leave a thread behind
+	if !h.authorize(ctx, command) {
+		return h.respond(ctx, command, "🙇 Sorry, you don't have access to me here. Please ask a workspace admin if you need it.")
+	}
+
+	// A new thread gives the prompt its own session
+	ts, err := h.slackRepo.PostMessage(ctx, command.ChannelID, fmt.Sprintf("❓ <@%s> asked: %s", command.UserID, prompt), "")
+	if err != nil {
+		log.Printf("Error starting thread for %s ask: %v", command.Command, err)
+		return h.respond(ctx, command, "🙇 I couldn't start a thread here. Please invite me to the channel first.")
+	}
+
+	message := domain.NewMessage(ts, command.UserID, command.ChannelID, fmt.Sprintf("<@%s> %s", h.bot.UserID, prompt), ts, time.Now())
+	message.TeamID = command.TeamID
+	return h.messages.HandleMessage(ctx, message)
+}
+
+// status lists the agents that are running
+func (h *commandHandlerImpl) status(ctx context.Context, command *domain.SlashCommand) error {
+	var lines []string
+	if h.pool != nil && h.cluster != nil {
+		lines = append(lines, fmt.Sprintf("🤖 %d agents running on replica %s, %d waiting for a free slot.", h.pool.Active(), h.cluster.replica, h.pool.QueueDepth()))
+	} else if h.pool != nil {
+		lines = append(lines, fmt.Sprintf("🤖 %d agents running, %d waiting for a free slot.", h.pool.Active(), h.pool.QueueDepth()))
+	}
+
+	if h.sessions != nil {
+		sessions, err := h.sessions.List(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		var running []*domain.Session
+		owners := make(map[domain.SessionKey]string)
+		for _, session := range sessions {
+			if session.Key.ChannelID != command.ChannelID || session.Status != domain.SessionRunning {
+				continue
+			}
+			if h.cluster != nil {
+				owner := h.cluster.locate(ctx, session.Key)
+				if owner == "" {
+					// The replica that ran it is gone
+					continue
+				}
+				owners[session.Key] = owner
+			}
+			running = append(running, session)
+		}
+		sort.Slice(running, func(i, j int) bool { return running[i].LastActivity.Before(running[j].LastActivity) })
+
+		if len(running) == 0 {
+			lines = append(lines, "Nothing is running in this channel.")
+		} else {
+			lines = append(lines, "Running in this channel:")
+		}
+		for _, session := range running {
+			line := fmt.Sprintf("• thread %s, started %s ago (%d messages)",
+				session.Key.ThreadTS, time.Since(session.LastActivity).Round(time.Second), session.MessageCount)
+			if owner := owners[session.Key]; h.cluster != nil && owner != h.cluster.replica {
+				line += " on replica " + owner
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	if len(lines) == 0 {
+		lines = append(lines, "No status is available.")
+	}
+	return h.respond(ctx, command, strings.Join(lines, "\n"))
+}
+
+// reset removes the idle sessions of the channel so that the next mention starts afresh
+func (h *commandHandlerImpl) reset(ctx context.Context, command *domain.SlashCommand) error {
+	if h.sessions == nil {
+		return h.respond(ctx, command, "Sessions are not stored, so there is nothing to reset.")
+	}
+	if !h.authorize(ctx, command) {
+		return h.respond(ctx, command, "🙇 Sorry, you don't have access to me here. Please ask a workspace admin if you need it.")
+	}
+
+	sessions, err := h.sessions.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	removed, running, elsewhere := 0, 0, 0
+	var errs []error
+	for _, session := range sessions {
+		if session.Key.ChannelID != command.ChannelID || session.Key.TeamID != command.TeamID {
+			continue
+		}
+		if h.cluster != nil {
+			switch owner := h.cluster.locate(ctx, session.Key); owner {
+			case "":
+				// The run ended with a replica that is gone
+				session.Abandon()
+			case h.cluster.replica:
+			default:
+				// The owner keeps state of the thread that only it can forget
+				elsewhere++
+				continue
+			}
+		}
+		if session.Status == domain.SessionRunning {
+			running++
+			continue
+		}
+		if h.workspaces != nil {
+			if err := h.workspaces.Remove(ctx, session.Key); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+		if err := h.sessions.Delete(ctx, session.Key); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if h.agentRepo != nil {
+			h.agentRepo.ForgetSession(session.Key)
+		}
+		removed++
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.Printf("Error resetting sessions in channel %s: %v", command.ChannelID, err)
+	}
+	log.Printf("User %s reset %d sessions in channel %s", command.UserID, removed, command.ChannelID)
+
+	text := fmt.Sprintf("🧹 Cleared %d conversations in this channel.", removed)
+	if running > 0 {
+		text += fmt.Sprintf(" %d running ones were kept; stop them first to clear them.", running)
+	}
+	if elsewhere > 0 {
+		text += fmt.Sprintf(" %d were kept because other replicas are handling them; try again later.", elsewhere)
+	}
+	if len(errs) > 0 {
+		text += fmt.Sprintf(" %d could not be cleared.", len(errs))
+	}
+	return h.respond(ctx, command, text)
+}
+
+// authorize reports whether the user may use the agent in the channel
+func (h *commandHandlerImpl) authorize(ctx context.Context, command *domain.SlashCommand) bool {
+	if h.authorizer == nil {
+		return true
+	}
+	var profile *domain.Profile
+	if h.profiles != nil {
+		profile = h.profiles.Resolve(ctx, command.ChannelID)
+	}
+	return h.authorizer.Authorize(ctx, command.UserID, command.ChannelID, profile).Allowed
+}
+
+// respond posts a reply only the invoking user can see.
+// The response URL also works where the bot is not a member; ephemeral messages are the fallback.
+func (h *commandHandlerImpl) respond(ctx context.Context, command *domain.SlashCommand, text string) error {
+	if command.ResponseURL != "" {
+		err := h.slackRepo.RespondToCommand(ctx, command.ResponseURL, text)
+		if err == nil {
+			return nil
+		}
+		log.Printf("Error responding to %s through its response URL: %v", command.Command, err)
+	}
+	return h.slackRepo.PostEphemeral(ctx, command.ChannelID, command.UserID, text, "")
+}
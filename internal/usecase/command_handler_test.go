@@ -0,0 +1,254 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func newCommand(text string) *domain.SlashCommand {
+	return &domain.SlashCommand{TeamID: "T1", ChannelID: "C123", UserID: "U123", Command: "/agent", Text: text}
+}
+
+func TestHandleCommand_Ask(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	messages := mocks.NewMockMessageHandler(ctrl)
+	handler := usecase.NewCommandHandler(slackRepo, messages, domain.NewBot("UBOT"))
+
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "❓ <@U123> asked: why is CI red?", "").Return("1.5", nil)
+	messages.EXPECT().HandleMessage(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, msg *domain.Message) error {
+		if msg.ID != "1.5" || msg.ThreadTS != "1.5" || msg.TeamID != "T1" || msg.UserID != "U123" {
+			t.Errorf("unexpected message: %+v", msg)
+		}
+		if msg.Text != "<@UBOT> why is CI red?" {
+			t.Errorf("expected prompt to mention the bot, got %q", msg.Text)
+		}
+		return nil
+	})
+
+	if err := handler.HandleCommand(context.Background(), newCommand("ask why is CI red?")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleCommand_AskErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		postErr  error
+		wantText string
+	}{
+		{name: "missing prompt", text: "ask", wantText: "Please tell me what to ask"},
+		{name: "not in channel", text: "ask hello", postErr: errors.New("not_in_channel"), wantText: "invite me"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			messages := mocks.NewMockMessageHandler(ctrl)
+			handler := usecase.NewCommandHandler(slackRepo, messages, domain.NewBot("UBOT"))
+
+			if tt.postErr != nil {
+				slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "").Return("", tt.postErr)
+			}
+			slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "").DoAndReturn(
+				func(_ context.Context, _, _, text, _ string) error {
+					if !strings.Contains(text, tt.wantText) {
+						t.Errorf("expected reply to contain %q, got %q", tt.wantText, text)
+					}
+					return nil
+				})
+
+			if err := handler.HandleCommand(context.Background(), newCommand(tt.text)); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleCommand_RespondsThroughResponseURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		respondErr  error
+		ephemeral   bool
+		expectReply string
+	}{
+		{name: "response url", expectReply: "invite me"},
+		{name: "falls back to an ephemeral message", respondErr: errors.New("expired_url"), ephemeral: true, expectReply: "invite me"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			handler := usecase.NewCommandHandler(slackRepo, mocks.NewMockMessageHandler(ctrl), domain.NewBot("UBOT"))
+
+			// The bot is not a member of the channel, so only the response URL reaches the user
+			command := newCommand("ask hello")
+			command.ResponseURL = "https://hooks.slack.com/commands/T1/1/abc"
+			slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "").Return("", errors.New("not_in_channel"))
+			slackRepo.EXPECT().RespondToCommand(gomock.Any(), command.ResponseURL, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _, text string) error {
+					if !strings.Contains(text, tt.expectReply) {
+						t.Errorf("expected reply to contain %q, got %q", tt.expectReply, text)
+					}
+					return tt.respondErr
+				})
+			if tt.ephemeral {
+				slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "").Return(nil)
+			}
+
+			if err := handler.HandleCommand(context.Background(), command); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleCommand_Status(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	sessionRepo := mocks.NewMockSessionRepository(ctrl)
+	handler := usecase.NewCommandHandler(slackRepo, mocks.NewMockMessageHandler(ctrl), domain.NewBot("UBOT"),
+		usecase.WithCommandAgentPool(usecase.NewAgentPool(2, 0)),
+		usecase.WithCommandSessions(sessionRepo, nil),
+	)
+
+	running := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "1.1"}, time.Now())
+	running.Start(time.Now())
+	idle := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "2.2"}, time.Now())
+	elsewhere := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C999", ThreadTS: "3.3"}, time.Now())
+	elsewhere.Start(time.Now())
+	sessionRepo.EXPECT().List(gomock.Any()).Return([]*domain.Session{running, idle, elsewhere}, nil)
+
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "").DoAndReturn(
+		func(_ context.Context, _, _, text, _ string) error {
+			if !strings.Contains(text, "0 agents running") || !strings.Contains(text, "thread 1.1") {
+				t.Errorf("unexpected status: %q", text)
+			}
+			if strings.Contains(text, "2.2") || strings.Contains(text, "3.3") {
+				t.Errorf("expected only running sessions of the channel, got %q", text)
+			}
+			return nil
+		})
+
+	if err := handler.HandleCommand(context.Background(), newCommand("status")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleCommand_Reset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	sessionRepo := mocks.NewMockSessionRepository(ctrl)
+	workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewCommandHandler(slackRepo, mocks.NewMockMessageHandler(ctrl), domain.NewBot("UBOT"),
+		usecase.WithCommandSessions(sessionRepo, workspaces),
+		usecase.WithCommandAgentRepository(agentRepo),
+	)
+
+	idleKey := domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "1.1"}
+	running := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "2.2"}, time.Now())
+	running.Start(time.Now())
+	elsewhere := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C999", ThreadTS: "3.3"}, time.Now())
+	sessionRepo.EXPECT().List(gomock.Any()).Return([]*domain.Session{domain.NewSession(idleKey, time.Now()), running, elsewhere}, nil)
+
+	workspaces.EXPECT().Remove(gomock.Any(), idleKey).Return(nil)
+	sessionRepo.EXPECT().Delete(gomock.Any(), idleKey).Return(nil)
+	agentRepo.EXPECT().ForgetSession(idleKey)
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "").DoAndReturn(
+		func(_ context.Context, _, _, text, _ string) error {
+			if !strings.Contains(text, "Cleared 1 conversations") || !strings.Contains(text, "1 running ones were kept") {
+				t.Errorf("unexpected reply: %q", text)
+			}
+			return nil
+		})
+
+	if err := handler.HandleCommand(context.Background(), newCommand("reset")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleCommand_ResetInCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	sessionRepo := mocks.NewMockSessionRepository(ctrl)
+	leases := mocks.NewMockLeaseRepository(ctrl)
+	handler := usecase.NewCommandHandler(slackRepo, mocks.NewMockMessageHandler(ctrl), domain.NewBot("UBOT"),
+		usecase.WithCommandSessions(sessionRepo, nil),
+		usecase.WithCommandCluster(usecase.NewCluster("a", leases, mocks.NewMockHandoffRepository(ctrl), time.Minute)),
+	)
+
+	owned := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "1.1"}, time.Now())
+	elsewhere := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "2.2"}, time.Now())
+	orphaned := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "3.3"}, time.Now())
+	orphaned.Start(time.Now())
+	sessionRepo.EXPECT().List(gomock.Any()).Return([]*domain.Session{owned, elsewhere, orphaned}, nil)
+
+	// This replica owns the first thread and replica b the second; replica c ran the third and is gone
+	leases.EXPECT().Holder(gomock.Any(), "thread/T1/C123/1.1").Return("a", nil).Times(2)
+	leases.EXPECT().Holder(gomock.Any(), "thread/T1/C123/2.2").Return("b", nil)
+	leases.EXPECT().Holder(gomock.Any(), "replica/b").Return("b", nil)
+	gomock.InOrder(
+		leases.EXPECT().Holder(gomock.Any(), "thread/T1/C123/3.3").Return("c", nil),
+		leases.EXPECT().Holder(gomock.Any(), "replica/c").Return("", nil),
+		leases.EXPECT().Release(gomock.Any(), "thread/T1/C123/3.3", "c").Return(nil),
+		leases.EXPECT().Holder(gomock.Any(), "thread/T1/C123/3.3").Return("", nil),
+	)
+
+	sessionRepo.EXPECT().Delete(gomock.Any(), owned.Key).Return(nil)
+	sessionRepo.EXPECT().Delete(gomock.Any(), orphaned.Key).Return(nil)
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "").DoAndReturn(
+		func(_ context.Context, _, _, text, _ string) error {
+			if !strings.Contains(text, "Cleared 2 conversations") || !strings.Contains(text, "1 were kept because other replicas") {
+				t.Errorf("unexpected reply: %q", text)
+			}
+			return nil
+		})
+
+	if err := handler.HandleCommand(context.Background(), newCommand("reset")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleCommand_Help(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		wantText string
+	}{
+		{name: "no subcommand", text: "", wantText: "`/agent ask <prompt>`"},
+		{name: "help", text: "help", wantText: "`/agent status`"},
+		{name: "unknown", text: "dance", wantText: "Unknown command `dance`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			handler := usecase.NewCommandHandler(slackRepo, mocks.NewMockMessageHandler(ctrl), domain.NewBot("UBOT"))
+
+			slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "").DoAndReturn(
+				func(_ context.Context, _, _, text, _ string) error {
+					if !strings.Contains(text, tt.wantText) {
+						t.Errorf("expected reply to contain %q, got %q", tt.wantText, text)
+					}
+					return nil
+				})
+
+			if err := handler.HandleCommand(context.Background(), newCommand(tt.text)); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
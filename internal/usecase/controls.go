@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// regeneratePrefix asks the agent for a new answer to a prompt it has already answered
+const regeneratePrefix = "Please answer this again with a fresh attempt:\n\n"
+
+// continuePrompt asks the agent to carry on where its last answer stopped
+const continuePrompt = "Please continue."
+
+// HandleAction handles clicks on the bot's controls
+func (h *messageHandlerImpl) HandleAction(ctx context.Context, action *domain.Action) error {
+	if h.handOff(ctx, action.SessionKey(), &domain.Handoff{Action: action}) {
+		return nil
+	}
+	if !h.authorize(ctx, action.UserID, action.ChannelID) {
+		return h.slackRepo.PostEphemeral(ctx, action.ChannelID, action.UserID,
+			"🙇 Sorry, you don't have access to me here. Please ask a workspace admin if you need it.",
+			action.ThreadTS)
+	}
+
+	switch action.Control {
+	case domain.ControlStop:
+		if !h.agentRepo.CancelSession(action.SessionKey()) {
+			return nil
+		}
+		dropped := h.queue.clear(action.SessionKey().String())
+		log.Printf("Cancelled agent in thread %s by button from user %s (dropped %d queued messages)", action.ThreadTS, action.UserID, dropped)
+		return h.postCancelled(ctx, action.ChannelID, action.ThreadTS)
+
+	case domain.ControlRegenerate, domain.ControlContinue:
+		message, err := h.followUp(ctx, action)
+		if err != nil {
+			return err
+		}
+		if message == nil {
+			return h.slackRepo.PostEphemeral(ctx, action.ChannelID, action.UserID,
+				"🙇 I don't remember the request in this thread anymore. Please mention me with it again.",
+				action.ThreadTS)
+		}
+		log.Printf("User %s clicked %s in thread %s", action.UserID, action.Control, action.ThreadTS)
+		return h.submit(ctx, message)
+
+	case domain.ControlGood, domain.ControlBad:
+		rating, _ := domain.RatingFromControl(action.Control)
+		if err := h.recordFeedback(ctx, action.SessionKey(), action.AnswerTS, action.UserID, rating, domain.FeedbackButton); err != nil {
+			return err
+		}
+		return h.slackRepo.PostEphemeral(ctx, action.ChannelID, action.UserID, "🙏 Thanks for the feedback!", action.ThreadTS)
+
+	default:
+		log.Printf("Ignoring unknown action %q from user %s", action.Control, action.UserID)
+		return nil
+	}
+}
+
+// followUp builds the prompt for a regenerate or continue click, or returns nil if the
+// prompt of the answer to regenerate is not known
+func (h *messageHandlerImpl) followUp(ctx context.Context, action *domain.Action) (*domain.Message, error) {
+	text := continuePrompt
+	if action.Control == domain.ControlRegenerate {
+		if h.sessions == nil {
+			return nil, nil
+		}
+		session, err := h.sessions.Get(ctx, action.SessionKey())
+		if errors.Is(err, domain.ErrSessionNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session: %w", err)
+		}
+		// Regenerate the answer the button belongs to, which need not be the latest one
+		answer := session.FindAnswer(action.AnswerTS)
+		if answer == nil || answer.Prompt == "" {
+			return nil, nil
+		}
+		// Regenerating a regenerated answer must not stack the prefix
+		text = regeneratePrefix + strings.TrimPrefix(answer.Prompt, regeneratePrefix)
+	}
+
+	message := domain.NewMessage(action.MessageTS, action.UserID, action.ChannelID, text, action.ThreadTS, time.Now())
+	message.TeamID = action.TeamID
+	return message, nil
+}
+
+// postControls posts buttons for the controls to the thread and returns the message timestamp.
+// Nothing is posted when controls are disabled; failures are only logged.
+func (h *messageHandlerImpl) postControls(ctx context.Context, message *domain.Message, text string, controls ...domain.Control) string {
+	if !h.controls {
+		return ""
+	}
+	ts, err := h.slackRepo.PostControls(context.WithoutCancel(ctx), message.ChannelID, message.ThreadTS, text, controls)
+	if err != nil {
+		log.Printf("Error posting controls: %v", err)
+		return ""
+	}
+	return ts
+}
+
+// answered remembers the answer in the session and moves the controls from the previous answer to it
+func (h *messageHandlerImpl) answered(ctx context.Context, session *domain.Session, answer domain.Answer, text string, controls ...domain.Control) {
+	if answer.TS() == "" {
+		return
+	}
+	session.AddAnswer(answer)
+	if h.controls {
+		ctx = context.WithoutCancel(ctx)
+		// Only the latest answer shows controls
+		if session.ControlsTS != "" {
+			if err := h.slackRepo.SetControls(ctx, session.Key.ChannelID, session.Key.ThreadTS, session.ControlsTS, session.ControlsText, nil); err != nil {
+				log.Printf("Error removing controls: %v", err)
+			}
+			session.ControlsTS, session.ControlsText = "", ""
+		}
+		if err := h.slackRepo.SetControls(ctx, session.Key.ChannelID, session.Key.ThreadTS, answer.TS(), text, controls); err != nil {
+			log.Printf("Error adding controls: %v", err)
+		} else {
+			session.ControlsTS, session.ControlsText = answer.TS(), text
+		}
+	}
+	h.saveSession(ctx, session)
+}
+
+// removeControls deletes a message posted by postControls
+func (h *messageHandlerImpl) removeControls(ctx context.Context, channelID, ts string) {
+	if !h.controls || ts == "" {
+		return
+	}
+	if err := h.slackRepo.DeleteMessage(context.WithoutCancel(ctx), channelID, ts); err != nil {
+		log.Printf("Error removing controls: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func newAction(control domain.Control) *domain.Action {
+	return &domain.Action{TeamID: "T1", UserID: "U123", ChannelID: "C123", ThreadTS: "1.1", MessageTS: "1.9", AnswerTS: "1.9", Control: control}
+}
+
+func TestHandleMessage_Controls(t *testing.T) {
+	tests := []struct {
+		name         string
+		result       *domain.AgentResult
+		wantTS       string
+		wantText     string
+		wantControls []domain.Control
+	}{
+		{
+			name:         "answer",
+			result:       domain.NewAgentResult("hi", nil),
+			wantTS:       "1.3",
+			wantText:     "hi",
+			wantControls: []domain.Control{domain.ControlRegenerate, domain.ControlContinue, domain.ControlGood, domain.ControlBad},
+		},
+		{
+			name:         "streamed answer",
+			result:       &domain.AgentResult{Response: "long", Posted: true, PostedTS: []string{"1.5", "1.6"}, PostedText: "the end"},
+			wantTS:       "1.6",
+			wantText:     "the end",
+			wantControls: []domain.Control{domain.ControlRegenerate, domain.ControlContinue, domain.ControlGood, domain.ControlBad},
+		},
+		{
+			name:         "error",
+			result:       domain.NewAgentResult("", &domain.AgentError{Kind: domain.AgentErrorExit, Message: "boom"}),
+			wantTS:       "1.3",
+			wantControls: []domain.Control{domain.ControlRegenerate},
+		},
+		{
+			name:   "cancelled",
+			result: domain.NewAgentResult("", domain.ErrAgentCancelled),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			agentRepo := mocks.NewMockAgentRepository(ctrl)
+			handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"), usecase.WithControls(true))
+
+			msg := domain.NewMessage("1.1", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+			gomock.InOrder(
+				slackRepo.EXPECT().PostControls(gomock.Any(), "C123", "1.1", gomock.Any(), []domain.Control{domain.ControlStop}).Return("1.2", nil),
+				agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(tt.result, nil),
+				slackRepo.EXPECT().DeleteMessage(gomock.Any(), "C123", "1.2").Return(nil),
+			)
+			if !tt.result.IsCancelled() && !tt.result.Posted {
+				slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").Return("1.3", nil)
+			}
+			if tt.wantControls != nil {
+				// The controls go on the last message of the answer
+				slackRepo.EXPECT().SetControls(gomock.Any(), "C123", "1.1", tt.wantTS, gomock.Any(), tt.wantControls).DoAndReturn(
+					func(_ context.Context, _, _, _, text string, _ []domain.Control) error {
+						if tt.wantText != "" && text != tt.wantText {
+							t.Errorf("expected the answer's text, got %q", text)
+						}
+						return nil
+					})
+			}
+
+			if err := handler.HandleMessage(context.Background(), msg); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleMessage_MovesControlsToTheNewAnswer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"), usecase.WithControls(true),
+		usecase.WithSessionRepository(sessions),
+	)
+
+	msg := domain.NewMessage("1.7", "U123", "C123", "<@UBOT> and now?", "1.1", time.Now())
+	stored := domain.NewSession(msg.SessionKey(), time.Now())
+	stored.AddAnswer(domain.Answer{MessageTS: []string{"1.2"}, Prompt: "<@UBOT> hello"})
+	stored.ControlsTS, stored.ControlsText = "1.2", "hi"
+	stored.Workdir = "/work"
+	sessions.EXPECT().Get(gomock.Any(), msg.SessionKey()).Return(stored, nil)
+	sessions.EXPECT().Save(gomock.Any(), stored).Return(nil).Times(3)
+
+	slackRepo.EXPECT().PostControls(gomock.Any(), "C123", "1.1", gomock.Any(), gomock.Any()).Return("", nil)
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.NewAgentResult("again", nil), nil)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "again", "1.1").Return("1.8", nil)
+	gomock.InOrder(
+		slackRepo.EXPECT().SetControls(gomock.Any(), "C123", "1.1", "1.2", "hi", nil).Return(nil),
+		slackRepo.EXPECT().SetControls(gomock.Any(), "C123", "1.1", "1.8", "again", gomock.Any()).Return(nil),
+	)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if stored.ControlsTS != "1.8" || stored.ControlsText != "again" {
+		t.Errorf("expected the new answer to carry the controls, got %q", stored.ControlsTS)
+	}
+	if answer := stored.FindAnswer("1.8"); answer == nil || answer.Prompt != "<@UBOT> and now?" {
+		t.Errorf("expected the new answer to be remembered, got %+v", stored.Answers)
+	}
+}
+
+func TestHandleAction_Stop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"), usecase.WithControls(true))
+
+	action := newAction(domain.ControlStop)
+	agentRepo.EXPECT().CancelSession(action.SessionKey()).Return(true)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "🛑 Cancelled.", "1.1").Return("", nil)
+
+	if err := handler.HandleAction(context.Background(), action); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleAction_Regenerate(t *testing.T) {
+	tests := []struct {
+		name     string
+		prompt   string
+		wantText string
+	}{
+		{name: "first regenerate", prompt: "<@UBOT> hello", wantText: "Please answer this again with a fresh attempt:\n\n<@UBOT> hello"},
+		{name: "repeated regenerate", prompt: "Please answer this again with a fresh attempt:\n\n<@UBOT> hello", wantText: "Please answer this again with a fresh attempt:\n\n<@UBOT> hello"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			agentRepo := mocks.NewMockAgentRepository(ctrl)
+			sessions := mocks.NewMockSessionRepository(ctrl)
+			handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+				usecase.WithSessionRepository(sessions),
+			)
+
+			action := newAction(domain.ControlRegenerate)
+			stored := domain.NewSession(action.SessionKey(), time.Now())
+			// The clicked answer is older than the latest one
+			stored.AddAnswer(domain.Answer{MessageTS: []string{"1.8", "1.9"}, Prompt: tt.prompt})
+			stored.AddAnswer(domain.Answer{MessageTS: []string{"2.1"}, Prompt: "<@UBOT> something else"})
+			stored.LastPrompt = "<@UBOT> something else"
+			sessions.EXPECT().Get(gomock.Any(), action.SessionKey()).Return(stored, nil).Times(2)
+			sessions.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+			agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, session *domain.Session, msg *domain.Message) (*domain.AgentResult, error) {
+					if msg.Text != tt.wantText || msg.ThreadTS != "1.1" || msg.TeamID != "T1" {
+						t.Errorf("unexpected message: %+v", msg)
+					}
+					return domain.NewAgentResult("", nil), nil
+				})
+
+			if err := handler.HandleAction(context.Background(), action); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleAction_RegenerateUnknownPrompt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithSessionRepository(sessions),
+	)
+
+	action := newAction(domain.ControlRegenerate)
+	sessions.EXPECT().Get(gomock.Any(), action.SessionKey()).Return(nil, domain.ErrSessionNotFound)
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "1.1").DoAndReturn(
+		func(_ context.Context, _, _, text, _ string) error {
+			if !strings.Contains(text, "don't remember") {
+				t.Errorf("unexpected reply: %q", text)
+			}
+			return nil
+		})
+
+	if err := handler.HandleAction(context.Background(), action); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleAction_Continue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"), usecase.WithControls(true))
+
+	action := newAction(domain.ControlContinue)
+	slackRepo.EXPECT().PostControls(gomock.Any(), "C123", "1.1", gomock.Any(), []domain.Control{domain.ControlStop}).Return("", nil)
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, session *domain.Session, msg *domain.Message) (*domain.AgentResult, error) {
+			if msg.Text != "Please continue." {
+				t.Errorf("unexpected prompt: %q", msg.Text)
+			}
+			return domain.NewAgentResult("", nil), nil
+		})
+
+	if err := handler.HandleAction(context.Background(), action); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleAction_Feedback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, mocks.NewMockAgentRepository(ctrl), domain.NewBot("UBOT"))
+
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", "🙏 Thanks for the feedback!", "1.1").Return(nil)
+
+	if err := handler.HandleAction(context.Background(), newAction(domain.ControlGood)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
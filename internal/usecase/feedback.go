@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// rateByReaction records a 👍/👎 reaction on one of the bot's messages as feedback
+func (h *messageHandlerImpl) rateByReaction(ctx context.Context, reaction *domain.Reaction, rating domain.Rating) error {
+	if h.feedback == nil || reaction.ItemUserID != h.bot.UserID {
+		return nil
+	}
+	if !h.authorize(ctx, reaction.UserID, reaction.ChannelID) {
+		return nil
+	}
+
+	// Reactions refer to the message, but the session belongs to its thread
+	threadTS, err := h.slackRepo.GetThreadTS(ctx, reaction.ChannelID, reaction.ItemTS)
+	if err != nil {
+		return fmt.Errorf("failed to find thread of rated message: %w", err)
+	}
+	key := domain.SessionKey{TeamID: reaction.TeamID, ChannelID: reaction.ChannelID, ThreadTS: threadTS}
+	return h.recordFeedback(ctx, key, reaction.ItemTS, reaction.UserID, rating, domain.FeedbackReaction)
+}
+
+// recordFeedback stores a rating of the answer that was posted as the message
+func (h *messageHandlerImpl) recordFeedback(ctx context.Context, key domain.SessionKey, messageTS, userID string, rating domain.Rating, source domain.FeedbackSource) error {
+	if h.feedback == nil {
+		return nil
+	}
+
+	// The rating is still worth keeping without the answer
+	var answer *domain.Answer
+	if h.sessions != nil {
+		session, err := h.sessions.Get(ctx, key)
+		switch {
+		case err == nil:
+			answer = session.FindAnswer(messageTS)
+		case !errors.Is(err, domain.ErrSessionNotFound):
+			log.Printf("Error loading session %s for feedback: %v", key, err)
+		}
+	}
+	if answer == nil {
+		log.Printf("Rated message %s in thread %s is not a remembered answer", messageTS, key.ThreadTS)
+	}
+
+	feedback := domain.NewFeedback(key, answer, messageTS, userID, rating, source, time.Now())
+	if feedback.Profile == "" && h.profiles != nil {
+		feedback.Profile = h.profiles.Resolve(ctx, key.ChannelID).Name
+	}
+	if err := h.feedback.Add(ctx, feedback); err != nil {
+		return fmt.Errorf("failed to record feedback: %w", err)
+	}
+
+	log.Printf("Recorded %s feedback by %s from user %s in thread %s", rating, source, userID, key.ThreadTS)
+	return nil
+}
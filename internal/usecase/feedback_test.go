@@ -0,0 +1,147 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleReaction_Feedback(t *testing.T) {
+	tests := []struct {
+		name       string
+		itemUserID string
+		wantRecord bool
+	}{
+		{name: "reaction on the bot's answer", itemUserID: "UBOT", wantRecord: true},
+		{name: "reaction on a user's message", itemUserID: "U999"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			sessions := mocks.NewMockSessionRepository(ctrl)
+			feedbackRepo := mocks.NewMockFeedbackRepository(ctrl)
+			handler := usecase.NewMessageHandler(slackRepo, mocks.NewMockAgentRepository(ctrl), domain.NewBot("UBOT"),
+				usecase.WithSessionRepository(sessions),
+				usecase.WithFeedback(feedbackRepo),
+			)
+
+			reaction := domain.NewReaction("U123", "C123", "1.5", "-1")
+			reaction.TeamID = "T1"
+			reaction.ItemUserID = tt.itemUserID
+
+			if tt.wantRecord {
+				key := domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "1.1"}
+				stored := domain.NewSession(key, time.Now())
+				stored.AddAnswer(domain.Answer{MessageTS: []string{"1.5"}, Prompt: "<@UBOT> why?", Response: "because", Latency: 2 * time.Second})
+
+				slackRepo.EXPECT().GetThreadTS(gomock.Any(), "C123", "1.5").Return("1.1", nil)
+				sessions.EXPECT().Get(gomock.Any(), key).Return(stored, nil)
+				feedbackRepo.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, feedback *domain.Feedback) error {
+						if feedback.Key != key || feedback.MessageTS != "1.5" || feedback.UserID != "U123" {
+							t.Errorf("unexpected feedback target: %+v", feedback)
+						}
+						if feedback.Rating != domain.RatingBad || feedback.Source != domain.FeedbackReaction {
+							t.Errorf("unexpected rating: %+v", feedback)
+						}
+						if feedback.Prompt != "<@UBOT> why?" || feedback.Response != "because" || feedback.Latency != 2*time.Second {
+							t.Errorf("expected the rated answer, got %+v", feedback)
+						}
+						return nil
+					})
+			}
+
+			if err := handler.HandleReaction(context.Background(), reaction); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleReaction_FeedbackOnAnEarlierAnswer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	feedbackRepo := mocks.NewMockFeedbackRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithSessionRepository(sessions),
+		usecase.WithFeedback(feedbackRepo),
+	)
+
+	// The thread's session is kept across the runs
+	var stored *domain.Session
+	sessions.EXPECT().Get(gomock.Any(), gomock.Any()).DoAndReturn(func(context.Context, domain.SessionKey) (*domain.Session, error) {
+		if stored == nil {
+			return nil, domain.ErrSessionNotFound
+		}
+		return stored, nil
+	}).AnyTimes()
+	sessions.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, session *domain.Session) error {
+		stored = session
+		return nil
+	}).AnyTimes()
+
+	// Two answers in the same thread
+	first := domain.NewMessage("1.1", "U123", "C123", "<@UBOT> first question", "1.1", time.Now())
+	second := domain.NewMessage("1.3", "U123", "C123", "<@UBOT> second question", "1.1", time.Now())
+	first.TeamID, second.TeamID = "T1", "T1"
+	gomock.InOrder(
+		agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.NewAgentResult("first answer", nil), nil),
+		slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "first answer", "1.1").Return("1.2", nil),
+		agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.NewAgentResult("second answer", nil), nil),
+		slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "second answer", "1.1").Return("1.4", nil),
+	)
+	for _, msg := range []*domain.Message{first, second} {
+		if err := handler.HandleMessage(context.Background(), msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The first answer is rated after the second one was posted
+	reaction := domain.NewReaction("U123", "C123", "1.2", "+1")
+	reaction.TeamID = "T1"
+	reaction.ItemUserID = "UBOT"
+	slackRepo.EXPECT().GetThreadTS(gomock.Any(), "C123", "1.2").Return("1.1", nil)
+	feedbackRepo.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, feedback *domain.Feedback) error {
+			if feedback.Prompt != "<@UBOT> first question" || feedback.Response != "first answer" {
+				t.Errorf("expected the first answer, got %+v", feedback)
+			}
+			return nil
+		})
+
+	if err := handler.HandleReaction(context.Background(), reaction); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleAction_RecordsFeedback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	feedbackRepo := mocks.NewMockFeedbackRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, mocks.NewMockAgentRepository(ctrl), domain.NewBot("UBOT"),
+		usecase.WithFeedback(feedbackRepo),
+		usecase.WithProfiles(usecase.NewProfileResolver(domain.NewProfiles(domain.Profile{Name: "default"}, nil), slackRepo)),
+	)
+
+	feedbackRepo.EXPECT().Add(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, feedback *domain.Feedback) error {
+			if feedback.Rating != domain.RatingGood || feedback.Source != domain.FeedbackButton || feedback.Profile != "default" {
+				t.Errorf("unexpected feedback: %+v", feedback)
+			}
+			return nil
+		})
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", "🙏 Thanks for the feedback!", "1.1").Return(nil)
+
+	if err := handler.HandleAction(context.Background(), newAction(domain.ControlGood)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -2,22 +2,131 @@ package usecase
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/takutakahashi/slack-agent/internal/domain"
 )
 
 // SlackRepository defines the interface for Slack operations
 type SlackRepository interface {
-	PostMessage(ctx context.Context, channelID, text, threadTS string) error
+	// PostMessage posts a message and returns its timestamp
+	PostMessage(ctx context.Context, channelID, text, threadTS string) (string, error)
+	// UpdateMessage replaces the text of a message posted by the bot
+	UpdateMessage(ctx context.Context, channelID, ts, text string) error
 	GetBotUserID(ctx context.Context) (string, error)
+	// GetChannelName returns the name of the channel without the leading '#'
+	GetChannelName(ctx context.Context, channelID string) (string, error)
+	// PostEphemeral posts a message only the given user can see
+	PostEphemeral(ctx context.Context, channelID, userID, text, threadTS string) error
+	// RespondToCommand replies to a slash command through its response URL; only the invoking user sees the reply
+	RespondToCommand(ctx context.Context, responseURL, text string) error
+	// GetUserGroupMembers returns the IDs of the users in the user group
+	GetUserGroupMembers(ctx context.Context, groupID string) ([]string, error)
+	// DownloadFile writes the file at a private Slack URL to w
+	DownloadFile(ctx context.Context, url string, w io.Writer) error
+	// GetThreadReplies returns the messages of a thread, oldest first, posted before the given
+	// timestamp; an empty before returns the whole thread. Each message's ID is its timestamp.
+	GetThreadReplies(ctx context.Context, channelID, threadTS, before string) ([]*domain.Message, error)
+	// GetUserName returns the display name of the user
+	GetUserName(ctx context.Context, userID string) (string, error)
+	// UploadFile uploads a file of the given size to the thread
+	UploadFile(ctx context.Context, channelID, threadTS, filename string, size int64, content io.Reader) error
+	// PostControls posts a message with buttons for the controls and returns its timestamp
+	PostControls(ctx context.Context, channelID, threadTS, text string, controls []domain.Control) (string, error)
+	// SetControls puts buttons for the controls on a message posted by the bot, or takes them off when there are none
+	SetControls(ctx context.Context, channelID, threadTS, ts, text string, controls []domain.Control) error
+	// DeleteMessage deletes a message posted by the bot
+	DeleteMessage(ctx context.Context, channelID, ts string) error
+	// GetThreadTS returns the timestamp of the thread the message belongs to,
+	// which is the message's own timestamp if it is not a reply
+	GetThreadTS(ctx context.Context, channelID, ts string) (string, error)
 }
 
 // AgentRepository defines the interface for AI agent operations
 type AgentRepository interface {
-	GenerateResponse(ctx context.Context, message *domain.Message) (*domain.AgentResult, error)
+	// GenerateResponse runs the agent for the message, continuing the given session
+	GenerateResponse(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error)
+	// CancelSession cancels the agent running in the session and reports whether one was running
+	CancelSession(key domain.SessionKey) bool
+	// CancelAll cancels every running agent and returns how many were running
+	CancelAll() int
+	// ForgetSession drops what the backend remembers of the session's conversation
+	ForgetSession(key domain.SessionKey)
+}
+
+// SessionRepository stores the agent session behind each Slack thread
+type SessionRepository interface {
+	// Get returns the session for the key or domain.ErrSessionNotFound
+	Get(ctx context.Context, key domain.SessionKey) (*domain.Session, error)
+	Save(ctx context.Context, session *domain.Session) error
+	Delete(ctx context.Context, key domain.SessionKey) error
+	List(ctx context.Context) ([]*domain.Session, error)
+}
+
+// WorkspaceRepository manages the working directories that agents use for their sessions
+type WorkspaceRepository interface {
+	// Workdir returns the working directory of a session started by the user
+	Workdir(ctx context.Context, key domain.SessionKey, userID string) (string, error)
+	// Size returns the disk usage of the session's working directory in bytes
+	Size(ctx context.Context, key domain.SessionKey) (int64, error)
+	// Remove deletes the session's working directory
+	Remove(ctx context.Context, key domain.SessionKey) error
+	// SaveAttachment stores a file shared by the user in the session and returns its absolute path.
+	// Nothing is kept when content fails.
+	SaveAttachment(ctx context.Context, key domain.SessionKey, name string, content io.Reader) (string, error)
+	// Snapshot lists the files in the session's working directory, leaving out attachments and the bot's own files
+	Snapshot(ctx context.Context, key domain.SessionKey) (domain.WorkspaceSnapshot, error)
+	// Open opens a file in the session's working directory by its snapshot path
+	Open(ctx context.Context, key domain.SessionKey, path string) (io.ReadCloser, error)
+}
+
+// RateLimitRepository stores the token buckets of the rate limiter
+type RateLimitRepository interface {
+	// Get returns the bucket stored under the key, or nil if there is none
+	Get(ctx context.Context, key string) (*domain.TokenBucket, error)
+	Save(ctx context.Context, key string, bucket *domain.TokenBucket) error
+	// DeleteIdle removes buckets that have not been updated since the given time
+	DeleteIdle(ctx context.Context, before time.Time) error
+}
+
+// FeedbackRepository stores users' ratings of the agent's answers
+type FeedbackRepository interface {
+	Add(ctx context.Context, feedback *domain.Feedback) error
+	// List returns the feedback given at or after since, oldest first
+	List(ctx context.Context, since time.Time) ([]*domain.Feedback, error)
 }
 
 // MessageHandler defines the interface for message handling use case
 type MessageHandler interface {
 	HandleMessage(ctx context.Context, message *domain.Message) error
+	HandleReaction(ctx context.Context, reaction *domain.Reaction) error
+	HandleAction(ctx context.Context, action *domain.Action) error
+	// Shutdown drains the running agents before the bot exits
+	Shutdown(ctx context.Context, drain time.Duration) error
+}
+
+// CommandHandler defines the interface for the slash command use case
+type CommandHandler interface {
+	HandleCommand(ctx context.Context, command *domain.SlashCommand) error
+}
+
+// LeaseRepository grants time-limited leases that replicas use to claim work
+type LeaseRepository interface {
+	// Acquire grants or renews the lease for holder unless another holder has an unexpired lease.
+	// It returns the holder of the lease afterwards.
+	Acquire(ctx context.Context, name, holder string, ttl time.Duration) (string, error)
+	// Holder returns the holder of the lease, or an empty string when it is free
+	Holder(ctx context.Context, name string) (string, error)
+	// Release gives up the lease if holder holds it
+	Release(ctx context.Context, name, holder string) error
+}
+
+// HandoffRepository passes events on to other replicas
+type HandoffRepository interface {
+	Send(ctx context.Context, replica string, handoff *domain.Handoff) error
+	// Receive removes and returns the events sent to the replica, oldest first
+	Receive(ctx context.Context, replica string) ([]*domain.Handoff, error)
+	// Replicas returns the replicas that have an inbox
+	Replicas(ctx context.Context) ([]string, error)
 }
@@ -2,26 +2,176 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/takutakahashi/slack-agent/internal/domain"
 )
 
+// DefaultCancelReaction is the reaction that cancels a running agent when none is configured
+const DefaultCancelReaction = "octagonal_sign"
+
+// mentionPattern matches mention tags like <@U12345>
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9_]+>`)
+
 // messageHandlerImpl implements the MessageHandler interface
 type messageHandlerImpl struct {
-	slackRepo SlackRepository
-	agentRepo AgentRepository
-	bot       *domain.Bot
+	slackRepo      SlackRepository
+	agentRepo      AgentRepository
+	bot            *domain.Bot
+	cancelReaction string
+	mergeQueued    bool
+	queue          *threadQueue
+	pool           *AgentPool
+	sessions       SessionRepository
+	profiles       *ProfileResolver
+	authorizer     *Authorizer
+	rateLimiter    *RateLimiter
+	// workspaces holds the session files; the policies select the files passed to and from the agent
+	workspaces       WorkspaceRepository
+	attachmentPolicy domain.AttachmentPolicy
+	artifactPolicy   domain.ArtifactPolicy
+	historyChars     int
+	userNames        *userNames
+	followThreads    bool
+	followIdle       time.Duration
+	controls         bool
+	feedback         FeedbackRepository
+	drain            *drainState
+	cluster          *Cluster
+}
+
+// HandlerOption configures optional behaviour of the message handler
+type HandlerOption func(*messageHandlerImpl)
+
+// WithCancelReaction sets the reaction name that cancels a running agent
+func WithCancelReaction(name string) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		if name != "" {
+			h.cancelReaction = strings.Trim(name, ":")
+		}
+	}
+}
+
+// WithMergeQueuedMessages makes messages that queued up while an agent was running
+// in the thread run as a single combined prompt instead of one by one
+func WithMergeQueuedMessages(merge bool) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.mergeQueued = merge
+	}
+}
+
+// WithAgentPool limits how many agents run at the same time across all threads
+func WithAgentPool(pool *AgentPool) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.pool = pool
+	}
+}
+
+// WithSessionRepository persists agent sessions so that conversations survive restarts
+func WithSessionRepository(sessions SessionRepository) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.sessions = sessions
+	}
+}
+
+// WithProfiles sets the per-channel profiles whose access policies apply to messages
+func WithProfiles(profiles *ProfileResolver) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.profiles = profiles
+	}
+}
+
+// WithAuthorizer restricts who may invoke the agent
+func WithAuthorizer(authorizer *Authorizer) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.authorizer = authorizer
+	}
+}
+
+// WithRateLimiter throttles how often users, channels and workspaces may invoke the agent
+func WithRateLimiter(limiter *RateLimiter) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.rateLimiter = limiter
+	}
+}
+
+// WithAttachments downloads files shared with a message into the session so the agent can read them
+func WithAttachments(workspaces WorkspaceRepository, policy domain.AttachmentPolicy) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.workspaces = workspaces
+		h.attachmentPolicy = policy
+	}
+}
+
+// WithArtifactUploads uploads files that the agent creates in the session to the thread
+func WithArtifactUploads(workspaces WorkspaceRepository, policy domain.ArtifactPolicy) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.workspaces = workspaces
+		h.artifactPolicy = policy
+	}
+}
+
+// WithThreadHistory adds up to maxChars characters of the thread's earlier messages to the
+// prompt when a thread's session starts, e.g. when the bot is mentioned halfway through a thread
+func WithThreadHistory(maxChars int) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.historyChars = maxChars
+	}
+}
+
+// WithThreadFollowing makes the bot answer every message in threads with an active session,
+// without a mention, until the thread is idle for idleTimeout or someone says "bye".
+// Channel profiles decide where this applies when profiles are set; enabled applies otherwise.
+// Following needs a session repository.
+func WithThreadFollowing(enabled bool, idleTimeout time.Duration) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.followThreads = enabled
+		h.followIdle = idleTimeout
+	}
+}
+
+// WithControls adds buttons to stop a running agent, and to regenerate, continue or rate its answers
+func WithControls(enabled bool) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.controls = enabled
+	}
+}
+
+// WithFeedback records 👍/👎 reactions on the bot's messages and clicks on the feedback buttons
+func WithFeedback(feedback FeedbackRepository) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.feedback = feedback
+	}
+}
+
+// WithCluster hands events for threads owned by other replicas off to them
+func WithCluster(cluster *Cluster) HandlerOption {
+	return func(h *messageHandlerImpl) {
+		h.cluster = cluster
+	}
 }
 
 // NewMessageHandler creates a new MessageHandler instance
-func NewMessageHandler(slackRepo SlackRepository, agentRepo AgentRepository, bot *domain.Bot) MessageHandler {
-	return &messageHandlerImpl{
-		slackRepo: slackRepo,
-		agentRepo: agentRepo,
-		bot:       bot,
+func NewMessageHandler(slackRepo SlackRepository, agentRepo AgentRepository, bot *domain.Bot, opts ...HandlerOption) MessageHandler {
+	h := &messageHandlerImpl{
+		slackRepo:      slackRepo,
+		agentRepo:      agentRepo,
+		bot:            bot,
+		cancelReaction: DefaultCancelReaction,
+		queue:          newThreadQueue(),
+		pool:           NewAgentPool(0, 0),
+		userNames:      newUserNames(slackRepo),
+		drain:          newDrainState(),
+	}
+	for _, opt := range opts {
+		opt(h)
 	}
+	return h
 }
 
 // HandleMessage handles incoming Slack messages
@@ -31,26 +181,431 @@ func (h *messageHandlerImpl) HandleMessage(ctx context.Context, message *domain.
 		return nil
 	}
 
-	// Check if the bot is mentioned or if it's a direct message
-	if !h.bot.IsMentioned(message.Text) && message.ChannelID[0] != 'D' {
+	// The replica that owns the thread has its session and running agent
+	if h.handOff(ctx, message.SessionKey(), &domain.Handoff{Message: message}) {
 		return nil
 	}
 
+	// "stop" in a thread with a running agent cancels it, even without a mention
+	if isStopRequest(message.Text) && h.authorize(ctx, message.UserID, message.ChannelID) && h.agentRepo.CancelSession(message.SessionKey()) {
+		dropped := h.queue.clear(message.SessionKey().String())
+		log.Printf("Cancelled agent in thread %s on request from user %s (dropped %d queued messages)", message.ThreadTS, message.UserID, dropped)
+		return h.postCancelled(ctx, message.ChannelID, message.ThreadTS)
+	}
+
+	// Check if the bot is mentioned, if it's a direct message or if the bot follows the thread
+	if !h.bot.IsMentioned(message.Text) && message.ChannelID[0] != 'D' && !h.isFollowing(ctx, message) {
+		return nil
+	}
+
+	// Only authorized users may run the agent
+	if !h.authorize(ctx, message.UserID, message.ChannelID) {
+		return h.slackRepo.PostEphemeral(ctx, message.ChannelID, message.UserID,
+			"🙇 Sorry, you don't have access to me here. Please ask a workspace admin if you need it.",
+			message.ThreadTS)
+	}
+
+	// "bye" ends a followed thread
+	if isByeRequest(message.Text) && h.followsThreads(ctx, message.ChannelID) {
+		return h.dismissThread(ctx, message)
+	}
+
+	return h.submit(ctx, message)
+}
+
+// submit runs the agent for an authorized message once the thread is free
+func (h *messageHandlerImpl) submit(ctx context.Context, message *domain.Message) error {
+	// Claim the thread unless another replica owns it
+	if h.cluster != nil {
+		if owner := h.cluster.claim(ctx, message.SessionKey()); owner != "" && h.sendHandoff(ctx, message.SessionKey(), owner, &domain.Handoff{Message: message}) {
+			return nil
+		}
+	}
+
+	// Throttle users, channels and workspaces that send too many requests
+	if limited, err := h.rateLimited(ctx, message); limited || err != nil {
+		return err
+	}
+
+	// Only one agent may run per thread since runs share the session
+	key := message.SessionKey().String()
+	position, owner := h.queue.enqueue(key, message)
+	markQueued(ctx)
+	if !owner {
+		log.Printf("Queued message from user %s in thread %s at position %d", message.UserID, message.ThreadTS, position)
+		return h.reply(ctx, message.ChannelID,
+			fmt.Sprintf("⏳ I'm still working on an earlier message in this thread. I'll get to this one next (position %d in this thread).", position),
+			message.ThreadTS)
+	}
+
+	h.drain.begin()
+	defer h.drain.end()
+	if h.cluster != nil {
+		defer h.cluster.hold(message.SessionKey())()
+	}
+
+	// Drain the thread's queue; other threads keep running in parallel
+	var errs []error
+	for batch := []*domain.Message{message}; batch != nil; batch = h.queue.next(key, h.mergeQueued) {
+		if h.drain.isClosing() {
+			// No new runs start while the bot shuts down
+			h.queue.clear(key)
+			if err := h.postRestarting(ctx, batch[len(batch)-1]); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := h.respond(ctx, mergeMessages(batch)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// respond runs the agent for a single prompt and reports failures to the thread
+func (h *messageHandlerImpl) respond(ctx context.Context, message *domain.Message) error {
 	// Log the incoming message
 	log.Printf("Handling message from user %s in channel %s: %s", message.UserID, message.ChannelID, message.Text)
 
-	// Generate response using AI agent (it handles posting directly to Slack)
-	result, err := h.agentRepo.GenerateResponse(ctx, message)
+	// Wait for a free agent slot
+	release, err := h.pool.Acquire(ctx, func(position int) {
+		log.Printf("All agents busy; thread %s is #%d in line", message.ThreadTS, position)
+		if err := h.reply(ctx, message.ChannelID,
+			fmt.Sprintf("⏳ All agents are busy right now. You are #%d in line.", position),
+			message.ThreadTS); err != nil {
+			log.Printf("Error posting queue position: %v", err)
+		}
+	})
+	if errors.Is(err, ErrAgentQueueFull) {
+		log.Printf("Rejected message in thread %s: agent queue is full", message.ThreadTS)
+		return h.reply(ctx, message.ChannelID,
+			"🙇 I'm handling too many requests right now. Please try again in a few minutes.",
+			message.ThreadTS)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to acquire agent slot: %w", err)
+	}
+	defer release()
+	if h.drain.isClosing() {
+		// The slot may have been freed by a run cancelled on shutdown
+		return h.postRestarting(ctx, message)
+	}
+
+	// Generate response using AI agent, continuing the thread's session
+	session := h.startSession(ctx, message)
+
+	// Hand shared files to the agent
+	message = h.attachFiles(ctx, message)
+	session.LastPrompt = message.Text
+
+	before := h.snapshotWorkspace(ctx, message.SessionKey())
+	if session.MessageCount == 1 {
+		// The agent has no context of the thread yet
+		message = h.withThreadHistory(ctx, message)
+	}
+	stop := h.postControls(ctx, message, "⏳ Working on it…", domain.ControlStop)
+	result, err := h.generate(ctx, session, message)
+	h.finishSession(ctx, session, result)
+	h.removeControls(ctx, message.ChannelID, stop)
 	if err != nil {
 		log.Printf("Error generating response: %v", err)
-		return h.slackRepo.PostMessage(ctx, message.ChannelID, "申し訳ございません。応答の生成中にエラーが発生しました。", message.ThreadTS)
+		return h.replyFailed(ctx, session, message, "申し訳ございません。応答の生成中にエラーが発生しました。")
+	}
+	if result.IsCancelled() && h.drain.isClosing() {
+		log.Printf("Agent in thread %s was stopped for shutdown", message.ThreadTS)
+		return h.postRestarting(ctx, message)
+	}
+	if result.IsCancelled() {
+		// The cancel notice has already been posted by whoever cancelled the run
+		log.Printf("Agent in thread %s was cancelled", message.ThreadTS)
+		return nil
+	}
+	if result.AgentErrorKind() == domain.AgentErrorTimeout {
+		log.Printf("Agent in thread %s timed out: %v", message.ThreadTS, result.Error)
+		return h.replyFailed(ctx, session, message, timedOutText(result))
 	}
 	if result.IsError() {
 		log.Printf("Agent returned error: %v", result.Error)
-		return h.slackRepo.PostMessage(ctx, message.ChannelID, fmt.Sprintf("Sorry, I encountered an error: %s", result.Error.Error()), message.ThreadTS)
+		return h.replyFailed(ctx, session, message, fmt.Sprintf("Sorry, I encountered an error: %s", result.Error.Error()))
+	}
+
+	// Backends that stream to Slack themselves have already posted the response
+	answer := h.newAnswer(ctx, session, result.PostedTS)
+	text := result.PostedText
+	if !result.Posted && result.Response != "" {
+		ts, err := h.slackRepo.PostMessage(ctx, message.ChannelID, result.Response, message.ThreadTS)
+		if err != nil {
+			return err
+		}
+		answer.MessageTS, text = []string{ts}, result.Response
+	}
+
+	// Share the files the agent produced
+	h.uploadArtifacts(ctx, message, before)
+	h.answered(ctx, session, answer, text,
+		domain.ControlRegenerate, domain.ControlContinue, domain.ControlGood, domain.ControlBad)
+	return nil
+}
+
+// HandleReaction handles reactions added to messages.
+// The cancel reaction on a thread root stops the agent running in that thread,
+// and 👍/👎 on the bot's messages are recorded as feedback.
+func (h *messageHandlerImpl) HandleReaction(ctx context.Context, reaction *domain.Reaction) error {
+	if reaction.UserID == h.bot.UserID {
+		return nil
+	}
+	if h.handOff(ctx, reaction.SessionKey(), &domain.Handoff{Reaction: reaction}) {
+		return nil
+	}
+	if rating, ok := domain.RatingFromReaction(reaction.Name); ok {
+		return h.rateByReaction(ctx, reaction, rating)
+	}
+	if reaction.Name != h.cancelReaction {
+		return nil
+	}
+	if !h.authorize(ctx, reaction.UserID, reaction.ChannelID) {
+		return nil
+	}
+
+	if !h.agentRepo.CancelSession(reaction.SessionKey()) {
+		return nil
+	}
+
+	dropped := h.queue.clear(reaction.SessionKey().String())
+	log.Printf("Cancelled agent in thread %s by :%s: from user %s (dropped %d queued messages)", reaction.ItemTS, reaction.Name, reaction.UserID, dropped)
+	return h.postCancelled(ctx, reaction.ChannelID, reaction.ItemTS)
+}
+
+// authorize reports whether the user may use the agent in the channel
+func (h *messageHandlerImpl) authorize(ctx context.Context, userID, channelID string) bool {
+	if h.authorizer == nil {
+		return true
+	}
+
+	var profile *domain.Profile
+	if h.profiles != nil {
+		profile = h.profiles.Resolve(ctx, channelID)
+	}
+	return h.authorizer.Authorize(ctx, userID, channelID, profile).Allowed
+}
+
+// followsThreads reports whether thread following is enabled in the channel
+func (h *messageHandlerImpl) followsThreads(ctx context.Context, channelID string) bool {
+	if h.sessions == nil {
+		return false
+	}
+	if h.profiles != nil {
+		return h.profiles.Resolve(ctx, channelID).FollowThreads
+	}
+	return h.followThreads
+}
+
+// isFollowing reports whether the message is a reply in a thread that the bot follows
+func (h *messageHandlerImpl) isFollowing(ctx context.Context, message *domain.Message) bool {
+	// Messages that start a thread need a mention
+	if message.ID == message.ThreadTS || !h.followsThreads(ctx, message.ChannelID) {
+		return false
+	}
+
+	session, err := h.sessions.Get(ctx, message.SessionKey())
+	if err != nil {
+		if !errors.Is(err, domain.ErrSessionNotFound) {
+			log.Printf("Error loading session %s: %v", message.SessionKey(), err)
+		}
+		return false
+	}
+	return session.IsFollowing(time.Now(), h.followIdle)
+}
+
+// dismissThread stops following the thread until the bot is mentioned again
+func (h *messageHandlerImpl) dismissThread(ctx context.Context, message *domain.Message) error {
+	session, err := h.sessions.Get(ctx, message.SessionKey())
+	if err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+	if session != nil {
+		session.Dismiss(time.Now())
+		h.saveSession(ctx, session)
+	}
+
+	log.Printf("User %s ended thread %s", message.UserID, message.ThreadTS)
+	return h.reply(ctx, message.ChannelID, "👋 Bye! Mention me if you need me again in this thread.", message.ThreadTS)
+}
+
+// rateLimited reports whether the message exceeds a rate limit and, if so, tells the user when to retry
+func (h *messageHandlerImpl) rateLimited(ctx context.Context, message *domain.Message) (bool, error) {
+	if h.rateLimiter == nil {
+		return false, nil
+	}
+
+	decision, err := h.rateLimiter.Allow(ctx, message, time.Now())
+	if err != nil {
+		// Don't lock everyone out because the limiter state is unavailable
+		log.Printf("Error checking rate limits, allowing message: %v", err)
+		return false, nil
+	}
+	if decision.Allowed {
+		return false, nil
+	}
+
+	log.Printf("Rate limited user %s in channel %s: %s limit of %s, retry at %s",
+		message.UserID, message.ChannelID, decision.Scope, decision.Limit, decision.RetryAt.Format(time.RFC3339))
+	return true, h.slackRepo.PostEphemeral(ctx, message.ChannelID, message.UserID,
+		fmt.Sprintf("🐢 You've hit the per-%s limit of %s. Please try again %s.",
+			decision.Scope, decision.Limit, slackTime(decision.RetryAt)),
+		message.ThreadTS)
+}
+
+// slackTime formats t so that Slack shows it in the reader's time zone.
+// The time is rounded up to the minute so that retrying at the displayed time succeeds.
+func slackTime(t time.Time) string {
+	if rounded := t.Truncate(time.Minute); rounded.Before(t) {
+		t = rounded.Add(time.Minute)
+	}
+	return fmt.Sprintf("<!date^%d^{date_short_pretty} at {time}|%s>", t.Unix(), t.UTC().Format("Jan 2 at 15:04 UTC"))
+}
+
+// startSession loads the thread's session, or creates one, and marks it as running
+func (h *messageHandlerImpl) startSession(ctx context.Context, message *domain.Message) *domain.Session {
+	now := time.Now()
+	session := domain.NewSession(message.SessionKey(), now)
+	if h.sessions != nil {
+		stored, err := h.sessions.Get(ctx, message.SessionKey())
+		switch {
+		case err == nil:
+			session = stored
+		case !errors.Is(err, domain.ErrSessionNotFound):
+			log.Printf("Error loading session %s, starting a new one: %v", message.SessionKey(), err)
+		}
+	}
+
+	session.Start(now)
+	session.LastPrompt = message.Text
+	if session.Workdir == "" && h.workspaces != nil {
+		// Attachments, uploads and the agent then all use the directory the session started in
+		workdir, err := h.workspaces.Workdir(ctx, session.Key, message.UserID)
+		if err != nil {
+			log.Printf("Error resolving the working directory of session %s: %v", session.Key, err)
+		}
+		session.Workdir = workdir
+	}
+	h.saveSession(ctx, session)
+	return session
+}
+
+// generate runs the agent for the message. When the conversation the session continues is gone,
+// e.g. after a restart or when the thread moved to another replica, it starts over once with the thread's history.
+func (h *messageHandlerImpl) generate(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+	result, err := h.agentRepo.GenerateResponse(ctx, session, message)
+	if err != nil || result.AgentErrorKind() != domain.AgentErrorResume {
+		return result, err
+	}
+
+	log.Printf("Agent conversation of thread %s is gone, starting over: %v", message.ThreadTS, result.Error)
+	// Drop what the failed run posted, such as its placeholder
+	for _, ts := range result.PostedTS {
+		if err := h.slackRepo.DeleteMessage(ctx, message.ChannelID, ts); err != nil {
+			log.Printf("Error deleting message %s: %v", ts, err)
+		}
 	}
+	session.AgentSessionID = ""
+	if session.MessageCount > 1 {
+		message = h.withThreadHistory(ctx, message)
+	}
+	return h.agentRepo.GenerateResponse(ctx, session, message)
+}
+
+// finishSession records the outcome of a run in the session
+func (h *messageHandlerImpl) finishSession(ctx context.Context, session *domain.Session, result *domain.AgentResult) {
+	session.Finish(result, time.Now())
+	h.saveSession(ctx, session)
+}
 
-	// Response has already been posted by claude-posts command
-	// So we just return nil here
+// newAnswer describes the answer to the session's last run that was posted as the messages
+func (h *messageHandlerImpl) newAnswer(ctx context.Context, session *domain.Session, messageTS []string) domain.Answer {
+	answer := domain.Answer{
+		MessageTS: messageTS,
+		Prompt:    session.LastPrompt,
+		Response:  session.LastResponse,
+		Latency:   session.LastLatency,
+	}
+	if h.profiles != nil {
+		answer.Profile = h.profiles.Resolve(ctx, session.Key.ChannelID).Name
+	}
+	return answer
+}
+
+// saveSession stores the session if a session repository is configured
+func (h *messageHandlerImpl) saveSession(ctx context.Context, session *domain.Session) {
+	if h.sessions == nil {
+		return
+	}
+	// Record the outcome even if the run was cancelled
+	if err := h.sessions.Save(context.WithoutCancel(ctx), session); err != nil {
+		log.Printf("Error saving session %s: %v", session.Key, err)
+	}
+}
+
+// postCancelled tells the thread that the agent has been stopped
+func (h *messageHandlerImpl) postCancelled(ctx context.Context, channelID, threadTS string) error {
+	return h.reply(ctx, channelID, "🛑 Cancelled.", threadTS)
+}
+
+// handOff passes the event on to the replica that owns its thread and reports whether it did
+func (h *messageHandlerImpl) handOff(ctx context.Context, key domain.SessionKey, handoff *domain.Handoff) bool {
+	if h.cluster == nil {
+		return false
+	}
+	owner := h.cluster.owner(ctx, key)
+	return owner != "" && h.sendHandoff(ctx, key, owner, handoff)
+}
+
+// sendHandoff sends the event to the owner, reporting false so that it is handled here if that fails
+func (h *messageHandlerImpl) sendHandoff(ctx context.Context, key domain.SessionKey, owner string, handoff *domain.Handoff) bool {
+	if err := h.cluster.handOff(ctx, owner, handoff); err != nil {
+		log.Printf("Failed to hand thread %s off to replica %s: %v", key, owner, err)
+		return false
+	}
+	log.Printf("Handed thread %s off to replica %s", key, owner)
+	return true
+}
+
+// reply posts a message to the thread
+func (h *messageHandlerImpl) reply(ctx context.Context, channelID, text, threadTS string) error {
+	_, err := h.slackRepo.PostMessage(ctx, channelID, text, threadTS)
+	return err
+}
+
+// replyFailed reports a failed run to the thread and offers to try again
+func (h *messageHandlerImpl) replyFailed(ctx context.Context, session *domain.Session, message *domain.Message, text string) error {
+	ts, err := h.slackRepo.PostMessage(ctx, message.ChannelID, text, message.ThreadTS)
+	if err != nil {
+		return err
+	}
+	h.answered(ctx, session, h.newAnswer(ctx, session, []string{ts}), text, domain.ControlRegenerate)
 	return nil
 }
+
+// timedOutText tells the thread that the run timed out, with whatever the agent had produced
+func timedOutText(result *domain.AgentResult) string {
+	text := fmt.Sprintf("⏱️ The run timed out (%s).", result.Error.Error())
+	if result.Posted {
+		return text + " Any output so far is above."
+	}
+	if result.Response != "" {
+		return text + "\n\nWhat I had so far:\n\n" + result.Response
+	}
+	return text
+}
+
+// isByeRequest reports whether the message says goodbye to the bot
+func isByeRequest(text string) bool {
+	cleaned := strings.ToLower(strings.Trim(strings.TrimSpace(mentionPattern.ReplaceAllString(text, "")), "!.👋 "))
+	return cleaned == "bye" || cleaned == "goodbye" || cleaned == "bye bye"
+}
+
+// isStopRequest reports whether the message asks to stop the running agent
+func isStopRequest(text string) bool {
+	cleaned := strings.TrimSpace(mentionPattern.ReplaceAllString(text, ""))
+	return strings.EqualFold(cleaned, "stop")
+}
@@ -0,0 +1,668 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleMessage_Mention(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(domain.NewAgentResult("", nil), nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_IgnoresUnmentioned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+	msg := domain.NewMessage("", "U123", "C123", "hello", "1.1", time.Now())
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_Stop(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		running bool
+	}{
+		{name: "stop without mention", text: "stop", running: true},
+		{name: "stop with mention", text: "<@UBOT> STOP", running: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			agentRepo := mocks.NewMockAgentRepository(ctrl)
+			handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+			msg := domain.NewMessage("", "U123", "C123", tt.text, "1.1", time.Now())
+			agentRepo.EXPECT().CancelSession(domain.SessionKey{ChannelID: "C123", ThreadTS: "1.1"}).Return(tt.running)
+			slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").Return("", nil)
+
+			if err := handler.HandleMessage(context.Background(), msg); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleMessage_CancelledRunIsSilent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(domain.NewAgentResult("", domain.ErrAgentCancelled), nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_TimedOut(t *testing.T) {
+	timeout := &domain.AgentError{Kind: domain.AgentErrorTimeout, Message: "no output for 5m"}
+	tests := []struct {
+		name     string
+		result   *domain.AgentResult
+		expected string
+	}{
+		{
+			name:     "streamed",
+			result:   &domain.AgentResult{Response: "half", Error: timeout, Posted: true},
+			expected: "⏱️ The run timed out (no output for 5m). Any output so far is above.",
+		},
+		{
+			name:     "partial output",
+			result:   domain.NewAgentResult("half an answer", timeout),
+			expected: "⏱️ The run timed out (no output for 5m).\n\nWhat I had so far:\n\nhalf an answer",
+		},
+		{
+			name:     "no output",
+			result:   domain.NewAgentResult("", timeout),
+			expected: "⏱️ The run timed out (no output for 5m).",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			agentRepo := mocks.NewMockAgentRepository(ctrl)
+			handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+			msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+			agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(tt.result, nil)
+			slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", tt.expected, "1.1").Return("", nil)
+
+			if err := handler.HandleMessage(context.Background(), msg); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleReaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		reaction   *domain.Reaction
+		wantCancel bool
+		running    bool
+	}{
+		{
+			name:       "cancel reaction on running thread",
+			reaction:   domain.NewReaction("U123", "C123", "1.1", "stop_sign"),
+			wantCancel: true,
+			running:    true,
+		},
+		{
+			name:       "cancel reaction on idle thread",
+			reaction:   domain.NewReaction("U123", "C123", "1.1", "stop_sign"),
+			wantCancel: true,
+			running:    false,
+		},
+		{
+			name:     "other reaction",
+			reaction: domain.NewReaction("U123", "C123", "1.1", "thumbsup"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			agentRepo := mocks.NewMockAgentRepository(ctrl)
+			handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+				usecase.WithCancelReaction(":stop_sign:"),
+			)
+
+			if tt.wantCancel {
+				agentRepo.EXPECT().CancelSession(domain.SessionKey{ChannelID: "C123", ThreadTS: "1.1"}).Return(tt.running)
+			}
+			if tt.running {
+				slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").Return("", nil)
+			}
+
+			if err := handler.HandleReaction(context.Background(), tt.reaction); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleMessage_SerializesThread(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithMergeQueuedMessages(true),
+	)
+
+	first := domain.NewMessage("", "U123", "C123", "<@UBOT> first", "1.1", time.Now())
+	second := domain.NewMessage("", "U123", "C123", "<@UBOT> second", "1.1", time.Now())
+	third := domain.NewMessage("", "U123", "C123", "<@UBOT> third", "1.1", time.Now())
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var prompts []string
+
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, session *domain.Session, msg *domain.Message) (*domain.AgentResult, error) {
+			prompts = append(prompts, msg.Text)
+			if len(prompts) == 1 {
+				close(started)
+				<-release
+			}
+			return domain.NewAgentResult("", nil), nil
+		}).Times(2)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").Return("", nil).Times(2)
+
+	done := make(chan error)
+	go func() { done <- handler.HandleMessage(context.Background(), first) }()
+	<-started
+
+	// Both follow-ups are queued while the first run is in progress
+	if err := handler.HandleMessage(context.Background(), second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := handler.HandleMessage(context.Background(), third); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(prompts) != 2 {
+		t.Fatalf("expected 2 agent runs, got %d", len(prompts))
+	}
+	if prompts[1] != "<@UBOT> second\n\n<@UBOT> third" {
+		t.Errorf("expected queued messages to be merged, got %q", prompts[1])
+	}
+}
+
+func TestHandleMessage_PostsReturnedResponse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(domain.NewAgentResult("hi there", nil), nil)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "hi there", "1.1").Return("", nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_ResumesStoredSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithSessionRepository(sessions),
+	)
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	msg.TeamID = "T1"
+	key := domain.SessionKey{TeamID: "T1", ChannelID: "C123", ThreadTS: "1.1"}
+
+	stored := domain.NewSession(key, time.Now().Add(-time.Hour))
+	stored.AgentSessionID = "sess-1"
+	stored.MessageCount = 3
+	sessions.EXPECT().Get(gomock.Any(), key).Return(stored, nil)
+
+	var saved []domain.Session
+	sessions.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, session *domain.Session) error {
+			saved = append(saved, *session)
+			return nil
+		}).Times(2)
+
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).DoAndReturn(
+		func(ctx context.Context, session *domain.Session, msg *domain.Message) (*domain.AgentResult, error) {
+			if session.AgentSessionID != "sess-1" {
+				t.Errorf("expected the stored session to be resumed, got %q", session.AgentSessionID)
+			}
+			result := domain.NewAgentResult("", nil)
+			result.SessionID = "sess-2"
+			return result, nil
+		})
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if saved[0].Status != domain.SessionRunning || saved[0].MessageCount != 4 {
+		t.Errorf("expected the session to be saved as running, got %+v", saved[0])
+	}
+	if saved[1].Status != domain.SessionIdle || saved[1].AgentSessionID != "sess-2" {
+		t.Errorf("expected the new agent session ID to be saved, got %+v", saved[1])
+	}
+}
+
+func TestHandleMessage_StartsNewSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithSessionRepository(sessions),
+	)
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	sessions.EXPECT().Get(gomock.Any(), msg.SessionKey()).Return(nil, domain.ErrSessionNotFound)
+	sessions.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).DoAndReturn(
+		func(ctx context.Context, session *domain.Session, msg *domain.Message) (*domain.AgentResult, error) {
+			if session.AgentSessionID != "" || session.MessageCount != 1 {
+				t.Errorf("expected a fresh session, got %+v", session)
+			}
+			return domain.NewAgentResult("", errors.New("boom")), nil
+		})
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").Return("", nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_Unauthorized(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	authorizer := usecase.NewAuthorizer(domain.AccessPolicy{Allow: domain.AccessRule{Users: []string{"U999"}}}, slackRepo)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithAuthorizer(authorizer),
+	)
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "1.1").Return(nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A denied user cannot stop someone else's agent either
+	if err := handler.HandleMessage(context.Background(), domain.NewMessage("", "U123", "C123", "stop", "1.1", time.Now())); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_RateLimited(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	limiter := usecase.NewRateLimiter(newMemRateLimitRepository(), usecase.RateLimits{
+		User: domain.RateLimit{Requests: 1, Per: time.Hour},
+	})
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithRateLimiter(limiter),
+	)
+
+	first := domain.NewMessage("", "U123", "C123", "<@UBOT> first", "1.1", time.Now())
+	second := domain.NewMessage("", "U123", "C123", "<@UBOT> second", "1.1", time.Now())
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), first).Return(domain.NewAgentResult("", nil), nil)
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "1.1").
+		DoAndReturn(func(_ context.Context, _, _, text, _ string) error {
+			if !strings.Contains(text, "1 requests per hour") || !strings.Contains(text, "<!date^") {
+				t.Errorf("unexpected rate limit notice: %q", text)
+			}
+			return nil
+		})
+
+	for _, msg := range []*domain.Message{first, second} {
+		if err := handler.HandleMessage(context.Background(), msg); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestHandleMessage_Attachments(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithAttachments(workspaces, domain.AttachmentPolicy{MaxBytes: 1024, AllowedTypes: []string{"text/*"}}),
+	)
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> what's wrong here?", "1.1", time.Now())
+	msg.Files = []domain.File{
+		{ID: "F1", Name: "app.log", MimeType: "text/plain", Size: 11, URL: "https://files.slack.com/F1"},
+		{ID: "F2", Name: "dump.zip", MimeType: "application/zip", Size: 100, URL: "https://files.slack.com/F2"},
+	}
+
+	// The session keeps the directory it started in, which the agent then runs in
+	workspaces.EXPECT().Workdir(gomock.Any(), msg.SessionKey(), "U123").Return("/srv/sessions/_/C123/1.1", nil)
+	slackRepo.EXPECT().DownloadFile(gomock.Any(), "https://files.slack.com/F1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, w io.Writer) error {
+			_, err := io.WriteString(w, "panic: oops")
+			return err
+		})
+	workspaces.EXPECT().SaveAttachment(gomock.Any(), msg.SessionKey(), "F1-app.log", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ domain.SessionKey, _ string, content io.Reader) (string, error) {
+			if data, _ := io.ReadAll(content); string(data) != "panic: oops" {
+				t.Errorf("unexpected attachment content %q", data)
+			}
+			return "/srv/sessions/_/C123/1.1/attachments/F1-app.log", nil
+		})
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "1.1").
+		DoAndReturn(func(_ context.Context, _, _, text, _ string) error {
+			if !strings.Contains(text, "dump.zip") {
+				t.Errorf("expected the skipped file to be reported, got %q", text)
+			}
+			return nil
+		})
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+			if session.Workdir != "/srv/sessions/_/C123/1.1" {
+				t.Errorf("expected the session to record its working directory, got %q", session.Workdir)
+			}
+			if !strings.Contains(message.Text, "/srv/sessions/_/C123/1.1/attachments/F1-app.log") {
+				t.Errorf("expected the prompt to mention the attachment, got %q", message.Text)
+			}
+			if strings.Contains(message.Text, "dump.zip") {
+				t.Errorf("expected the skipped file to be left out, got %q", message.Text)
+			}
+			return domain.NewAgentResult("", nil), nil
+		})
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_UploadsArtifacts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithArtifactUploads(workspaces, domain.ArtifactPolicy{Patterns: []string{"*.csv"}, MaxBytes: 1024}),
+	)
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> export the data", "1.1", time.Now())
+	now := time.Now()
+	before := domain.WorkspaceSnapshot{
+		"old.csv": {Path: "old.csv", Size: 10, ModTime: now},
+	}
+	after := domain.WorkspaceSnapshot{
+		"old.csv":        {Path: "old.csv", Size: 10, ModTime: now},
+		"out/report.csv": {Path: "out/report.csv", Size: 12, ModTime: now},
+		"export.py":      {Path: "export.py", Size: 100, ModTime: now},
+		"huge.csv":       {Path: "huge.csv", Size: 4096, ModTime: now},
+	}
+
+	gomock.InOrder(
+		workspaces.EXPECT().Workdir(gomock.Any(), msg.SessionKey(), "U123").Return("sessions/_/C123/1.1", nil),
+		workspaces.EXPECT().Snapshot(gomock.Any(), msg.SessionKey()).Return(before, nil),
+		agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(domain.NewAgentResult("Done.", nil), nil),
+		slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "Done.", "1.1").Return("", nil),
+		workspaces.EXPECT().Snapshot(gomock.Any(), msg.SessionKey()).Return(after, nil),
+	)
+	workspaces.EXPECT().Open(gomock.Any(), msg.SessionKey(), "out/report.csv").Return(io.NopCloser(strings.NewReader("a,b\n1,2\n")), nil)
+	slackRepo.EXPECT().UploadFile(gomock.Any(), "C123", "1.1", "report.csv", int64(12), gomock.Any()).Return(nil)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").
+		DoAndReturn(func(_ context.Context, _, text, _ string) (string, error) {
+			if !strings.Contains(text, "huge.csv") {
+				t.Errorf("expected the oversized file to be reported, got %q", text)
+			}
+			return "", nil
+		})
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_AttachmentTooLarge(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithAttachments(workspaces, domain.AttachmentPolicy{MaxBytes: 8}),
+	)
+
+	// The event does not tell the size, so the limit is enforced while the file is streamed to disk
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> what's wrong here?", "1.1", time.Now())
+	msg.Files = []domain.File{{ID: "F1", Name: "app.log", MimeType: "text/plain", URL: "https://files.slack.com/F1"}}
+	workspaces.EXPECT().Workdir(gomock.Any(), msg.SessionKey(), "U123").Return("", nil)
+	slackRepo.EXPECT().DownloadFile(gomock.Any(), "https://files.slack.com/F1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ string, w io.Writer) error {
+			_, err := io.WriteString(w, strings.Repeat("panic: oops\n", 1000))
+			return err
+		})
+	workspaces.EXPECT().SaveAttachment(gomock.Any(), msg.SessionKey(), "F1-app.log", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ domain.SessionKey, _ string, content io.Reader) (string, error) {
+			data, err := io.ReadAll(content)
+			if err == nil || len(data) > 9 {
+				t.Errorf("expected the download to stop past the limit, read %d bytes, %v", len(data), err)
+			}
+			return "", err
+		})
+	slackRepo.EXPECT().PostEphemeral(gomock.Any(), "C123", "U123", gomock.Any(), "1.1").
+		DoAndReturn(func(_ context.Context, _, _, text, _ string) error {
+			if !strings.Contains(text, "app.log (the file is too large)") {
+				t.Errorf("expected the file to be reported as too large, got %q", text)
+			}
+			return nil
+		})
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.NewAgentResult("", nil), nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_ThreadHistory(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithThreadHistory(60),
+	)
+
+	msg := domain.NewMessage("1.4", "U1", "C123", "<@UBOT> can you fix this?", "1.1", time.Now())
+	slackRepo.EXPECT().GetThreadReplies(gomock.Any(), "C123", "1.1", "1.4").Return([]*domain.Message{
+		domain.NewMessage("1.1", "U2", "C123", "this message is too old to fit into the budget", "1.1", time.Time{}),
+		domain.NewMessage("1.2", "U2", "C123", "deploy is failing", "1.1", time.Time{}),
+		domain.NewMessage("1.3", "U1", "C123", "<@U2> which job?", "1.1", time.Time{}),
+	}, nil)
+	slackRepo.EXPECT().GetUserName(gomock.Any(), "U1").Return("alice", nil)
+	slackRepo.EXPECT().GetUserName(gomock.Any(), "U2").Return("bob", nil)
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, _ *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+			expected := "Earlier messages in this Slack thread, oldest first:\n<transcript>\n" +
+				"(earlier messages omitted)\n[bob] deploy is failing\n[alice] @bob which job?\n</transcript>\n\n" +
+				"<@UBOT> can you fix this?"
+			if message.Text != expected {
+				t.Errorf("unexpected prompt:\n%s", message.Text)
+			}
+			return domain.NewAgentResult("", nil), nil
+		})
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestHandleMessage_ResumeFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithSessionRepository(sessions),
+		usecase.WithThreadHistory(1000),
+	)
+
+	msg := domain.NewMessage("1.3", "U1", "C123", "<@UBOT> and now?", "1.1", time.Now())
+	stored := domain.NewSession(msg.SessionKey(), time.Now().Add(-time.Hour))
+	stored.Start(time.Now().Add(-time.Hour))
+	stored.Finish(&domain.AgentResult{SessionID: "gone"}, time.Now().Add(-time.Hour))
+	sessions.EXPECT().Get(gomock.Any(), msg.SessionKey()).Return(stored, nil)
+	var saved *domain.Session
+	sessions.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, session *domain.Session) error {
+		copied := *session
+		saved = &copied
+		return nil
+	}).AnyTimes()
+
+	// The conversation was kept by a replica or pod that is gone, so the thread starts over with its history
+	failed := domain.NewAgentResult("", &domain.AgentError{Kind: domain.AgentErrorResume, Message: "conversation gone not found"})
+	failed.PostedTS = []string{"1.4"}
+	gomock.InOrder(
+		agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(failed, nil),
+		slackRepo.EXPECT().DeleteMessage(gomock.Any(), "C123", "1.4").Return(nil),
+		slackRepo.EXPECT().GetThreadReplies(gomock.Any(), "C123", "1.1", "1.3").Return([]*domain.Message{
+			domain.NewMessage("1.2", "U2", "C123", "deploy is failing", "1.1", time.Time{}),
+		}, nil),
+		agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+				if session.AgentSessionID != "" || !strings.Contains(message.Text, "deploy is failing") {
+					t.Errorf("expected a fresh run with the thread history, got session %q and prompt %q", session.AgentSessionID, message.Text)
+				}
+				result := domain.NewAgentResult("done", nil)
+				result.SessionID = "fresh"
+				return result, nil
+			}),
+	)
+	slackRepo.EXPECT().GetUserName(gomock.Any(), "U2").Return("bob", nil)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "done", "1.1").Return("1.5", nil)
+
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if saved == nil || saved.AgentSessionID != "fresh" || saved.Status != domain.SessionIdle {
+		t.Errorf("expected the session to continue the fresh conversation, got %+v", saved)
+	}
+}
+
+func TestHandleMessage_FollowsThread(t *testing.T) {
+	now := time.Now()
+	key := domain.SessionKey{ChannelID: "C123", ThreadTS: "1.1"}
+	active := domain.NewSession(key, now.Add(-time.Minute))
+	idle := domain.NewSession(key, now.Add(-time.Hour))
+	dismissed := domain.NewSession(key, now.Add(-time.Minute))
+	dismissed.Dismiss(now.Add(-time.Minute))
+
+	tests := []struct {
+		name    string
+		enabled bool
+		session *domain.Session
+		handled bool
+	}{
+		{name: "active session", enabled: true, session: active, handled: true},
+		{name: "following disabled", enabled: false, session: active},
+		{name: "idle session", enabled: true, session: idle},
+		{name: "dismissed session", enabled: true, session: dismissed},
+		{name: "no session", enabled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			slackRepo := mocks.NewMockSlackRepository(ctrl)
+			agentRepo := mocks.NewMockAgentRepository(ctrl)
+			sessions := mocks.NewMockSessionRepository(ctrl)
+			handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+				usecase.WithSessionRepository(sessions),
+				usecase.WithThreadFollowing(tt.enabled, 30*time.Minute),
+			)
+
+			msg := domain.NewMessage("1.2", "U123", "C123", "and what about staging?", "1.1", now)
+			if tt.enabled {
+				stored := tt.session
+				if stored == nil {
+					sessions.EXPECT().Get(gomock.Any(), key).Return(nil, domain.ErrSessionNotFound)
+				} else {
+					copied := *stored
+					sessions.EXPECT().Get(gomock.Any(), key).Return(&copied, nil).AnyTimes()
+				}
+			}
+			if tt.handled {
+				sessions.EXPECT().Save(gomock.Any(), gomock.Any()).Return(nil).Times(2)
+				agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).Return(domain.NewAgentResult("", nil), nil)
+			}
+
+			if err := handler.HandleMessage(context.Background(), msg); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleMessage_ByeEndsFollowedThread(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"),
+		usecase.WithSessionRepository(sessions),
+		usecase.WithThreadFollowing(true, 30*time.Minute),
+	)
+
+	key := domain.SessionKey{ChannelID: "C123", ThreadTS: "1.1"}
+	sessions.EXPECT().Get(gomock.Any(), key).DoAndReturn(func(context.Context, domain.SessionKey) (*domain.Session, error) {
+		return domain.NewSession(key, time.Now()), nil
+	}).Times(2)
+	sessions.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, session *domain.Session) error {
+		if !session.Dismissed {
+			t.Error("expected the session to be dismissed")
+		}
+		return nil
+	})
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").Return("", nil)
+
+	if err := handler.HandleMessage(context.Background(), domain.NewMessage("1.2", "U123", "C123", "Bye!", "1.1", time.Now())); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
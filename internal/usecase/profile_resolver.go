@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// channelNameRetryInterval is how long a failed channel name lookup is remembered before it is retried
+const channelNameRetryInterval = time.Minute
+
+// ProfileResolver finds the agent profile for a channel.
+// Profiles may be keyed by channel name, so names are looked up once and cached.
+type ProfileResolver struct {
+	profiles  *domain.Profiles
+	slackRepo SlackRepository
+
+	mu    sync.Mutex
+	names map[string]string
+	// failures holds when the name lookup of a channel last failed
+	failures map[string]time.Time
+}
+
+// NewProfileResolver creates a new ProfileResolver instance.
+// slackRepo may be nil, in which case profiles can only be matched by channel ID.
+func NewProfileResolver(profiles *domain.Profiles, slackRepo SlackRepository) *ProfileResolver {
+	return &ProfileResolver{
+		profiles:  profiles,
+		slackRepo: slackRepo,
+		names:     make(map[string]string),
+		failures:  make(map[string]time.Time),
+	}
+}
+
+// Resolve returns the profile for the channel, or the default profile
+func (r *ProfileResolver) Resolve(ctx context.Context, channelID string) *domain.Profile {
+	if !r.profiles.HasChannelProfiles() {
+		return r.profiles.Default()
+	}
+	// Avoid the name lookup when the channel has a profile under its ID
+	if profile := r.profiles.Lookup(channelID, ""); profile != r.profiles.Default() {
+		return profile
+	}
+	return r.profiles.Lookup(channelID, r.channelName(ctx, channelID))
+}
+
+// HasToolRules reports whether any profile has tool rules
+func (r *ProfileResolver) HasToolRules() bool {
+	return r.profiles.HasToolRules()
+}
+
+// channelName returns the cached name of the channel, looking it up on first use.
+// Failed lookups are retried after channelNameRetryInterval.
+func (r *ProfileResolver) channelName(ctx context.Context, channelID string) string {
+	if r.slackRepo == nil {
+		return ""
+	}
+
+	r.mu.Lock()
+	name, ok := r.names[channelID]
+	failedAt, failed := r.failures[channelID]
+	r.mu.Unlock()
+	if ok {
+		return name
+	}
+	// Remember failures for a while so that every message does not hit the API again
+	if failed && time.Since(failedAt) < channelNameRetryInterval {
+		return ""
+	}
+
+	name, err := r.slackRepo.GetChannelName(ctx, channelID)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		log.Printf("Error looking up name of channel %s: %v", channelID, err)
+		r.failures[channelID] = time.Now()
+		return ""
+	}
+	delete(r.failures, channelID)
+	r.names[channelID] = name
+	return name
+}
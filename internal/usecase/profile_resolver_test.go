@@ -0,0 +1,48 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func TestProfileResolver_Resolve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	profiles := domain.NewProfiles(domain.Profile{SystemPrompt: "default"}, map[string]domain.Profile{
+		"infra": {SystemPrompt: "infra"},
+		"C222":  {SystemPrompt: "by id"},
+	})
+	resolver := usecase.NewProfileResolver(profiles, slackRepo)
+
+	// Names are looked up once per channel; failures are only retried after a while
+	slackRepo.EXPECT().GetChannelName(gomock.Any(), "C111").Return("infra", nil).Times(1)
+	slackRepo.EXPECT().GetChannelName(gomock.Any(), "C333").Return("", errors.New("channel_not_found")).Times(1)
+
+	for i := 0; i < 2; i++ {
+		if got := resolver.Resolve(context.Background(), "C111").SystemPrompt; got != "infra" {
+			t.Errorf("expected infra profile, got %q", got)
+		}
+		if got := resolver.Resolve(context.Background(), "C222").SystemPrompt; got != "by id" {
+			t.Errorf("expected profile matched by ID, got %q", got)
+		}
+		if got := resolver.Resolve(context.Background(), "C333").SystemPrompt; got != "default" {
+			t.Errorf("expected default profile, got %q", got)
+		}
+	}
+}
+
+func TestProfileResolver_NoChannelProfiles(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	resolver := usecase.NewProfileResolver(domain.NewProfiles(domain.Profile{SystemPrompt: "default"}, nil), slackRepo)
+
+	if got := resolver.Resolve(context.Background(), "C111").Name; got != domain.DefaultProfileName {
+		t.Errorf("expected default profile, got %q", got)
+	}
+}
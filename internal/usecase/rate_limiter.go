@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// rateLimitCompactInterval is how often buckets of inactive users and channels are dropped
+const rateLimitCompactInterval = time.Hour
+
+// Rate limit scopes
+const (
+	RateLimitScopeUser      = "user"
+	RateLimitScopeChannel   = "channel"
+	RateLimitScopeWorkspace = "workspace"
+)
+
+// RateLimits holds the limits applied to each user, each channel and each workspace.
+// A zero limit disables the scope.
+type RateLimits struct {
+	User      domain.RateLimit
+	Channel   domain.RateLimit
+	Workspace domain.RateLimit
+}
+
+// RateLimitDecision is the outcome of a rate limit check
+type RateLimitDecision struct {
+	Allowed bool
+	// Scope and Limit name the limit that was hit
+	Scope string
+	Limit domain.RateLimit
+	// RetryAt is when the request would be allowed
+	RetryAt time.Time
+}
+
+// RateLimiter throttles agent requests with token buckets per user, channel and workspace.
+// Buckets are kept in a RateLimitRepository so that limits survive restarts.
+type RateLimiter struct {
+	repo   RateLimitRepository
+	limits RateLimits
+
+	mu          sync.Mutex
+	compactedAt time.Time
+}
+
+// NewRateLimiter creates a new RateLimiter instance
+func NewRateLimiter(repo RateLimitRepository, limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		repo:   repo,
+		limits: limits,
+	}
+}
+
+// rateLimitBucket is a bucket taking part in a check
+type rateLimitBucket struct {
+	scope  string
+	key    string
+	limit  domain.RateLimit
+	bucket *domain.TokenBucket
+}
+
+// Allow takes one request from the message's user, channel and workspace buckets.
+// Nothing is taken unless every bucket has a request left; the decision then names the
+// limit that was hit and when the request could be retried.
+func (l *RateLimiter) Allow(ctx context.Context, message *domain.Message, now time.Time) (RateLimitDecision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.compact(ctx, now); err != nil {
+		return RateLimitDecision{Allowed: true}, err
+	}
+
+	var buckets []rateLimitBucket
+	for _, b := range []rateLimitBucket{
+		{scope: RateLimitScopeUser, key: fmt.Sprintf("user/%s/%s", message.TeamID, message.UserID), limit: l.limits.User},
+		{scope: RateLimitScopeChannel, key: fmt.Sprintf("channel/%s/%s", message.TeamID, message.ChannelID), limit: l.limits.Channel},
+		{scope: RateLimitScopeWorkspace, key: fmt.Sprintf("workspace/%s", message.TeamID), limit: l.limits.Workspace},
+	} {
+		if b.limit.IsZero() {
+			continue
+		}
+		bucket, err := l.repo.Get(ctx, b.key)
+		if err != nil {
+			return RateLimitDecision{Allowed: true}, fmt.Errorf("failed to load rate limit %s: %w", b.key, err)
+		}
+		if bucket == nil {
+			bucket = domain.NewTokenBucket(b.limit, now)
+		}
+		bucket.Refill(b.limit, now)
+		b.bucket = bucket
+		buckets = append(buckets, b)
+	}
+
+	// Report the limit that lasts the longest so that the retry time is accurate
+	decision := RateLimitDecision{Allowed: true}
+	for _, b := range buckets {
+		if retryAfter := b.bucket.RetryAfter(b.limit); retryAfter > 0 && now.Add(retryAfter).After(decision.RetryAt) {
+			decision = RateLimitDecision{Scope: b.scope, Limit: b.limit, RetryAt: now.Add(retryAfter)}
+		}
+	}
+	if !decision.Allowed {
+		return decision, nil
+	}
+
+	for _, b := range buckets {
+		b.bucket.Take()
+		if err := l.repo.Save(ctx, b.key, b.bucket); err != nil {
+			return decision, fmt.Errorf("failed to save rate limit %s: %w", b.key, err)
+		}
+	}
+	return decision, nil
+}
+
+// compact drops buckets that have been idle long enough to be full again.
+// A missing bucket is treated as a full one, so this does not change any decision.
+func (l *RateLimiter) compact(ctx context.Context, now time.Time) error {
+	if now.Sub(l.compactedAt) < rateLimitCompactInterval {
+		return nil
+	}
+	l.compactedAt = now
+
+	var longest time.Duration
+	for _, limit := range []domain.RateLimit{l.limits.User, l.limits.Channel, l.limits.Workspace} {
+		if !limit.IsZero() && limit.Per > longest {
+			longest = limit.Per
+		}
+	}
+	if err := l.repo.DeleteIdle(ctx, now.Add(-longest)); err != nil {
+		return fmt.Errorf("failed to remove idle rate limits: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+)
+
+// memRateLimitRepository keeps token buckets in memory
+type memRateLimitRepository struct {
+	mu      sync.Mutex
+	buckets map[string]domain.TokenBucket
+	err     error
+}
+
+func newMemRateLimitRepository() *memRateLimitRepository {
+	return &memRateLimitRepository{buckets: make(map[string]domain.TokenBucket)}
+}
+
+func (r *memRateLimitRepository) Get(ctx context.Context, key string) (*domain.TokenBucket, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	bucket, ok := r.buckets[key]
+	if !ok {
+		return nil, nil
+	}
+	return &bucket, nil
+}
+
+func (r *memRateLimitRepository) Save(ctx context.Context, key string, bucket *domain.TokenBucket) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buckets[key] = *bucket
+	return nil
+}
+
+func (r *memRateLimitRepository) DeleteIdle(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, bucket := range r.buckets {
+		if bucket.UpdatedAt.Before(before) {
+			delete(r.buckets, key)
+		}
+	}
+	return nil
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	message := func(userID, channelID string) *domain.Message {
+		msg := domain.NewMessage("", userID, channelID, "<@UBOT> hi", "1.1", now)
+		msg.TeamID = "T1"
+		return msg
+	}
+
+	tests := []struct {
+		name     string
+		limits   usecase.RateLimits
+		messages []*domain.Message
+		// at is the offset from now at which the last message is sent
+		at              time.Duration
+		expectedAllowed bool
+		expectedScope   string
+		expectedRetry   time.Duration
+	}{
+		{
+			name:            "no limits",
+			messages:        []*domain.Message{message("U1", "C1"), message("U1", "C1"), message("U1", "C1")},
+			expectedAllowed: true,
+		},
+		{
+			name:            "user limit",
+			limits:          usecase.RateLimits{User: domain.RateLimit{Requests: 2, Per: time.Hour}},
+			messages:        []*domain.Message{message("U1", "C1"), message("U1", "C2"), message("U1", "C3")},
+			expectedScope:   usecase.RateLimitScopeUser,
+			expectedRetry:   30 * time.Minute,
+			expectedAllowed: false,
+		},
+		{
+			name:            "user limit is per user",
+			limits:          usecase.RateLimits{User: domain.RateLimit{Requests: 1, Per: time.Hour}},
+			messages:        []*domain.Message{message("U1", "C1"), message("U2", "C1")},
+			expectedAllowed: true,
+		},
+		{
+			name:            "user limit refills",
+			limits:          usecase.RateLimits{User: domain.RateLimit{Requests: 2, Per: time.Hour}},
+			messages:        []*domain.Message{message("U1", "C1"), message("U1", "C1"), message("U1", "C1")},
+			at:              30 * time.Minute,
+			expectedAllowed: true,
+		},
+		{
+			name:            "channel limit",
+			limits:          usecase.RateLimits{Channel: domain.RateLimit{Requests: 1, Per: time.Minute}},
+			messages:        []*domain.Message{message("U1", "C1"), message("U2", "C1")},
+			expectedScope:   usecase.RateLimitScopeChannel,
+			expectedRetry:   time.Minute,
+			expectedAllowed: false,
+		},
+		{
+			name: "the longest wait is reported",
+			limits: usecase.RateLimits{
+				User:      domain.RateLimit{Requests: 1, Per: time.Minute},
+				Workspace: domain.RateLimit{Requests: 1, Per: time.Hour},
+			},
+			messages:        []*domain.Message{message("U1", "C1"), message("U1", "C2")},
+			expectedScope:   usecase.RateLimitScopeWorkspace,
+			expectedRetry:   time.Hour,
+			expectedAllowed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := usecase.NewRateLimiter(newMemRateLimitRepository(), tt.limits)
+
+			var decision usecase.RateLimitDecision
+			for i, msg := range tt.messages {
+				at := now
+				if i == len(tt.messages)-1 {
+					at = now.Add(tt.at)
+				}
+				var err error
+				decision, err = limiter.Allow(context.Background(), msg, at)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if decision.Allowed != tt.expectedAllowed {
+				t.Fatalf("expected allowed %v, got %+v", tt.expectedAllowed, decision)
+			}
+			if decision.Scope != tt.expectedScope {
+				t.Errorf("expected scope %q, got %q", tt.expectedScope, decision.Scope)
+			}
+			if !tt.expectedAllowed && !decision.RetryAt.Equal(now.Add(tt.expectedRetry)) {
+				t.Errorf("expected retry at %v, got %v", now.Add(tt.expectedRetry), decision.RetryAt)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_DeniedRequestsTakeNothing(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := newMemRateLimitRepository()
+	limiter := usecase.NewRateLimiter(repo, usecase.RateLimits{
+		User:    domain.RateLimit{Requests: 5, Per: time.Hour},
+		Channel: domain.RateLimit{Requests: 1, Per: time.Hour},
+	})
+
+	for _, channelID := range []string{"C1", "C1", "C1"} {
+		if _, err := limiter.Allow(context.Background(), domain.NewMessage("", "U1", channelID, "hi", "1.1", now), now); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Only the first request counts against the user
+	if bucket := repo.buckets["user//U1"]; bucket.Tokens != 4 {
+		t.Errorf("expected 4 requests left for the user, got %v", bucket.Tokens)
+	}
+}
+
+func TestRateLimiter_RepositoryFailure(t *testing.T) {
+	repo := newMemRateLimitRepository()
+	repo.err = errors.New("disk full")
+	limiter := usecase.NewRateLimiter(repo, usecase.RateLimits{User: domain.RateLimit{Requests: 1, Per: time.Hour}})
+
+	decision, err := limiter.Allow(context.Background(), domain.NewMessage("", "U1", "C1", "hi", "1.1", time.Now()), time.Now())
+	if err == nil {
+		t.Error("expected error but got none")
+	}
+	if !decision.Allowed {
+		t.Error("expected the request to be allowed when the limiter state is unavailable")
+	}
+}
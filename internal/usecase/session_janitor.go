@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// RetentionPolicy limits how many agent sessions are kept and for how long.
+// A zero value disables the corresponding limit.
+type RetentionPolicy struct {
+	// TTL evicts sessions that have been inactive for longer than this
+	TTL time.Duration
+	// MaxTotalBytes bounds the disk usage of all session working directories
+	MaxTotalBytes int64
+	// MaxSessions bounds the number of stored sessions
+	MaxSessions int
+}
+
+// PruneReason explains why a session was evicted
+type PruneReason string
+
+const (
+	// PruneExpired means the session was inactive for longer than the TTL
+	PruneExpired PruneReason = "expired"
+	// PruneMaxSessions means there were more sessions than allowed
+	PruneMaxSessions PruneReason = "max sessions"
+	// PruneMaxDisk means the sessions used more disk space than allowed
+	PruneMaxDisk PruneReason = "max disk"
+)
+
+// PrunedSession is a session evicted by the janitor
+type PrunedSession struct {
+	Session *domain.Session
+	Bytes   int64
+	Reason  PruneReason
+}
+
+// PruneReport summarizes a pass of the janitor
+type PruneReport struct {
+	Pruned         []PrunedSession
+	Remaining      int
+	RemainingBytes int64
+}
+
+// SessionJanitor evicts agent sessions according to a retention policy.
+// Eviction is least recently used first and never touches sessions that are running.
+type SessionJanitor struct {
+	sessions   SessionRepository
+	workspaces WorkspaceRepository
+	policy     RetentionPolicy
+	cluster    *Cluster
+}
+
+// NewSessionJanitor creates a new SessionJanitor instance
+func NewSessionJanitor(sessions SessionRepository, workspaces WorkspaceRepository, policy RetentionPolicy) *SessionJanitor {
+	return &SessionJanitor{
+		sessions:   sessions,
+		workspaces: workspaces,
+		policy:     policy,
+	}
+}
+
+// SetCluster makes the janitor leave the sessions of threads owned by other replicas alone
+func (j *SessionJanitor) SetCluster(cluster *Cluster) {
+	j.cluster = cluster
+}
+
+// Run prunes sessions every interval until the context is cancelled
+func (j *SessionJanitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := j.Prune(ctx, time.Now(), false)
+		if err != nil {
+			log.Printf("Error pruning sessions: %v", err)
+		} else if len(report.Pruned) > 0 {
+			log.Printf("Pruned %d sessions; %d sessions using %d bytes remain", len(report.Pruned), report.Remaining, report.RemainingBytes)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecoverSessions marks the sessions that are still stored as running as failed.
+// It is called at startup, when no agent of this process runs yet, so those runs ended with
+// a previous process, e.g. on a crash or a drain timeout, and would otherwise never be pruned or reset.
+// With a cluster, runs in threads that another live replica owns are left alone.
+func RecoverSessions(ctx context.Context, sessions SessionRepository, cluster *Cluster) (int, error) {
+	list, err := sessions.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	recovered := 0
+	var errs []error
+	for _, session := range list {
+		if session.Status != domain.SessionRunning || cluster != nil && cluster.owner(ctx, session.Key) != "" {
+			continue
+		}
+		session.Abandon()
+		if err := sessions.Save(ctx, session); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		recovered++
+	}
+	return recovered, errors.Join(errs...)
+}
+
+// Prune applies the retention policy once.
+// With dryRun set it only reports what would be evicted.
+func (j *SessionJanitor) Prune(ctx context.Context, now time.Time, dryRun bool) (*PruneReport, error) {
+	sessions, err := j.sessions.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Least recently used first
+	sort.Slice(sessions, func(a, b int) bool {
+		return sessions[a].LastActivity.Before(sessions[b].LastActivity)
+	})
+
+	report := &PruneReport{}
+	sizes := make(map[domain.SessionKey]int64, len(sessions))
+	for _, session := range sessions {
+		size, err := j.workspaces.Size(ctx, session.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to measure session %s: %w", session.Key, err)
+		}
+		sizes[session.Key] = size
+		report.RemainingBytes += size
+	}
+	report.Remaining = len(sessions)
+
+	for _, session := range sessions {
+		if j.cluster != nil {
+			elsewhere, err := j.ownedElsewhere(ctx, session, dryRun)
+			if err != nil {
+				return report, err
+			}
+			if elsewhere {
+				continue
+			}
+		}
+		if session.Status == domain.SessionRunning {
+			continue
+		}
+
+		var reason PruneReason
+		switch {
+		case j.policy.TTL > 0 && now.Sub(session.LastActivity) > j.policy.TTL:
+			reason = PruneExpired
+		case j.policy.MaxSessions > 0 && report.Remaining > j.policy.MaxSessions:
+			reason = PruneMaxSessions
+		case j.policy.MaxTotalBytes > 0 && report.RemainingBytes > j.policy.MaxTotalBytes:
+			reason = PruneMaxDisk
+		default:
+			continue
+		}
+
+		if !dryRun {
+			// The session may have been picked up again since it was listed
+			current, err := j.sessions.Get(ctx, session.Key)
+			if err != nil && !errors.Is(err, domain.ErrSessionNotFound) {
+				return report, err
+			}
+			if current != nil && (current.Status == domain.SessionRunning || current.LastActivity.After(session.LastActivity)) {
+				continue
+			}
+			if err := j.workspaces.Remove(ctx, session.Key); err != nil {
+				return report, fmt.Errorf("failed to remove session %s: %w", session.Key, err)
+			}
+			if err := j.sessions.Delete(ctx, session.Key); err != nil {
+				return report, err
+			}
+		}
+		report.Pruned = append(report.Pruned, PrunedSession{Session: session, Bytes: sizes[session.Key], Reason: reason})
+		report.Remaining--
+		report.RemainingBytes -= sizes[session.Key]
+	}
+
+	return report, nil
+}
+
+// ownedElsewhere reports whether another replica owns the session's thread, so that the session is its to prune.
+// Runs that no live replica holds anymore ended with a replica that is gone, so they are marked failed.
+func (j *SessionJanitor) ownedElsewhere(ctx context.Context, session *domain.Session, dryRun bool) (bool, error) {
+	owner := j.cluster.locate(ctx, session.Key)
+	if owner != "" {
+		return owner != j.cluster.replica, nil
+	}
+	if session.Abandon() && !dryRun {
+		if err := j.sessions.Save(ctx, session); err != nil {
+			return false, err
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,245 @@
+package usecase_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+func TestSessionJanitor_Prune(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	// newSession returns a session last used the given number of hours ago
+	newSession := func(thread string, hoursAgo int, status domain.SessionStatus) *domain.Session {
+		session := domain.NewSession(domain.SessionKey{TeamID: "T1", ChannelID: "C1", ThreadTS: thread}, now.Add(-time.Duration(hoursAgo)*time.Hour))
+		session.Status = status
+		return session
+	}
+
+	tests := []struct {
+		name     string
+		policy   usecase.RetentionPolicy
+		sessions []*domain.Session
+		sizes    map[string]int64
+		expected []string
+	}{
+		{
+			name:   "ttl",
+			policy: usecase.RetentionPolicy{TTL: 24 * time.Hour},
+			sessions: []*domain.Session{
+				newSession("1", 48, domain.SessionIdle),
+				newSession("2", 1, domain.SessionIdle),
+				newSession("3", 72, domain.SessionFailed),
+			},
+			expected: []string{"3", "1"},
+		},
+		{
+			name:   "max sessions evicts least recently used",
+			policy: usecase.RetentionPolicy{MaxSessions: 2},
+			sessions: []*domain.Session{
+				newSession("1", 3, domain.SessionIdle),
+				newSession("2", 1, domain.SessionIdle),
+				newSession("3", 5, domain.SessionIdle),
+				newSession("4", 4, domain.SessionIdle),
+			},
+			expected: []string{"3", "4"},
+		},
+		{
+			name:   "max disk",
+			policy: usecase.RetentionPolicy{MaxTotalBytes: 100},
+			sessions: []*domain.Session{
+				newSession("1", 3, domain.SessionIdle),
+				newSession("2", 2, domain.SessionIdle),
+				newSession("3", 1, domain.SessionIdle),
+			},
+			sizes:    map[string]int64{"1": 60, "2": 30, "3": 30},
+			expected: []string{"1"},
+		},
+		{
+			name:   "running sessions are never evicted",
+			policy: usecase.RetentionPolicy{TTL: time.Hour, MaxSessions: 1},
+			sessions: []*domain.Session{
+				newSession("1", 48, domain.SessionRunning),
+				newSession("2", 24, domain.SessionIdle),
+			},
+			expected: []string{"2"},
+		},
+		{
+			name:   "no limits",
+			policy: usecase.RetentionPolicy{},
+			sessions: []*domain.Session{
+				newSession("1", 10000, domain.SessionIdle),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, dryRun := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/dry run %v", tt.name, dryRun), func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				sessions := mocks.NewMockSessionRepository(ctrl)
+				workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+
+				sessions.EXPECT().List(gomock.Any()).Return(tt.sessions, nil)
+				workspaces.EXPECT().Size(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, key domain.SessionKey) (int64, error) {
+						return tt.sizes[key.ThreadTS], nil
+					}).Times(len(tt.sessions))
+
+				var removed []string
+				if !dryRun {
+					sessions.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, domain.ErrSessionNotFound).AnyTimes()
+					workspaces.EXPECT().Remove(gomock.Any(), gomock.Any()).DoAndReturn(
+						func(ctx context.Context, key domain.SessionKey) error {
+							removed = append(removed, key.ThreadTS)
+							return nil
+						}).Times(len(tt.expected))
+					sessions.EXPECT().Delete(gomock.Any(), gomock.Any()).Return(nil).Times(len(tt.expected))
+				}
+
+				janitor := usecase.NewSessionJanitor(sessions, workspaces, tt.policy)
+				report, err := janitor.Prune(context.Background(), now, dryRun)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				var pruned []string
+				for _, p := range report.Pruned {
+					pruned = append(pruned, p.Session.Key.ThreadTS)
+				}
+				if !equalStrings(pruned, tt.expected) {
+					t.Errorf("expected %v to be pruned, got %v", tt.expected, pruned)
+				}
+				if !dryRun && !equalStrings(removed, tt.expected) {
+					t.Errorf("expected %v to be removed, got %v", tt.expected, removed)
+				}
+				if report.Remaining != len(tt.sessions)-len(tt.expected) {
+					t.Errorf("expected %d remaining sessions, got %d", len(tt.sessions)-len(tt.expected), report.Remaining)
+				}
+			})
+		}
+	}
+}
+
+func TestSessionJanitor_SkipsSessionsResumedSinceListing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+
+	now := time.Now()
+	key := domain.SessionKey{ChannelID: "C1", ThreadTS: "1"}
+	listed := domain.NewSession(key, now.Add(-48*time.Hour))
+	current := domain.NewSession(key, now.Add(-48*time.Hour))
+	current.Start(now)
+
+	sessions.EXPECT().List(gomock.Any()).Return([]*domain.Session{listed}, nil)
+	workspaces.EXPECT().Size(gomock.Any(), key).Return(int64(0), nil)
+	sessions.EXPECT().Get(gomock.Any(), key).Return(current, nil)
+
+	janitor := usecase.NewSessionJanitor(sessions, workspaces, usecase.RetentionPolicy{TTL: time.Hour})
+	report, err := janitor.Prune(context.Background(), now, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Pruned) != 0 {
+		t.Errorf("expected the running session to be kept, got %d pruned", len(report.Pruned))
+	}
+}
+
+func TestSessionJanitor_PrunesSessionsInterruptedByPreviousProcess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+
+	now := time.Now()
+	key := domain.SessionKey{ChannelID: "C1", ThreadTS: "1"}
+	stale := domain.NewSession(key, now.Add(-48*time.Hour))
+	stale.Start(now.Add(-48 * time.Hour))
+	idle := domain.NewSession(domain.SessionKey{ChannelID: "C1", ThreadTS: "2"}, now)
+
+	// The previous process died while the agent was running
+	sessions.EXPECT().List(gomock.Any()).Return([]*domain.Session{stale, idle}, nil).Times(2)
+	sessions.EXPECT().Save(gomock.Any(), stale).Return(nil)
+	recovered, err := usecase.RecoverSessions(context.Background(), sessions, nil)
+	if err != nil || recovered != 1 {
+		t.Fatalf("expected 1 recovered session, got %d, %v", recovered, err)
+	}
+	if stale.Status != domain.SessionFailed {
+		t.Errorf("expected the session to be marked failed, got %s", stale.Status)
+	}
+
+	workspaces.EXPECT().Size(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(2)
+	sessions.EXPECT().Get(gomock.Any(), key).Return(stale, nil)
+	workspaces.EXPECT().Remove(gomock.Any(), key).Return(nil)
+	sessions.EXPECT().Delete(gomock.Any(), key).Return(nil)
+
+	janitor := usecase.NewSessionJanitor(sessions, workspaces, usecase.RetentionPolicy{TTL: time.Hour})
+	report, err := janitor.Prune(context.Background(), now, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Pruned) != 1 || report.Pruned[0].Session.Key != key {
+		t.Errorf("expected the interrupted session to be pruned, got %+v", report.Pruned)
+	}
+}
+
+func TestSessionJanitor_InCluster(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := mocks.NewMockSessionRepository(ctrl)
+	workspaces := mocks.NewMockWorkspaceRepository(ctrl)
+	leases := mocks.NewMockLeaseRepository(ctrl)
+	cluster := usecase.NewCluster("a", leases, mocks.NewMockHandoffRepository(ctrl), time.Minute)
+
+	now := time.Now()
+	elsewhere := domain.NewSession(domain.SessionKey{ChannelID: "C1", ThreadTS: "1"}, now.Add(-48*time.Hour))
+	elsewhere.Start(now.Add(-48 * time.Hour))
+	orphaned := domain.NewSession(domain.SessionKey{ChannelID: "C1", ThreadTS: "2"}, now.Add(-48*time.Hour))
+	orphaned.Start(now.Add(-48 * time.Hour))
+	sessions.EXPECT().List(gomock.Any()).Return([]*domain.Session{elsewhere, orphaned}, nil).Times(2)
+
+	// Replica b is still running the first thread; nobody holds the second one anymore
+	leases.EXPECT().Holder(gomock.Any(), "thread/"+elsewhere.Key.String()).Return("b", nil).Times(2)
+	leases.EXPECT().Holder(gomock.Any(), "replica/b").Return("b", nil).Times(2)
+	leases.EXPECT().Holder(gomock.Any(), "thread/"+orphaned.Key.String()).Return("", nil).Times(3)
+
+	// A replica starting up leaves the run of replica b alone
+	sessions.EXPECT().Save(gomock.Any(), orphaned).Return(nil)
+	recovered, err := usecase.RecoverSessions(context.Background(), sessions, cluster)
+	if err != nil || recovered != 1 || elsewhere.Status != domain.SessionRunning {
+		t.Fatalf("expected only the orphaned session to be recovered, got %d, %v", recovered, err)
+	}
+
+	workspaces.EXPECT().Size(gomock.Any(), gomock.Any()).Return(int64(0), nil).Times(2)
+	sessions.EXPECT().Get(gomock.Any(), orphaned.Key).Return(orphaned, nil)
+	workspaces.EXPECT().Remove(gomock.Any(), orphaned.Key).Return(nil)
+	sessions.EXPECT().Delete(gomock.Any(), orphaned.Key).Return(nil)
+
+	janitor := usecase.NewSessionJanitor(sessions, workspaces, usecase.RetentionPolicy{TTL: time.Hour})
+	janitor.SetCluster(cluster)
+	report, err := janitor.Prune(context.Background(), now, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Pruned) != 1 || report.Pruned[0].Session.Key != orphaned.Key {
+		t.Errorf("expected only the orphaned session to be pruned, got %+v", report.Pruned)
+	}
+}
+
+// equalStrings reports whether two string slices have the same elements in order
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
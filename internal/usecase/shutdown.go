@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// restartingText is posted to threads whose run was stopped or never started because the bot shuts down
+const restartingText = "🔄 The bot is restarting, please retry in a moment."
+
+// drainState tracks the threads being worked on so that a shutdown can wait for them
+type drainState struct {
+	mu       sync.Mutex
+	closing  bool
+	active   int
+	idle     chan struct{}
+	notified map[string]bool
+}
+
+// newDrainState creates a new drainState instance
+func newDrainState() *drainState {
+	return &drainState{notified: make(map[string]bool)}
+}
+
+// begin records that a thread is being worked on
+func (s *drainState) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == 0 {
+		s.idle = make(chan struct{})
+	}
+	s.active++
+}
+
+// end records that a thread is no longer being worked on
+func (s *drainState) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.active == 0 {
+		close(s.idle)
+	}
+}
+
+// close stops new runs from starting
+func (s *drainState) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closing = true
+}
+
+// isClosing reports whether the bot is shutting down
+func (s *drainState) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// notify reports whether the thread still has to be told about the restart, so that it is told only once
+func (s *drainState) notify(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.notified[key] {
+		return false
+	}
+	s.notified[key] = true
+	return true
+}
+
+// wait waits until no thread is being worked on or the context ends
+func (s *drainState) wait(ctx context.Context) error {
+	s.mu.Lock()
+	if s.active == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	idle := s.idle
+	s.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops starting new agent runs and waits up to the drain timeout for the running ones.
+// Runs still going after that are cancelled and their threads are asked to retry.
+// It returns once every thread has been dealt with or the context ends.
+func (h *messageHandlerImpl) Shutdown(ctx context.Context, drain time.Duration) error {
+	h.drain.close()
+	if h.cluster != nil {
+		// Other replicas take over the threads once this one is done with them
+		defer h.cluster.releaseAll(context.Background())
+	}
+
+	drainCtx, cancel := context.WithTimeout(ctx, drain)
+	defer cancel()
+	if err := h.drain.wait(drainCtx); err == nil {
+		log.Println("All agent runs finished")
+		return nil
+	}
+
+	cancelled := h.agentRepo.CancelAll()
+	log.Printf("Drain timeout reached; cancelled %d agent runs", cancelled)
+	return h.drain.wait(ctx)
+}
+
+// postRestarting asks the thread to retry once the bot is back
+func (h *messageHandlerImpl) postRestarting(ctx context.Context, message *domain.Message) error {
+	if !h.drain.notify(message.SessionKey().String()) {
+		return nil
+	}
+	return h.reply(ctx, message.ChannelID, restartingText, message.ThreadTS)
+}
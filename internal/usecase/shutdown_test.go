@@ -0,0 +1,95 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+	"github.com/takutakahashi/slack-agent/internal/mocks"
+	"github.com/takutakahashi/slack-agent/internal/usecase"
+	"go.uber.org/mock/gomock"
+)
+
+const restartingText = "🔄 The bot is restarting, please retry in a moment."
+
+func TestShutdown_DrainsRunningAgents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), msg).DoAndReturn(
+		func(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+			close(started)
+			<-finish
+			return domain.NewAgentResult("done", nil), nil
+		})
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", "done", "1.1").Return("", nil)
+
+	go func() { _ = handler.HandleMessage(context.Background(), msg) }()
+	<-started
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(finish)
+	}()
+	if err := handler.Shutdown(context.Background(), 5*time.Second); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestShutdown_CancelsAfterDrainTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	first := domain.NewMessage("", "U123", "C123", "<@UBOT> first", "1.1", time.Now())
+	second := domain.NewMessage("", "U123", "C123", "<@UBOT> second", "1.1", time.Now())
+	agentRepo.EXPECT().GenerateResponse(gomock.Any(), gomock.Any(), first).DoAndReturn(
+		func(ctx context.Context, session *domain.Session, message *domain.Message) (*domain.AgentResult, error) {
+			close(started)
+			<-cancelled
+			return domain.NewAgentResult("", domain.ErrAgentCancelled), nil
+		})
+	agentRepo.EXPECT().CancelAll().DoAndReturn(func() int {
+		close(cancelled)
+		return 1
+	})
+	// The queued message is dropped and the thread is told to retry only once
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", gomock.Any(), "1.1").Return("", nil)
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", restartingText, "1.1").Return("", nil)
+
+	go func() { _ = handler.HandleMessage(context.Background(), first) }()
+	<-started
+	if err := handler.HandleMessage(context.Background(), second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := handler.Shutdown(context.Background(), 50*time.Millisecond); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestShutdown_RejectsNewRuns(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	slackRepo := mocks.NewMockSlackRepository(ctrl)
+	agentRepo := mocks.NewMockAgentRepository(ctrl)
+	handler := usecase.NewMessageHandler(slackRepo, agentRepo, domain.NewBot("UBOT"))
+
+	if err := handler.Shutdown(context.Background(), time.Second); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	msg := domain.NewMessage("", "U123", "C123", "<@UBOT> hello", "1.1", time.Now())
+	slackRepo.EXPECT().PostMessage(gomock.Any(), "C123", restartingText, "1.1").Return("", nil)
+	if err := handler.HandleMessage(context.Background(), msg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// userNames resolves and caches the display names of Slack users
+type userNames struct {
+	slackRepo SlackRepository
+
+	mu    sync.Mutex
+	names map[string]string
+}
+
+// newUserNames creates a new userNames instance
+func newUserNames(slackRepo SlackRepository) *userNames {
+	return &userNames{
+		slackRepo: slackRepo,
+		names:     make(map[string]string),
+	}
+}
+
+// get returns the display name of the user, or the ID if it cannot be resolved
+func (u *userNames) get(ctx context.Context, userID string) string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if name, ok := u.names[userID]; ok {
+		return name
+	}
+	name, err := u.slackRepo.GetUserName(ctx, userID)
+	if err != nil {
+		log.Printf("Error resolving name of user %s: %v", userID, err)
+		name = userID
+	}
+	// Failures are cached too so that a missing scope doesn't cost a request per message
+	u.names[userID] = name
+	return name
+}
+
+// withThreadHistory returns the message with the earlier messages of its thread added to the prompt.
+// The transcript keeps the most recent messages that fit in the character budget.
+func (h *messageHandlerImpl) withThreadHistory(ctx context.Context, message *domain.Message) *domain.Message {
+	if h.historyChars <= 0 || message.ID == message.ThreadTS {
+		return message
+	}
+
+	replies, err := h.slackRepo.GetThreadReplies(ctx, message.ChannelID, message.ThreadTS, message.ID)
+	if err != nil {
+		log.Printf("Error fetching history of thread %s: %v", message.ThreadTS, err)
+		return message
+	}
+
+	var lines []string
+	size := 0
+	omitted := false
+	for i := len(replies) - 1; i >= 0; i-- {
+		line := fmt.Sprintf("[%s] %s", h.speaker(ctx, replies[i].UserID), h.resolveMentions(ctx, replies[i].Text))
+		if size+len(line) > h.historyChars {
+			omitted = true
+			break
+		}
+		size += len(line) + 1
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return message
+	}
+
+	// The lines were collected newest first
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	if omitted {
+		lines = append([]string{"(earlier messages omitted)"}, lines...)
+	}
+	log.Printf("Added %d earlier messages of thread %s to the prompt", len(lines), message.ThreadTS)
+
+	withHistory := *message
+	withHistory.Text = "Earlier messages in this Slack thread, oldest first:\n<transcript>\n" +
+		strings.Join(lines, "\n") + "\n</transcript>\n\n" + message.Text
+	return &withHistory
+}
+
+// speaker returns the name shown for the author of a thread message
+func (h *messageHandlerImpl) speaker(ctx context.Context, userID string) string {
+	if userID == h.bot.UserID {
+		return "assistant"
+	}
+	if userID == "" || userID[0] == 'B' {
+		// Other bots and integrations have no user profile
+		return "bot"
+	}
+	return h.userNames.get(ctx, userID)
+}
+
+// resolveMentions replaces mention tags with the users' names.
+// Agents strip mention tags from prompts, so unresolved mentions would disappear.
+func (h *messageHandlerImpl) resolveMentions(ctx context.Context, text string) string {
+	return mentionPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		userID := strings.TrimSuffix(strings.TrimPrefix(tag, "<@"), ">")
+		return "@" + h.speaker(ctx, userID)
+	})
+}
@@ -0,0 +1,95 @@
+package usecase
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+// threadQueue serializes agent runs per thread.
+// A thread is busy while its key is present in pending; the first caller for an idle
+// thread becomes its owner and keeps draining the queue until it is empty.
+type threadQueue struct {
+	mu      sync.Mutex
+	pending map[string][]*domain.Message
+}
+
+// newThreadQueue creates a new threadQueue instance
+func newThreadQueue() *threadQueue {
+	return &threadQueue{
+		pending: make(map[string][]*domain.Message),
+	}
+}
+
+// enqueue claims the thread for the message.
+// It returns owner=true when the thread was idle and the caller must process the message itself;
+// otherwise the message is queued and its 1-based position is returned.
+func (q *threadQueue) enqueue(key string, message *domain.Message) (position int, owner bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, busy := q.pending[key]
+	if !busy {
+		q.pending[key] = nil
+		return 0, true
+	}
+
+	q.pending[key] = append(queued, message)
+	return len(q.pending[key]), false
+}
+
+// next returns the messages to process next for the thread.
+// When merge is true all queued messages are returned at once, otherwise only the oldest one.
+// When nothing is queued the thread is released and nil is returned.
+func (q *threadQueue) next(key string, merge bool) []*domain.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued := q.pending[key]
+	if len(queued) == 0 {
+		delete(q.pending, key)
+		return nil
+	}
+
+	if merge {
+		q.pending[key] = nil
+		return queued
+	}
+	q.pending[key] = queued[1:]
+	return queued[:1]
+}
+
+// clear drops the queued messages of the thread without releasing it
+func (q *threadQueue) clear(key string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued, busy := q.pending[key]
+	if !busy {
+		return 0
+	}
+	q.pending[key] = nil
+	return len(queued)
+}
+
+// mergeMessages combines queued messages into a single prompt.
+// The last message supplies the metadata since it is the one the user sent most recently.
+func mergeMessages(messages []*domain.Message) *domain.Message {
+	if len(messages) == 1 {
+		return messages[0]
+	}
+
+	texts := make([]string, 0, len(messages))
+	var files []domain.File
+	for _, m := range messages {
+		texts = append(texts, m.Text)
+		files = append(files, m.Files...)
+	}
+
+	last := messages[len(messages)-1]
+	merged := domain.NewMessage(last.ID, last.UserID, last.ChannelID, strings.Join(texts, "\n\n"), last.ThreadTS, last.Timestamp)
+	merged.TeamID = last.TeamID
+	merged.Files = files
+	return merged
+}
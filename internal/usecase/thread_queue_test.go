@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/takutakahashi/slack-agent/internal/domain"
+)
+
+func TestThreadQueue(t *testing.T) {
+	q := newThreadQueue()
+	first := domain.NewMessage("", "U1", "C1", "first", "1.1", time.Now())
+	second := domain.NewMessage("", "U1", "C1", "second", "1.1", time.Now())
+	third := domain.NewMessage("", "U2", "C1", "third", "1.1", time.Now())
+
+	if _, owner := q.enqueue("1.1", first); !owner {
+		t.Fatal("expected first message to own the idle thread")
+	}
+	if pos, owner := q.enqueue("1.1", second); owner || pos != 1 {
+		t.Errorf("expected second message to be queued at 1, got owner=%v pos=%d", owner, pos)
+	}
+	if pos, owner := q.enqueue("1.1", third); owner || pos != 2 {
+		t.Errorf("expected third message to be queued at 2, got owner=%v pos=%d", owner, pos)
+	}
+
+	// Other threads are independent
+	if _, owner := q.enqueue("2.2", first); !owner {
+		t.Error("expected a different thread to be idle")
+	}
+
+	batch := q.next("1.1", false)
+	if len(batch) != 1 || batch[0] != second {
+		t.Fatalf("expected second message next, got %v", batch)
+	}
+	batch = q.next("1.1", false)
+	if len(batch) != 1 || batch[0] != third {
+		t.Fatalf("expected third message next, got %v", batch)
+	}
+	if batch := q.next("1.1", false); batch != nil {
+		t.Fatalf("expected empty queue, got %v", batch)
+	}
+
+	// The thread is released once drained
+	if _, owner := q.enqueue("1.1", first); !owner {
+		t.Error("expected drained thread to be idle again")
+	}
+}
+
+func TestThreadQueue_Merge(t *testing.T) {
+	q := newThreadQueue()
+	q.enqueue("1.1", domain.NewMessage("", "U1", "C1", "first", "1.1", time.Now()))
+	second := domain.NewMessage("", "U1", "C1", "second", "1.1", time.Now())
+	second.Files = []domain.File{{ID: "F1", Name: "app.log"}}
+	q.enqueue("1.1", second)
+	third := domain.NewMessage("", "U2", "C1", "third", "1.1", time.Now())
+	third.TeamID = "T1"
+	third.Files = []domain.File{{ID: "F2", Name: "screenshot.png"}}
+	q.enqueue("1.1", third)
+
+	batch := q.next("1.1", true)
+	if len(batch) != 2 {
+		t.Fatalf("expected both queued messages, got %d", len(batch))
+	}
+
+	merged := mergeMessages(batch)
+	if merged.Text != "second\n\nthird" {
+		t.Errorf("expected merged text, got %q", merged.Text)
+	}
+	if merged.UserID != "U2" || merged.TeamID != "T1" {
+		t.Errorf("expected metadata of the last message, got user %s in team %s", merged.UserID, merged.TeamID)
+	}
+	if len(merged.Files) != 2 {
+		t.Errorf("expected the files of both messages, got %d", len(merged.Files))
+	}
+}
+
+func TestThreadQueue_Clear(t *testing.T) {
+	q := newThreadQueue()
+	q.enqueue("1.1", domain.NewMessage("", "U1", "C1", "first", "1.1", time.Now()))
+	q.enqueue("1.1", domain.NewMessage("", "U1", "C1", "second", "1.1", time.Now()))
+
+	if n := q.clear("1.1"); n != 1 {
+		t.Errorf("expected 1 dropped message, got %d", n)
+	}
+	if batch := q.next("1.1", false); batch != nil {
+		t.Errorf("expected empty queue after clear, got %v", batch)
+	}
+}
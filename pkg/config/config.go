@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"text/template"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -12,6 +14,45 @@ type Config struct {
 	Slack SlackConfig `mapstructure:"slack"`
 	App   AppConfig   `mapstructure:"app"`
 	AI    AIConfig    `mapstructure:"ai"`
+	// Access restricts who may invoke the agent
+	Access AccessConfig `mapstructure:"access"`
+	// RateLimits throttles how often the agent may be invoked
+	RateLimits RateLimitConfig `mapstructure:"rate_limits"`
+	// Channels maps channel IDs or names to per-channel agent profiles
+	Channels map[string]ChannelConfig `mapstructure:"channels"`
+	// Cluster lets several replicas serve the same Slack app
+	Cluster ClusterConfig `mapstructure:"cluster"`
+}
+
+// ClusterConfig coordinates replicas through a directory on a shared volume.
+// An empty directory runs a single replica.
+type ClusterConfig struct {
+	Dir string `mapstructure:"dir"`
+	// ReplicaID names this replica; it defaults to the host name, which is the pod name on Kubernetes
+	ReplicaID string `mapstructure:"replica_id"`
+	// ThreadLeaseTTL is how long a thread stays with the replica that last answered in it
+	ThreadLeaseTTL time.Duration `mapstructure:"thread_lease_ttl"`
+}
+
+// AccessConfig holds allow and deny lists; deny takes precedence
+type AccessConfig struct {
+	Allow AccessListConfig `mapstructure:"allow"`
+	Deny  AccessListConfig `mapstructure:"deny"`
+}
+
+// AccessListConfig lists user, user group and channel IDs
+type AccessListConfig struct {
+	Users      []string `mapstructure:"users"`
+	UserGroups []string `mapstructure:"user_groups"`
+	Channels   []string `mapstructure:"channels"`
+}
+
+// RateLimitConfig holds token-bucket limits such as "20/1h" (20 requests per hour).
+// An empty limit disables that scope.
+type RateLimitConfig struct {
+	User      string `mapstructure:"user"`
+	Channel   string `mapstructure:"channel"`
+	Workspace string `mapstructure:"workspace"`
 }
 
 // SlackConfig contains Slack-related configuration
@@ -23,19 +64,83 @@ type SlackConfig struct {
 
 // AppConfig contains application-level configuration
 type AppConfig struct {
-	Port             int  `mapstructure:"port"`
-	UseFinishedJudge bool `mapstructure:"use_finished_judge"`
-	Debug            bool `mapstructure:"debug"`
+	Port               int           `mapstructure:"port"`
+	UseFinishedJudge   bool          `mapstructure:"use_finished_judge"`
+	Debug              bool          `mapstructure:"debug"`
+	CancelReaction     string        `mapstructure:"cancel_reaction"`
+	MergeQueued        bool          `mapstructure:"merge_queued_messages"`
+	MaxConcurrent      int           `mapstructure:"max_concurrent_agents"`
+	MaxQueued          int           `mapstructure:"max_queued_agents"`
+	UpdateInterval     time.Duration `mapstructure:"stream_update_interval"`
+	StateDir           string        `mapstructure:"state_dir"`
+	SessionTTL         time.Duration `mapstructure:"session_ttl"`
+	SessionMaxDiskMB   int64         `mapstructure:"session_max_disk_mb"`
+	SessionMaxCount    int           `mapstructure:"session_max_count"`
+	SessionGCInterval  time.Duration `mapstructure:"session_gc_interval"`
+	AttachmentMaxMB    int64         `mapstructure:"attachment_max_mb"`
+	AttachmentTypes    []string      `mapstructure:"attachment_types"`
+	UploadPatterns     []string      `mapstructure:"upload_patterns"`
+	UploadMaxMB        int64         `mapstructure:"upload_max_mb"`
+	UploadMaxFiles     int           `mapstructure:"upload_max_files"`
+	ThreadHistoryChars int           `mapstructure:"thread_history_chars"`
+	FollowThreads      bool          `mapstructure:"follow_threads"`
+	FollowIdleTimeout  time.Duration `mapstructure:"follow_threads_idle_timeout"`
+	// InteractiveControls adds buttons to answers; the Slack app needs interactivity enabled
+	InteractiveControls bool `mapstructure:"interactive_controls"`
+	// ShutdownDrainTimeout is how long running agents may keep going after SIGTERM or SIGINT before they are cancelled
+	ShutdownDrainTimeout time.Duration `mapstructure:"shutdown_drain_timeout"`
+	// DedupTTL is how long delivered events are remembered to drop duplicates
+	DedupTTL        time.Duration `mapstructure:"dedup_ttl"`
+	DedupMaxEntries int           `mapstructure:"dedup_max_entries"`
+	// DedupDir keeps the delivered events in files, e.g. on a volume shared by replicas, instead of in memory
+	DedupDir string `mapstructure:"dedup_dir"`
 }
 
 // AIConfig contains AI-related configuration
 type AIConfig struct {
+	Backend             string `mapstructure:"backend"`
 	OpenAIAPIKey        string `mapstructure:"openai_api_key"`
+	OpenAIBaseURL       string `mapstructure:"openai_base_url"`
+	OpenAIModel         string `mapstructure:"openai_model"`
 	SystemPromptPath    string `mapstructure:"system_prompt_path"`
 	DefaultSystemPrompt string `mapstructure:"default_system_prompt"`
 	DisallowedTools     string `mapstructure:"disallowed_tools"`
 	AgentScriptPath     string `mapstructure:"agent_script_path"`
 	ClaudeExtraArgs     string `mapstructure:"claude_extra_args"`
+	// Timeout stops a run that takes longer; zero disables it
+	Timeout time.Duration `mapstructure:"timeout"`
+	// IdleTimeout stops a run that produces no output for this long; zero disables it
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+	// KillGracePeriod is how long a stopped agent may take to exit after SIGTERM before SIGKILL
+	KillGracePeriod time.Duration `mapstructure:"kill_grace_period"`
+}
+
+// ChannelConfig overrides the agent settings in a channel.
+// Settings left empty are inherited from the ai section.
+type ChannelConfig struct {
+	SystemPrompt     string   `mapstructure:"system_prompt"`
+	SystemPromptPath string   `mapstructure:"system_prompt_path"`
+	AllowedTools     []string `mapstructure:"allowed_tools"`
+	DisallowedTools  []string `mapstructure:"disallowed_tools"`
+	ClaudeExtraArgs  []string `mapstructure:"claude_extra_args"`
+	Model            string   `mapstructure:"model"`
+	Workdir          string   `mapstructure:"workdir"`
+	// Timeout and IdleTimeout override ai.timeout and ai.idle_timeout in this channel; zero disables them
+	Timeout     *time.Duration `mapstructure:"timeout"`
+	IdleTimeout *time.Duration `mapstructure:"idle_timeout"`
+	// FollowThreads overrides app.follow_threads in this channel
+	FollowThreads *bool `mapstructure:"follow_threads"`
+	// Access applies on top of the global access lists in this channel
+	Access AccessConfig `mapstructure:"access"`
+	// ToolRules change the tool lists for the users, user groups and channels they list
+	ToolRules []ToolRuleConfig `mapstructure:"tool_rules"`
+}
+
+// ToolRuleConfig allows or disallows tools for the users, user groups and channels it lists
+type ToolRuleConfig struct {
+	AccessListConfig `mapstructure:",squash"`
+	AllowedTools     []string `mapstructure:"allowed_tools"`
+	DisallowedTools  []string `mapstructure:"disallowed_tools"`
 }
 
 // Load loads configuration from environment variables and config file
@@ -44,9 +149,36 @@ func Load() (*Config, error) {
 	viper.SetDefault("app.port", 3000)
 	viper.SetDefault("app.use_finished_judge", false)
 	viper.SetDefault("app.debug", false)
+	viper.SetDefault("app.cancel_reaction", "octagonal_sign")
+	viper.SetDefault("app.merge_queued_messages", false)
+	viper.SetDefault("app.max_concurrent_agents", 2)
+	viper.SetDefault("app.max_queued_agents", 10)
+	viper.SetDefault("app.stream_update_interval", "2s")
+	viper.SetDefault("app.state_dir", "state")
+	viper.SetDefault("app.session_ttl", "720h")
+	viper.SetDefault("app.session_max_disk_mb", 0)
+	viper.SetDefault("app.session_max_count", 0)
+	viper.SetDefault("app.session_gc_interval", "10m")
+	viper.SetDefault("app.attachment_max_mb", 20)
+	viper.SetDefault("app.attachment_types", []string{"text/*", "image/*", "application/json", "application/pdf", "application/xml", "application/x-yaml"})
+	viper.SetDefault("app.upload_max_mb", 10)
+	viper.SetDefault("app.upload_max_files", 10)
+	viper.SetDefault("app.thread_history_chars", 8000)
+	viper.SetDefault("app.follow_threads", false)
+	viper.SetDefault("app.follow_threads_idle_timeout", "30m")
+	viper.SetDefault("app.interactive_controls", false)
+	viper.SetDefault("app.shutdown_drain_timeout", "1m")
+	viper.SetDefault("app.dedup_ttl", "10m")
+	viper.SetDefault("app.dedup_max_entries", 10000)
+	viper.SetDefault("cluster.thread_lease_ttl", "30m")
+	viper.SetDefault("ai.backend", "claude-cli")
+	viper.SetDefault("ai.openai_model", "gpt-4o")
 	viper.SetDefault("ai.disallowed_tools", "Bash,Edit,MultiEdit,Write,NotebookRead,NotebookEdit,WebFetch,TodoRead,TodoWrite,WebSearch")
 	viper.SetDefault("ai.agent_script_path", "/usr/local/bin/start_agent.sh")
 	viper.SetDefault("ai.default_system_prompt", defaultSystemPrompt)
+	viper.SetDefault("ai.timeout", "1h")
+	viper.SetDefault("ai.idle_timeout", 0)
+	viper.SetDefault("ai.kill_grace_period", "10s")
 
 	// Bind environment variables
 	viper.SetEnvPrefix("")
@@ -59,11 +191,52 @@ func Load() (*Config, error) {
 	_ = viper.BindEnv("app.port", "PORT")
 	_ = viper.BindEnv("app.use_finished_judge", "USE_FINISHED_JUDGE")
 	_ = viper.BindEnv("app.debug", "DEBUG")
+	_ = viper.BindEnv("app.cancel_reaction", "CANCEL_REACTION")
+	_ = viper.BindEnv("app.merge_queued_messages", "MERGE_QUEUED_MESSAGES")
+	_ = viper.BindEnv("app.max_concurrent_agents", "MAX_CONCURRENT_AGENTS")
+	_ = viper.BindEnv("app.max_queued_agents", "MAX_QUEUED_AGENTS")
+	_ = viper.BindEnv("app.stream_update_interval", "STREAM_UPDATE_INTERVAL")
+	_ = viper.BindEnv("app.state_dir", "STATE_DIR")
+	_ = viper.BindEnv("app.session_ttl", "SESSION_TTL")
+	_ = viper.BindEnv("app.session_max_disk_mb", "SESSION_MAX_DISK_MB")
+	_ = viper.BindEnv("app.session_max_count", "SESSION_MAX_COUNT")
+	_ = viper.BindEnv("app.session_gc_interval", "SESSION_GC_INTERVAL")
+	_ = viper.BindEnv("app.attachment_max_mb", "ATTACHMENT_MAX_MB")
+	_ = viper.BindEnv("app.attachment_types", "ATTACHMENT_TYPES")
+	_ = viper.BindEnv("app.upload_patterns", "UPLOAD_PATTERNS")
+	_ = viper.BindEnv("app.upload_max_mb", "UPLOAD_MAX_MB")
+	_ = viper.BindEnv("app.upload_max_files", "UPLOAD_MAX_FILES")
+	_ = viper.BindEnv("app.thread_history_chars", "THREAD_HISTORY_CHARS")
+	_ = viper.BindEnv("app.follow_threads", "FOLLOW_THREADS")
+	_ = viper.BindEnv("app.follow_threads_idle_timeout", "FOLLOW_THREADS_IDLE_TIMEOUT")
+	_ = viper.BindEnv("app.interactive_controls", "INTERACTIVE_CONTROLS")
+	_ = viper.BindEnv("app.shutdown_drain_timeout", "SHUTDOWN_DRAIN_TIMEOUT")
+	_ = viper.BindEnv("app.dedup_ttl", "DEDUP_TTL")
+	_ = viper.BindEnv("app.dedup_max_entries", "DEDUP_MAX_ENTRIES")
+	_ = viper.BindEnv("app.dedup_dir", "DEDUP_DIR")
+	_ = viper.BindEnv("cluster.dir", "CLUSTER_DIR")
+	_ = viper.BindEnv("cluster.replica_id", "REPLICA_ID")
+	_ = viper.BindEnv("cluster.thread_lease_ttl", "CLUSTER_THREAD_LEASE_TTL")
+	_ = viper.BindEnv("access.allow.users", "ALLOWED_USERS")
+	_ = viper.BindEnv("access.allow.user_groups", "ALLOWED_USER_GROUPS")
+	_ = viper.BindEnv("access.allow.channels", "ALLOWED_CHANNELS")
+	_ = viper.BindEnv("access.deny.users", "DENIED_USERS")
+	_ = viper.BindEnv("access.deny.user_groups", "DENIED_USER_GROUPS")
+	_ = viper.BindEnv("access.deny.channels", "DENIED_CHANNELS")
+	_ = viper.BindEnv("rate_limits.user", "RATE_LIMIT_USER")
+	_ = viper.BindEnv("rate_limits.channel", "RATE_LIMIT_CHANNEL")
+	_ = viper.BindEnv("rate_limits.workspace", "RATE_LIMIT_WORKSPACE")
+	_ = viper.BindEnv("ai.backend", "AGENT_BACKEND")
 	_ = viper.BindEnv("ai.openai_api_key", "OPENAI_API_KEY")
+	_ = viper.BindEnv("ai.openai_base_url", "OPENAI_BASE_URL")
+	_ = viper.BindEnv("ai.openai_model", "OPENAI_MODEL")
 	_ = viper.BindEnv("ai.system_prompt_path", "SYSTEM_PROMPT_PATH")
 	_ = viper.BindEnv("ai.disallowed_tools", "DISALLOWED_TOOLS")
 	_ = viper.BindEnv("ai.agent_script_path", "AGENT_SCRIPT_PATH")
 	_ = viper.BindEnv("ai.claude_extra_args", "CLAUDE_EXTRA_ARGS")
+	_ = viper.BindEnv("ai.timeout", "AGENT_TIMEOUT")
+	_ = viper.BindEnv("ai.idle_timeout", "AGENT_IDLE_TIMEOUT")
+	_ = viper.BindEnv("ai.kill_grace_period", "AGENT_KILL_GRACE_PERIOD")
 
 	// Try to read config file if it exists
 	if cfgFile := viper.GetString("config"); cfgFile != "" {
@@ -98,6 +271,19 @@ func Load() (*Config, error) {
 		config.AI.DefaultSystemPrompt = string(content)
 	}
 
+	// Load channel system prompts from files
+	for name, channel := range config.Channels {
+		if channel.SystemPromptPath == "" {
+			continue
+		}
+		content, err := os.ReadFile(channel.SystemPromptPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading system prompt file for channel %s: %w", name, err)
+		}
+		channel.SystemPrompt = string(content)
+		config.Channels[name] = channel
+	}
+
 	return &config, nil
 }
 
@@ -115,12 +301,22 @@ func (c *Config) Validate() error {
 	}
 
 	// Validate agent script path
+	if c.AI.Backend == "script" && c.AI.AgentScriptPath == "" {
+		return fmt.Errorf("AGENT_SCRIPT_PATH is required for the script backend")
+	}
 	if c.AI.AgentScriptPath != "" {
 		if _, err := os.Stat(c.AI.AgentScriptPath); os.IsNotExist(err) {
 			return fmt.Errorf("agent script not found: %s", c.AI.AgentScriptPath)
 		}
 	}
 
+	// Validate channel working-directory templates
+	for name, channel := range c.Channels {
+		if _, err := template.New(name).Parse(channel.Workdir); err != nil {
+			return fmt.Errorf("invalid workdir for channel %s: %w", name, err)
+		}
+	}
+
 	return nil
 }
 
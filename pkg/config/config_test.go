@@ -3,7 +3,9 @@ package config_test
 import (
 	"os"
 	"testing"
+	"time"
 
+	"github.com/spf13/viper"
 	"github.com/takutakahashi/slack-agent/pkg/config"
 )
 
@@ -83,6 +85,23 @@ func TestConfigValidate(t *testing.T) {
 			t.Error("expected error when agent script path does not exist")
 		}
 	})
+
+	t.Run("should require agent script path for script backend", func(t *testing.T) {
+		cfg := &config.Config{
+			Slack: config.SlackConfig{
+				BotToken: "xoxb-123",
+				AppToken: "xapp-123",
+			},
+			AI: config.AIConfig{
+				Backend: "script",
+			},
+		}
+
+		err := cfg.Validate()
+		if err == nil {
+			t.Error("expected error when script backend has no agent script path")
+		}
+	})
 }
 
 func TestConfigLoad(t *testing.T) {
@@ -127,4 +146,110 @@ func TestConfigLoad(t *testing.T) {
 	if cfg.AI.DefaultSystemPrompt == "" {
 		t.Error("expected DefaultSystemPrompt to have default value")
 	}
+
+	if cfg.AI.Backend != "claude-cli" {
+		t.Errorf("expected Backend to default to claude-cli, got %s", cfg.AI.Backend)
+	}
+
+	if cfg.AI.Timeout != time.Hour || cfg.AI.IdleTimeout != 0 || cfg.AI.KillGracePeriod != 10*time.Second {
+		t.Errorf("unexpected timeout defaults: %s, %s, %s", cfg.AI.Timeout, cfg.AI.IdleTimeout, cfg.AI.KillGracePeriod)
+	}
+
+	if cfg.App.ShutdownDrainTimeout != time.Minute {
+		t.Errorf("expected ShutdownDrainTimeout to default to 1m, got %s", cfg.App.ShutdownDrainTimeout)
+	}
+
+	if cfg.App.DedupTTL != 10*time.Minute || cfg.App.DedupMaxEntries != 10000 || cfg.App.DedupDir != "" {
+		t.Errorf("unexpected dedup defaults: %s, %d, %q", cfg.App.DedupTTL, cfg.App.DedupMaxEntries, cfg.App.DedupDir)
+	}
+
+	if cfg.Cluster.Dir != "" || cfg.Cluster.ThreadLeaseTTL != 30*time.Minute {
+		t.Errorf("unexpected cluster defaults: %q, %s", cfg.Cluster.Dir, cfg.Cluster.ThreadLeaseTTL)
+	}
+}
+
+func TestConfigLoad_Channels(t *testing.T) {
+	dir := t.TempDir()
+	promptPath := dir + "/infra.md"
+	if err := os.WriteFile(promptPath, []byte("You are an SRE."), 0644); err != nil {
+		t.Fatalf("failed to write prompt: %v", err)
+	}
+	configPath := dir + "/slack-agent.yaml"
+	content := `channels:
+  "#infra":
+    system_prompt_path: ` + promptPath + `
+    allowed_tools: [Bash, Read]
+    model: opus
+    workdir: /work/{{.ChannelID}}/{{.ThreadTS}}
+    follow_threads: true
+    timeout: 2h
+    idle_timeout: 10m
+    tool_rules:
+      - user_groups: [S0SRE]
+        allowed_tools: [Write]
+  C0123ABCDE:
+    disallowed_tools: Bash,Edit
+    timeout: 0
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	viper.Set("config", configPath)
+	t.Cleanup(viper.Reset)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	infra, ok := cfg.Channels["#infra"]
+	if !ok {
+		t.Fatalf("expected #infra channel, got %v", cfg.Channels)
+	}
+	if infra.SystemPrompt != "You are an SRE." {
+		t.Errorf("expected system prompt to be read from file, got %q", infra.SystemPrompt)
+	}
+	if len(infra.AllowedTools) != 2 || infra.AllowedTools[0] != "Bash" || infra.Model != "opus" {
+		t.Errorf("unexpected infra channel: %+v", infra)
+	}
+	if infra.Timeout == nil || *infra.Timeout != 2*time.Hour || infra.IdleTimeout == nil || *infra.IdleTimeout != 10*time.Minute {
+		t.Errorf("expected channel timeouts, got %v and %v", infra.Timeout, infra.IdleTimeout)
+	}
+	if len(infra.ToolRules) != 1 || infra.ToolRules[0].UserGroups[0] != "S0SRE" || infra.ToolRules[0].AllowedTools[0] != "Write" {
+		t.Errorf("unexpected tool rules: %+v", infra.ToolRules)
+	}
+	if infra.FollowThreads == nil || !*infra.FollowThreads {
+		t.Errorf("expected follow_threads to be set, got %v", infra.FollowThreads)
+	}
+
+	// Configuration keys are case-insensitive
+	byID, ok := cfg.Channels["c0123abcde"]
+	if !ok || len(byID.DisallowedTools) != 2 {
+		t.Errorf("expected channel by ID with comma-separated tools, got %+v", cfg.Channels)
+	}
+	if byID.FollowThreads != nil {
+		t.Errorf("expected follow_threads to be inherited, got %v", *byID.FollowThreads)
+	}
+	// A zero timeout is set, so that it can disable the limit; unset timeouts are inherited
+	if byID.Timeout == nil || *byID.Timeout != 0 || byID.IdleTimeout != nil {
+		t.Errorf("expected a zero timeout and no idle timeout, got %v and %v", byID.Timeout, byID.IdleTimeout)
+	}
+}
+
+func TestConfigLoad_AccessFromEnv(t *testing.T) {
+	t.Setenv("ALLOWED_USERS", "U1,U2")
+	t.Setenv("DENIED_USER_GROUPS", "S1")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+
+	if len(cfg.Access.Allow.Users) != 2 || cfg.Access.Allow.Users[1] != "U2" {
+		t.Errorf("expected allowed users from ALLOWED_USERS, got %v", cfg.Access.Allow.Users)
+	}
+	if len(cfg.Access.Deny.UserGroups) != 1 || cfg.Access.Deny.UserGroups[0] != "S1" {
+		t.Errorf("expected denied user groups from DENIED_USER_GROUPS, got %v", cfg.Access.Deny.UserGroups)
+	}
 }